@@ -2,49 +2,149 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 
 	"YoannLetacq/todo-api.git/config"
-	"YoannLetacq/todo-api.git/internal/handlers"
-	"YoannLetacq/todo-api.git/internal/repository"
-	"YoannLetacq/todo-api.git/internal/services"
-	"YoannLetacq/todo-api.git/routes"
-
-	"github.com/joho/godotenv"
+	"YoannLetacq/todo-api.git/internal/app"
+	"YoannLetacq/todo-api.git/internal/ratelimit"
+	"YoannLetacq/todo-api.git/internal/storage"
 )
 
 func main() {
-	// Charger les variables d'environnement depuis le fichier .env (si présent)
-	if err := godotenv.Load(); err != nil {
-		log.Println("Avertissement: impossible de charger le fichier .env")
+	// Charger la configuration : valeurs par défaut, CONFIG_FILE, .env puis environnement
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		// Sous-commande de migration : go run ./cmd migrate up|down [n]|status
+		case "migrate":
+			if err := runMigrate(cfg.Database, os.Args[2:]); err != nil {
+				log.Fatal("Erreur lors de la migration: ", err)
+			}
+			return
+		// Affiche la configuration effective, secrets masqués : go run ./cmd config
+		case "config":
+			fmt.Print(cfg)
+			return
+		default:
+			log.Fatalf("Commande inconnue %q, commandes disponibles: migrate, config", os.Args[1])
+		}
+	}
+	log.Printf("Configuration effective:\n%s", cfg)
+	for _, warning := range cfg.Warnings() {
+		log.Println("Avertissement:", warning)
+	}
+
+	// Clés de signature des tokens : la clé active et les anciennes encore acceptées
+	jwtKeys, err := cfg.JWT.Keys()
+	if err != nil {
+		log.Fatal("Erreur lors du chargement des clés JWT:", err)
+	}
+
+	// Ouvrir la base de données configurée et appliquer les migrations en attente
+	db, err := config.OpenDB(cfg.Database)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
+	// Initialiser le stockage des pièces jointes (local ou s3)
+	blobStore, err := storage.NewBlobStore(cfg.Storage)
+	if err != nil {
+		log.Fatal("Erreur lors de l'initialisation du stockage des pièces jointes:", err)
 	}
 
-	// Initialiser la base de données en mode production (false signifie non-test)
-	config.InitDB(false)
+	// Limitation des requêtes : quotas en mémoire ou partagés par la base entre les instances
+	var rateLimitStore ratelimit.Store
+	if cfg.RateLimit.Enabled {
+		rateLimitStore, err = ratelimit.NewStore(cfg.RateLimit, db)
+		if err != nil {
+			log.Fatal("Erreur lors de l'initialisation de la limitation des requêtes:", err)
+		}
+	}
 
-	// Initialiser le repository et le service pour les utilisateurs
-	userRepo := repository.NewUserRepository()
-	userService := services.NewUserService(userRepo)
-	handlers.InitUserHandlers(userService)
+	legacySunset, err := cfg.Server.ParseLegacySunset()
+	if err != nil {
+		log.Fatal("API_LEGACY_SUNSET invalide:", err)
+	}
+	trustedProxies, err := cfg.Server.ParseTrustedProxies()
+	if err != nil {
+		log.Fatal("TRUSTED_PROXIES invalide:", err)
+	}
 
-	// Initialiser le repository et le service pour les tâches
-	taskRepo := repository.NewTaskRepository()
-	taskService := services.NewTaskService(taskRepo)
-	handlers.InitTaskHandlers(taskService)
+	// Construire l'application : repositories, services, handlers et routes
+	application, err := app.New(db, app.Options{
+		Blobs:               blobStore,
+		JWTKeys:             jwtKeys,
+		MaxAttachmentSize:   cfg.Storage.MaxAttachmentSize,
+		RequestTimeout:      cfg.Server.RequestTimeout,
+		OIDCProviders:       cfg.OIDC,
+		RateLimitStore:      rateLimitStore,
+		RateLimits:          ratelimit.PoliciesFromConfig(cfg.RateLimit),
+		DisableLegacyRoutes: !cfg.Server.LegacyRoutes,
+		LegacySunset:        legacySunset,
+		TrustedProxies:      trustedProxies,
+	})
+	if err != nil {
+		log.Fatal("Erreur lors de l'initialisation de l'application:", err)
+	}
 
-	// Configurer le routeur avec l'ensemble des routes (utilisateurs et tâches)
-	router := routes.SetupRouter()
+	port := strconv.Itoa(cfg.Server.Port)
 
-	// Récupérer le port depuis la variable d'environnement ou utiliser 8080 par défaut
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Les délais protègent contre les clients lents qui monopolisent des connexions
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           application.Router,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
 	}
-	log.Println("Serveur démarré sur le port " + port)
 
 	// Démarrer le serveur
-	if err := router.Run(":" + port); err != nil {
-		log.Fatal("Erreur lors du démarrage du serveur:", err)
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Println("Serveur démarré sur le port " + port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	// Attendre SIGINT ou SIGTERM (envoyé lors d'un déploiement)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Erreur lors du démarrage du serveur:", err)
+		}
+		return
+	case <-ctx.Done():
+	}
+	// Un second signal interrompt immédiatement le processus
+	stop()
+
+	log.Println("Arrêt du serveur, fin des requêtes en cours...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	// Ne plus accepter de connexions et attendre la fin des requêtes en cours
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println("Erreur lors de l'arrêt du serveur HTTP:", err)
+	}
+	// Fermer les websockets puis le pool de connexions à la base
+	if err := application.Shutdown(shutdownCtx); err != nil {
+		log.Println("Erreur lors de l'arrêt de l'application:", err)
 	}
+	log.Println("Serveur arrêté.")
 }
@@ -0,0 +1,83 @@
+// cmd/migrate.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"YoannLetacq/todo-api.git/config"
+	"YoannLetacq/todo-api.git/internal/migrations"
+)
+
+const migrateUsage = "usage: migrate up | down [n] | status"
+
+// runMigrate exécute la sous-commande "migrate up|down [n]|status" sur la base configurée
+func runMigrate(cfg config.DatabaseConfig, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	db, err := config.ConnectDB(cfg)
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("Schéma à jour, aucune migration à appliquer.")
+		}
+		for _, mig := range applied {
+			fmt.Printf("%04d_%s appliquée\n", mig.Version, mig.Name)
+		}
+	case "down":
+		// Annule une seule migration par défaut
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return errors.New(migrateUsage)
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("Aucune migration à annuler.")
+		}
+		for _, mig := range reverted {
+			fmt.Printf("%04d_%s annulée\n", mig.Version, mig.Name)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			state := "en attente"
+			if st.AppliedAt != nil {
+				state = "appliquée le " + st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", st.Version, st.Name, state)
+		}
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
@@ -1,27 +1,673 @@
 package config
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
 )
 
-// Initialize .env file
-func InitEnv() {
-	err := godotenv.Load()
+// Config est la configuration typée de l'API.
+//
+// Chaque champ est lu, de la priorité la plus faible à la plus forte :
+// la valeur par défaut (tag default), le fichier CONFIG_FILE en YAML ou TOML
+// (tag config, imbriqué par section), le fichier .env puis les variables
+// d'environnement (tag env). Les champs marqués secret sont masqués à l'affichage
+// et peuvent être lus depuis un fichier, ex: JWT_SECRET_FILE=/run/secrets/jwt
+// ou secret_file dans la section jwt, pour les secrets Docker ou Kubernetes.
+type Config struct {
+	// Env vaut "production" ou "development", la production impose des secrets robustes
+	Env       string          `config:"env" env:"APP_ENV" default:"development"`
+	Server    ServerConfig    `config:"server"`
+	Database  DatabaseConfig  `config:"database"`
+	Storage   StorageConfig   `config:"storage"`
+	JWT       JWTConfig       `config:"jwt"`
+	RateLimit RateLimitConfig `config:"rate_limit"`
+	// OIDC contient les fournisseurs d'identité externes par nom, ex: oidc.google
+	// dans le fichier ou OIDC_PROVIDERS=google et OIDC_GOOGLE_ISSUER=... dans l'environnement
+	OIDC map[string]*OIDCProviderConfig `config:"oidc" env:"OIDC" names:"OIDC_PROVIDERS"`
+}
+
+// ServerConfig règle le serveur HTTP
+type ServerConfig struct {
+	Port              int           `config:"port" env:"PORT" default:"8080"`
+	ReadHeaderTimeout time.Duration `config:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT" default:"5s"`
+	ReadTimeout       time.Duration `config:"read_timeout" env:"SERVER_READ_TIMEOUT" default:"60s"`
+	WriteTimeout      time.Duration `config:"write_timeout" env:"SERVER_WRITE_TIMEOUT" default:"60s"`
+	IdleTimeout       time.Duration `config:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" default:"120s"`
+	MaxHeaderBytes    int           `config:"max_header_bytes" env:"SERVER_MAX_HEADER_BYTES" default:"65536"`
+	// RequestTimeout est la durée maximale de traitement d'une requête, 0 pour aucune limite
+	RequestTimeout  time.Duration `config:"request_timeout" env:"REQUEST_TIMEOUT" default:"30s"`
+	ShutdownTimeout time.Duration `config:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" default:"30s"`
+	// LegacyRoutes garde les routes sans préfixe /api/v1, dépréciées, pour les anciens clients
+	LegacyRoutes bool `config:"legacy_routes" env:"API_LEGACY_ROUTES" default:"true"`
+	// LegacySunset est la date de retrait des routes sans préfixe (AAAA-MM-JJ), annoncée
+	// dans l'en-tête Sunset, vide si elle n'est pas encore fixée
+	LegacySunset string `config:"legacy_sunset" env:"API_LEGACY_SUNSET"`
+	// TrustedProxies liste les IP ou réseaux CIDR des reverse proxies dont les en-têtes
+	// X-Forwarded-For et X-Real-IP sont crus, séparés par des virgules. Vide par défaut :
+	// l'IP du client est celle de la connexion et ces en-têtes sont ignorés.
+	TrustedProxies string `config:"trusted_proxies" env:"TRUSTED_PROXIES"`
+}
+
+// ParseTrustedProxies retourne les IP et réseaux CIDR des reverse proxies de confiance
+func (c ServerConfig) ParseTrustedProxies() ([]string, error) {
+	var proxies []string
+	for _, p := range strings.Split(c.TrustedProxies, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+			return nil, fmt.Errorf("%q n'est ni une IP ni un réseau CIDR", p)
+		}
+		proxies = append(proxies, p)
+	}
+	return proxies, nil
+}
+
+// ParseLegacySunset retourne la date de retrait des routes sans préfixe, zéro si elle n'est pas fixée
+func (c ServerConfig) ParseLegacySunset() (time.Time, error) {
+	if c.LegacySunset == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.DateOnly, c.LegacySunset)
+}
+
+// DatabaseConfig choisit et décrit la base de données
+type DatabaseConfig struct {
+	Type string `config:"type" env:"DB_TYPE" default:"sqlite"`
+	// Path est le fichier de la base SQLite
+	Path        string `config:"path" env:"DB_PATH" default:"db.db"`
+	Host        string `config:"host" env:"DB_HOST" default:"localhost"`
+	Port        int    `config:"port" env:"DB_PORT" default:"5432"`
+	User        string `config:"user" env:"DB_USER" default:"postgres"`
+	Password    string `config:"password" env:"DB_PASSWORD" secret:"true"`
+	Name        string `config:"name" env:"DB_NAME" default:"todo_db"`
+	SSLMode     string `config:"sslmode" env:"DB_SSLMODE" default:"disable"`
+	AutoMigrate bool   `config:"auto_migrate" env:"DB_AUTO_MIGRATE" default:"true"`
+}
+
+// StorageConfig choisit le stockage des pièces jointes
+type StorageConfig struct {
+	Backend           string   `config:"backend" env:"BLOB_STORE" default:"local"`
+	Dir               string   `config:"dir" env:"BLOB_DIR" default:"data/attachments"`
+	MaxAttachmentSize int64    `config:"max_attachment_size" env:"ATTACHMENT_MAX_SIZE" default:"10485760"`
+	S3                S3Config `config:"s3"`
+}
+
+// S3Config décrit un bucket S3 ou compatible
+type S3Config struct {
+	Endpoint        string `config:"endpoint" env:"S3_ENDPOINT" default:"https://s3.amazonaws.com"`
+	Region          string `config:"region" env:"S3_REGION" default:"us-east-1"`
+	Bucket          string `config:"bucket" env:"S3_BUCKET"`
+	AccessKeyID     string `config:"access_key_id" env:"S3_ACCESS_KEY_ID"`
+	SecretAccessKey string `config:"secret_access_key" env:"S3_SECRET_ACCESS_KEY" secret:"true"`
+}
+
+// OIDCProviderConfig décrit un fournisseur d'identité OpenID Connect
+type OIDCProviderConfig struct {
+	// Issuer est l'URL de l'émetteur, le reste de la configuration est découvert
+	// sur <issuer>/.well-known/openid-configuration
+	Issuer   string `config:"issuer" env:"ISSUER"`
+	ClientID string `config:"client_id" env:"CLIENT_ID"`
+	// ClientSecret est vide pour un client public, PKCE protège alors l'échange du code
+	ClientSecret string `config:"client_secret" env:"CLIENT_SECRET" secret:"true"`
+	// RedirectURL est l'URL publique du callback, ex: https://api.example.com/auth/google/callback
+	RedirectURL string `config:"redirect_url" env:"REDIRECT_URL"`
+	// Scopes demandés en plus de openid, séparés par des espaces
+	Scopes string `config:"scopes" env:"SCOPES" default:"email profile"`
+}
+
+// Nom d'un fournisseur OIDC, utilisé dans l'URL /auth/{provider}/login
+var oidcProviderName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Algorithmes de signature des tokens
+const (
+	JWTAlgHS256 = "HS256"
+	JWTAlgRS256 = "RS256"
+	JWTAlgEdDSA = "EdDSA"
+)
+
+// JWTConfig contient les clés de signature des tokens
+type JWTConfig struct {
+	// Algorithm est l'algorithme de la clé active: HS256 (secret partagé),
+	// RS256 ou EdDSA (clé privée PEM, clés publiques publiées dans le JWKS)
+	Algorithm string `config:"algorithm" env:"JWT_ALGORITHM" default:"HS256"`
+	// Secret est la clé active HS256, utilisée pour signer, identifiée par KeyID (kid)
+	Secret string `config:"secret" env:"JWT_SECRET" secret:"true"`
+	// PrivateKey est la clé active RS256 ou EdDSA au format PEM,
+	// en général lue depuis un fichier avec JWT_PRIVATE_KEY_FILE
+	PrivateKey string `config:"private_key" env:"JWT_PRIVATE_KEY" secret:"true"`
+	KeyID      string `config:"key_id" env:"JWT_KEY_ID" default:"default"`
+	// PreviousKeys sont les anciennes clés HS256 encore acceptées en vérification
+	// après une rotation, au format "kid1:secret1,kid2:secret2"
+	PreviousKeys string `config:"previous_keys" env:"JWT_PREVIOUS_KEYS" secret:"true"`
+	// PreviousPublicKeys sont les anciennes clés RS256 ou EdDSA encore acceptées
+	// en vérification, au format "kid1:/chemin/cle1.pub,kid2:/chemin/cle2.pub"
+	PreviousPublicKeys string `config:"previous_public_keys" env:"JWT_PREVIOUS_PUBLIC_KEYS"`
+}
+
+// RateLimitConfig règle la limitation du nombre de requêtes. Chaque classe de routes
+// a son quota, par utilisateur connecté ou à défaut par IP, renouvelé en continu sur la fenêtre.
+type RateLimitConfig struct {
+	Enabled bool `config:"enabled" env:"RATE_LIMIT_ENABLED" default:"true"`
+	// Store vaut "memory" (quotas propres à chaque instance) ou "sql"
+	// (quotas partagés entre les instances par la base de données)
+	Store string `config:"store" env:"RATE_LIMIT_STORE" default:"memory"`
+	// Auth limite l'inscription et la connexion, par IP
+	AuthLimit  int           `config:"auth_limit" env:"RATE_LIMIT_AUTH" default:"10"`
+	AuthWindow time.Duration `config:"auth_window" env:"RATE_LIMIT_AUTH_WINDOW" default:"1m"`
+	// Read limite les requêtes GET
+	ReadLimit  int           `config:"read_limit" env:"RATE_LIMIT_READ" default:"300"`
+	ReadWindow time.Duration `config:"read_window" env:"RATE_LIMIT_READ_WINDOW" default:"1m"`
+	// Write limite les requêtes qui modifient des données
+	WriteLimit  int           `config:"write_limit" env:"RATE_LIMIT_WRITE" default:"60"`
+	WriteWindow time.Duration `config:"write_window" env:"RATE_LIMIT_WRITE_WINDOW" default:"1m"`
+}
+
+// Longueur minimale d'un secret JWT en production
+const minJWTSecretLength = 32
+
+// Secrets d'exemple qui ne doivent jamais protéger une instance de production
+var weakJWTSecrets = map[string]bool{
+	"my_secret_key": true,
+	"secret":        true,
+	"changeme":      true,
+}
+
+// ParsePreviousKeys décode PreviousKeys en une table kid -> secret
+func (j JWTConfig) ParsePreviousKeys() (map[string]string, error) {
+	return j.parseKeyList(j.PreviousKeys, "secret", nil)
+}
+
+// ParsePreviousPublicKeys décode PreviousPublicKeys en une table kid -> chemin du fichier PEM
+func (j JWTConfig) ParsePreviousPublicKeys() (map[string]string, error) {
+	previous, err := j.ParsePreviousKeys()
 	if err != nil {
-		log.Fatal("Failed to load .env file.")
+		return nil, err
+	}
+	return j.parseKeyList(j.PreviousPublicKeys, "chemin", previous)
+}
+
+// parseKeyList décode une liste "kid:valeur" séparée par des virgules.
+// Un kid ne peut apparaître qu'une fois ni reprendre celui de la clé active ou d'une clé déjà dans taken.
+func (j JWTConfig) parseKeyList(list, value string, taken map[string]string) (map[string]string, error) {
+	keys := make(map[string]string)
+	if strings.TrimSpace(list) == "" {
+		return keys, nil
+	}
+	for _, pair := range strings.Split(list, ",") {
+		kid, v, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || kid == "" || v == "" {
+			// La valeur n'est pas reprise dans l'erreur, ce peut être un secret
+			return nil, fmt.Errorf("format attendu kid:%s, séparés par des virgules", value)
+		}
+		_, exists := keys[kid]
+		_, reserved := taken[kid]
+		if exists || reserved || kid == j.KeyID {
+			return nil, fmt.Errorf("kid %q en double", kid)
+		}
+		keys[kid] = v
+	}
+	return keys, nil
+}
+
+// weakJWTSecret indique si un secret est trop faible pour la production
+func weakJWTSecret(secret string) bool {
+	return len(secret) < minJWTSecretLength || weakJWTSecrets[secret]
+}
+
+// Load charge la configuration depuis le fichier .env du dossier courant et l'environnement du processus
+func Load() (*Config, error) {
+	return LoadFrom(".env", os.LookupEnv)
+}
+
+// LoadFrom charge la configuration depuis un fichier .env (ignoré s'il n'existe pas)
+// et une fonction de lecture de l'environnement, puis la valide.
+// Toutes les erreurs sont retournées ensemble.
+func LoadFrom(dotenvPath string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	dotenv := map[string]string{}
+	if dotenvPath != "" {
+		values, err := godotenv.Read(dotenvPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("lecture de %s: %w", dotenvPath, err)
+		}
+		if err == nil {
+			dotenv = values
+		}
+	}
+
+	// L'environnement est prioritaire sur le fichier .env
+	lookup := func(key string) (string, bool) {
+		if value, ok := lookupEnv(key); ok {
+			return value, true
+		}
+		value, ok := dotenv[key]
+		return value, ok
+	}
+
+	file := map[string]string{}
+	if path, ok := lookup("CONFIG_FILE"); ok && path != "" {
+		var err error
+		if file, err = readConfigFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var cfg Config
+	var errs []error
+	used := make(map[string]bool)
+	// Les sections nommées, ex: oidc.google, sont celles listées dans la variable
+	// du tag names (OIDC_PROVIDERS=google,okta) et celles présentes dans le fichier
+	names := func(key string, tag reflect.StructTag, _ reflect.Value) []string {
+		var list []string
+		if value, ok := lookup(tag.Get("names")); ok {
+			list = strings.Split(value, ",")
+		}
+		var fromFile []string
+		for k := range file {
+			if rest, ok := strings.CutPrefix(k, key+"."); ok {
+				name, _, _ := strings.Cut(rest, ".")
+				fromFile = append(fromFile, name)
+			}
+		}
+		sort.Strings(fromFile)
+		return uniqueNames(append(list, fromFile...))
+	}
+	eachField(reflect.ValueOf(&cfg).Elem(), "", "", names, func(f field) {
+		secret := f.tag.Get("secret") == "true"
+		raw, source := f.tag.Get("default"), "valeur par défaut"
+		if value, ok := file[f.key]; ok {
+			raw, source = value, "fichier de configuration"
+			used[f.key] = true
+		}
+		if secret {
+			if path, ok := file[f.key+"_file"]; ok {
+				used[f.key+"_file"] = true
+				raw, source = readSecretFile(path, &errs, f.key+"_file"), f.key+"_file"
+			}
+		}
+		if env := f.env; env != "" {
+			value, direct := lookup(env)
+			var path string
+			var fromFile bool
+			if secret {
+				path, fromFile = lookup(env + "_FILE")
+			}
+			switch {
+			case direct && fromFile:
+				errs = append(errs, fmt.Errorf("%s et %s_FILE sont tous deux définis", env, env))
+			case fromFile:
+				raw, source = readSecretFile(path, &errs, env+"_FILE"), env+"_FILE"
+			case direct:
+				raw, source = value, env
+			}
+		}
+		if err := setField(f.value, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s (%s): %w", f.name(), source, err))
+		}
+	})
+
+	// Une clé inconnue dans le fichier est le plus souvent une faute de frappe
+	var unknown []string
+	for key := range file {
+		if !used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		errs = append(errs, fmt.Errorf("clé inconnue dans le fichier de configuration: %s", key))
+	}
+
+	if len(errs) == 0 {
+		errs = cfg.validate()
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("configuration invalide:\n%w", errors.Join(errs...))
+	}
+	return &cfg, nil
+}
+
+// readSecretFile lit un secret depuis un fichier, sans le saut de ligne final.
+// Une erreur de lecture est ajoutée à errs.
+func readSecretFile(path string, errs *[]error, source string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("%s: %w", source, err))
+		return ""
+	}
+	return strings.TrimRight(string(data), "\r\n")
+}
+
+// validate vérifie la cohérence des valeurs et retourne toutes les erreurs trouvées
+func (c *Config) validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	production := c.Env == "production"
+	check(production || c.Env == "development", "APP_ENV: %q inconnu, valeurs possibles: development, production", c.Env)
+
+	s := c.Server
+	check(s.Port > 0 && s.Port <= 65535, "PORT: %d hors de l'intervalle 1-65535", s.Port)
+	check(s.MaxHeaderBytes > 0, "SERVER_MAX_HEADER_BYTES: doit être positif")
+	check(s.ReadHeaderTimeout > 0, "SERVER_READ_HEADER_TIMEOUT: doit être positif")
+	check(s.ReadTimeout >= 0, "SERVER_READ_TIMEOUT: ne peut pas être négatif")
+	check(s.WriteTimeout >= 0, "SERVER_WRITE_TIMEOUT: ne peut pas être négatif")
+	check(s.IdleTimeout >= 0, "SERVER_IDLE_TIMEOUT: ne peut pas être négatif")
+	check(s.RequestTimeout >= 0, "REQUEST_TIMEOUT: ne peut pas être négatif")
+	check(s.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT: doit être positif")
+	_, err := s.ParseLegacySunset()
+	check(err == nil, "API_LEGACY_SUNSET: date AAAA-MM-JJ attendue, %q reçu", s.LegacySunset)
+	_, err = s.ParseTrustedProxies()
+	check(err == nil, "TRUSTED_PROXIES: %v", err)
+
+	db := c.Database
+	switch db.Type {
+	case "sqlite":
+		check(db.Path != "", "DB_PATH: requis pour SQLite")
+	case "postgres":
+		check(db.Host != "", "DB_HOST: requis pour PostgreSQL")
+		check(db.Port > 0 && db.Port <= 65535, "DB_PORT: %d hors de l'intervalle 1-65535", db.Port)
+		check(db.User != "", "DB_USER: requis pour PostgreSQL")
+		check(db.Name != "", "DB_NAME: requis pour PostgreSQL")
+	default:
+		check(false, "DB_TYPE: %q inconnu, valeurs possibles: sqlite, postgres", db.Type)
+	}
+
+	st := c.Storage
+	check(st.MaxAttachmentSize > 0, "ATTACHMENT_MAX_SIZE: doit être positif")
+	switch st.Backend {
+	case "local":
+		check(st.Dir != "", "BLOB_DIR: requis pour le stockage local")
+	case "s3":
+		check(st.S3.Endpoint != "", "S3_ENDPOINT: requis pour le stockage S3")
+		check(st.S3.Region != "", "S3_REGION: requis pour le stockage S3")
+		check(st.S3.Bucket != "", "S3_BUCKET: requis pour le stockage S3")
+		check(st.S3.AccessKeyID != "", "S3_ACCESS_KEY_ID: requis pour le stockage S3")
+		check(st.S3.SecretAccessKey != "", "S3_SECRET_ACCESS_KEY: requis pour le stockage S3")
+	default:
+		check(false, "BLOB_STORE: %q inconnu, valeurs possibles: local, s3", st.Backend)
+	}
+
+	jwt := c.JWT
+	switch jwt.Algorithm {
+	case JWTAlgHS256:
+		check(jwt.Secret != "", "JWT_SECRET: requis avec JWT_ALGORITHM=HS256")
+	case JWTAlgRS256, JWTAlgEdDSA:
+		check(jwt.PrivateKey != "", "JWT_PRIVATE_KEY: requis avec JWT_ALGORITHM=%s", jwt.Algorithm)
+	default:
+		check(false, "JWT_ALGORITHM: %q inconnu, valeurs possibles: HS256, RS256, EdDSA", jwt.Algorithm)
+	}
+	check(jwt.KeyID != "", "JWT_KEY_ID: requis")
+	check(!production || jwt.Secret == "" || !weakJWTSecret(jwt.Secret),
+		"JWT_SECRET: trop faible pour la production, %d caractères aléatoires minimum", minJWTSecretLength)
+	previous, err := jwt.ParsePreviousKeys()
+	check(err == nil, "JWT_PREVIOUS_KEYS: %v", err)
+	for kid, secret := range previous {
+		check(!production || !weakJWTSecret(secret), "JWT_PREVIOUS_KEYS: clé %q trop faible pour la production", kid)
 	}
-	os.Setenv("JWT_SECRET", GetEnv("JWT_SECRET", "my_secret_key"))
+	if err == nil {
+		_, err = jwt.ParsePreviousPublicKeys()
+		check(err == nil, "JWT_PREVIOUS_PUBLIC_KEYS: %v", err)
+	}
+
+	if rl := c.RateLimit; rl.Enabled {
+		check(rl.Store == "memory" || rl.Store == "sql", "RATE_LIMIT_STORE: %q inconnu, valeurs possibles: memory, sql", rl.Store)
+		check(rl.AuthLimit > 0 && rl.AuthWindow > 0, "RATE_LIMIT_AUTH: quota et fenêtre doivent être positifs")
+		check(rl.ReadLimit > 0 && rl.ReadWindow > 0, "RATE_LIMIT_READ: quota et fenêtre doivent être positifs")
+		check(rl.WriteLimit > 0 && rl.WriteWindow > 0, "RATE_LIMIT_WRITE: quota et fenêtre doivent être positifs")
+	}
+
+	for _, name := range existingNames("", "", reflect.ValueOf(c.OIDC)) {
+		p, env := c.OIDC[name], "OIDC_"+envName(name)+"_"
+		if !oidcProviderName.MatchString(name) {
+			check(false, "OIDC_PROVIDERS: nom %q invalide, lettres minuscules, chiffres et tirets", name)
+			continue
+		}
+		check(validURL(p.Issuer, production), "%sISSUER: URL absolue requise%s", env, httpsRequired(production))
+		check(p.ClientID != "", "%sCLIENT_ID: requis", env)
+		check(validURL(p.RedirectURL, production), "%sREDIRECT_URL: URL absolue requise%s", env, httpsRequired(production))
+	}
+	return errs
+}
 
+// validURL vérifie qu'une URL est absolue, en https si requis
+func validURL(raw string, requireHTTPS bool) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	return u.Scheme == "https" || (u.Scheme == "http" && !requireHTTPS)
 }
 
-// Getenv return the value for a given key of the loaded .env.
-// You can specify a defaultValue.
-func GetEnv(secret, defaultValue string) string {
-	if value, exists := os.LookupEnv(secret); exists {
+func httpsRequired(production bool) string {
+	if production {
+		return " en https en production"
+	}
+	return ""
+}
+
+// Warnings retourne les réglages acceptés en développement mais déconseillés
+func (c *Config) Warnings() []string {
+	var warnings []string
+	hmac := c.JWT.Algorithm == JWTAlgHS256 || c.JWT.Secret != ""
+	if c.Env != "production" && hmac && weakJWTSecret(c.JWT.Secret) {
+		warnings = append(warnings, "JWT_SECRET est faible, il sera refusé avec APP_ENV=production")
+	}
+	return warnings
+}
+
+// String affiche la configuration effective, une ligne par variable, secrets masqués.
+// Le format %v d'une Config ne peut donc pas divulguer de secret dans les logs.
+func (c Config) String() string {
+	var b strings.Builder
+	eachField(reflect.ValueOf(&c).Elem(), "", "", existingNames, func(f field) {
+		value := formatField(f.value)
+		if f.tag.Get("secret") == "true" && value != "" {
+			value = "********"
+		}
+		fmt.Fprintf(&b, "%s=%s\n", f.name(), value)
+	})
+	return b.String()
+}
+
+// GetEnv retourne la valeur d'une variable d'environnement ou defaultValue si elle n'est pas définie
+func GetEnv(key, defaultValue string) string {
+	if value, exists := os.LookupEnv(key); exists {
 		return value
 	}
 	return defaultValue
 }
+
+// field est un champ terminal de Config avec son chemin dans le fichier de configuration
+// et sa variable d'environnement
+type field struct {
+	key   string
+	env   string
+	tag   reflect.StructTag
+	value reflect.Value
+}
+
+// name retourne la variable d'environnement du champ, ou sa clé de fichier à défaut
+func (f field) name() string {
+	if f.env != "" {
+		return f.env
+	}
+	return f.key
+}
+
+// namesFunc retourne les noms des sections d'un champ map[string]*struct
+type namesFunc func(key string, tag reflect.StructTag, m reflect.Value) []string
+
+// existingNames retourne les sections déjà présentes, triées
+func existingNames(_ string, _ reflect.StructTag, m reflect.Value) []string {
+	names := make([]string, 0, m.Len())
+	for _, k := range m.MapKeys() {
+		names = append(names, k.String())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// uniqueNames supprime les noms vides et les doublons en gardant l'ordre
+func uniqueNames(list []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, name := range list {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// envName convertit un nom de section en fragment de variable d'environnement, ex: my-idp -> MY_IDP
+func envName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// eachField parcourt les champs terminaux d'une structure, dans l'ordre de déclaration.
+// Un champ map[string]*struct contient des sections nommées : chaque section
+// a pour clé "prefixe.nom.cle" et pour variables PREFIXE_NOM_VARIABLE.
+func eachField(v reflect.Value, prefix, envPrefix string, names namesFunc, fn func(f field)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key := sf.Tag.Get("config")
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		env := sf.Tag.Get("env")
+		if env != "" {
+			env = envPrefix + env
+		}
+		switch sf.Type.Kind() {
+		case reflect.Struct:
+			eachField(v.Field(i), key, envPrefix, names, fn)
+			continue
+		case reflect.Map:
+			m := v.Field(i)
+			if m.IsNil() {
+				m.Set(reflect.MakeMap(sf.Type))
+			}
+			for _, name := range names(key, sf.Tag, m) {
+				elem := m.MapIndex(reflect.ValueOf(name))
+				if !elem.IsValid() {
+					elem = reflect.New(sf.Type.Elem().Elem())
+					m.SetMapIndex(reflect.ValueOf(name), elem)
+				}
+				eachField(elem.Elem(), key+"."+name, env+"_"+envName(name)+"_", names, fn)
+			}
+			continue
+		}
+		fn(field{key: key, env: env, tag: sf.Tag, value: v.Field(i)})
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setField convertit une valeur textuelle vers le type du champ
+func setField(v reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+	if v.Type() == durationType {
+		if raw == "" {
+			v.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("durée invalide %q, ex: 30s, 2m", raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("booléen invalide %q, attendu true ou false", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("entier invalide %q", raw)
+		}
+		v.SetInt(n)
+	default:
+		return fmt.Errorf("type %s non supporté", v.Type())
+	}
+	return nil
+}
+
+func formatField(v reflect.Value) string {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// readConfigFile lit un fichier YAML (.yaml, .yml) ou TOML (.toml) et
+// l'aplatit en clés "section.cle" avec des valeurs textuelles
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("lecture du fichier de configuration: %w", err)
+	}
+
+	tree := map[string]interface{}{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("format de configuration %q non supporté, attendu .yaml, .yml ou .toml", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	flat := make(map[string]string)
+	flatten(tree, "", flat)
+	return flat, nil
+}
+
+func flatten(tree map[string]interface{}, prefix string, out map[string]string) {
+	for key, value := range tree {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flatten(v, key, out)
+		case nil:
+			out[key] = ""
+		default:
+			out[key] = fmt.Sprint(v)
+		}
+	}
+}
@@ -1,49 +1,96 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"strings"
+	"sync/atomic"
 
-	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/migrations"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-// connexion à la base de donnée
-var DB *gorm.DB
+// Compteur des bases de test, chaque appel à OpenTestDB ouvre une base distincte
+var testDBCount int64
 
-// initialise la connexion à la DBB
-func InitDB(testing bool) {
-	var err error
-
-	if testing {
-		DB, err = gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+// OpenDB ouvre la connexion à la BDD configurée et applique les migrations
+// en attente, sauf si AutoMigrate est désactivé
+func OpenDB(cfg DatabaseConfig) (*gorm.DB, error) {
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		return nil, err
 	}
+	if !cfg.AutoMigrate {
+		return db, nil
+	}
+	if err := Migrate(db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
 
-	dbType := GetEnv("DB_TYPE", "DB_TYPE")
-
-	if dbType == "postgres" {
+// ConnectDB ouvre la connexion à la BDD configurée sans appliquer les migrations
+func ConnectDB(cfg DatabaseConfig) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	if cfg.Type == "postgres" {
 		dsn := fmt.Sprintf(
-			"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-			GetEnv("DB_HOST", "DB_HOST"),
-			GetEnv("DB_USER", "DB_USER"),
-			GetEnv("DB_PASSWORD", "DB_PASSWORD"),
-			GetEnv("DB_NAME", "DB_NAME"),
-			GetEnv("DB_PORT", "DB_PORT"),
+			"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
+			dsnValue(cfg.Host),
+			dsnValue(cfg.User),
+			dsnValue(cfg.Password),
+			dsnValue(cfg.Name),
+			cfg.Port,
+			dsnValue(cfg.SSLMode),
 		)
-		DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		dialector = postgres.Open(dsn)
 	} else {
-		DB, err = gorm.Open(sqlite.Open("db.db"), &gorm.Config{})
+		dialector = sqlite.Open(cfg.Path)
 	}
 
+	db, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		return nil, err
 	}
-
 	log.Println("Base de connecté avec succès !")
+	return db, nil
+}
+
+// dsnValue protège une valeur du DSN PostgreSQL, ex: un mot de passe contenant un espace
+func dsnValue(v string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
 
-	// Applicaiton des migrations
-	DB.AutoMigrate(&models.User{}, &models.Task{})
+// OpenTestDB ouvre une base SQLite en mémoire propre à l'appelant,
+// deux instances de l'API dans le même processus ne partagent donc pas leurs données
+func OpenTestDB() (*gorm.DB, error) {
+	n := atomic.AddInt64(&testDBCount, 1)
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:todo_test_%d?mode=memory&cache=shared", n)), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	if err := Migrate(db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Migrate applique les migrations SQL versionnées en attente.
+// Un verrou empêche deux instances démarrées en même temps de migrer simultanément.
+func Migrate(db *gorm.DB) error {
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, mig := range applied {
+		log.Printf("Migration %04d_%s appliquée", mig.Version, mig.Name)
+	}
+	return nil
 }
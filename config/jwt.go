@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"YoannLetacq/todo-api.git/internal/utils"
+)
+
+// Keys construit le jeu de clés JWT : la clé active, puis les anciennes clés encore
+// acceptées en vérification. Les clés publiques de PreviousPublicKeys sont lues
+// depuis leurs fichiers PEM.
+func (j JWTConfig) Keys() (*utils.JWTKeys, error) {
+	active := utils.JWTKey{ID: j.KeyID, Secret: []byte(j.Secret)}
+	if j.Algorithm != JWTAlgHS256 {
+		var err error
+		active, err = utils.ParseJWTKeyPEM(j.KeyID, []byte(j.PrivateKey))
+		if err != nil {
+			return nil, err
+		}
+		if active.Algorithm != j.Algorithm {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY: clé %s, JWT_ALGORITHM=%s attendu", active.Algorithm, j.Algorithm)
+		}
+	}
+
+	previous, err := j.ParsePreviousKeys()
+	if err != nil {
+		return nil, err
+	}
+	var keys []utils.JWTKey
+	for _, kid := range sortedKeys(previous) {
+		keys = append(keys, utils.JWTKey{ID: kid, Secret: []byte(previous[kid])})
+	}
+
+	publicKeys, err := j.ParsePreviousPublicKeys()
+	if err != nil {
+		return nil, err
+	}
+	for _, kid := range sortedKeys(publicKeys) {
+		data, err := os.ReadFile(publicKeys[kid])
+		if err != nil {
+			return nil, fmt.Errorf("JWT_PREVIOUS_PUBLIC_KEYS: %w", err)
+		}
+		key, err := utils.ParseJWTKeyPEM(kid, data)
+		if err != nil {
+			return nil, err
+		}
+		// Seule la partie publique d'une ancienne clé est conservée
+		key.PrivateKey = nil
+		keys = append(keys, key)
+	}
+	return utils.NewJWTKeys(active, keys...)
+}
+
+// sortedKeys retourne les kids triés, pour un ordre des clés stable d'un démarrage à l'autre
+func sortedKeys(m map[string]string) []string {
+	kids := make([]string, 0, len(m))
+	for kid := range m {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	return kids
+}
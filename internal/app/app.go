@@ -0,0 +1,131 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"YoannLetacq/todo-api.git/config"
+	"YoannLetacq/todo-api.git/internal/handlers"
+	"YoannLetacq/todo-api.git/internal/ratelimit"
+	"YoannLetacq/todo-api.git/internal/realtime"
+	"YoannLetacq/todo-api.git/internal/repository"
+	"YoannLetacq/todo-api.git/internal/services"
+	"YoannLetacq/todo-api.git/internal/storage"
+	"YoannLetacq/todo-api.git/internal/utils"
+	"YoannLetacq/todo-api.git/routes"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Options sont les dépendances externes d'une instance en plus de la base
+type Options struct {
+	// Blobs stocke le contenu des pièces jointes
+	Blobs storage.BlobStore
+	// JWTKeys signe et vérifie les tokens de connexion
+	JWTKeys *utils.JWTKeys
+	// MaxAttachmentSize est la taille maximale d'une pièce jointe, 0 pour la valeur par défaut
+	MaxAttachmentSize int64
+	// RequestTimeout est la durée maximale de traitement d'une requête, 0 pour aucune limite
+	RequestTimeout time.Duration
+	// OIDCProviders sont les fournisseurs d'identité externes par nom, aucun par défaut
+	OIDCProviders map[string]*config.OIDCProviderConfig
+	// RateLimitStore conserve les quotas de requêtes, nil pour ne pas limiter
+	RateLimitStore ratelimit.Store
+	// RateLimits sont les quotas des classes de routes, utilisés avec RateLimitStore
+	RateLimits ratelimit.Policies
+	// DisableLegacyRoutes retire les routes sans préfixe /api/v1, conservées par défaut
+	DisableLegacyRoutes bool
+	// LegacySunset est la date de retrait des routes sans préfixe, zéro si elle n'est pas fixée
+	LegacySunset time.Time
+	// TrustedProxies sont les reverse proxies dont les en-têtes donnent l'IP du client, aucun par défaut
+	TrustedProxies []string
+}
+
+// App est une instance complète de l'API. Chaque instance a sa propre base,
+// son hub temps réel et son routeur, plusieurs instances peuvent donc
+// cohabiter dans le même processus.
+type App struct {
+	DB     *gorm.DB
+	Hub    realtime.Hub
+	Router *gin.Engine
+
+	realtime *handlers.RealtimeHandler
+}
+
+// New construit les repositories, les services, les handlers et le routeur
+func New(db *gorm.DB, opts Options) (*App, error) {
+	if db == nil {
+		return nil, errors.New("app: base de données manquante")
+	}
+	if opts.Blobs == nil {
+		return nil, errors.New("app: stockage des pièces jointes manquant")
+	}
+	if opts.JWTKeys == nil {
+		return nil, errors.New("app: clés JWT manquantes")
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	taskRepo := repository.NewTaskRepository(db)
+	searcher, err := repository.NewSearcher(db)
+	if err != nil {
+		return nil, err
+	}
+
+	userService := services.NewUserService(userRepo)
+	mfaService := services.NewMFAService(repository.NewMFARepository(db), userRepo)
+	sessionService := services.NewSessionService(repository.NewSessionRepository(db))
+	oidcService := services.NewOIDCService(userRepo, repository.NewIdentityRepository(db), opts.OIDCProviders)
+	apiTokenService := services.NewAPITokenService(repository.NewAPITokenRepository(db))
+	taskService := services.NewTaskService(taskRepo, searcher)
+	calendarService := services.NewCalendarService(repository.NewCalendarFeedRepository(db), taskRepo)
+	commentService := services.NewCommentService(repository.NewCommentRepository(db), taskRepo, userRepo)
+	notificationService := services.NewNotificationService(repository.NewNotificationRepository(db))
+	attachmentService := services.NewAttachmentService(repository.NewAttachmentRepository(db), taskRepo, opts.Blobs, opts.MaxAttachmentSize)
+
+	hub := realtime.NewHub()
+	realtimeHandler := handlers.NewRealtimeHandler(hub)
+
+	router := routes.SetupRouter(routes.Handlers{
+		Users:         handlers.NewUserHandler(userService, mfaService, sessionService, opts.JWTKeys),
+		MFA:           handlers.NewMFAHandler(mfaService, sessionService, opts.JWTKeys),
+		Sessions:      handlers.NewSessionHandler(sessionService, realtimeHandler),
+		OIDC:          handlers.NewOIDCHandler(oidcService, mfaService, sessionService, opts.JWTKeys),
+		APITokens:     handlers.NewAPITokenHandler(apiTokenService),
+		Tasks:         handlers.NewTaskHandler(taskService, attachmentService, hub),
+		Comments:      handlers.NewCommentHandler(commentService),
+		Attachments:   handlers.NewAttachmentHandler(attachmentService),
+		Notifications: handlers.NewNotificationHandler(notificationService),
+		Calendar:      handlers.NewCalendarHandler(calendarService),
+		Realtime:      realtimeHandler,
+		Idempotency:   handlers.NewIdempotencyHandler(services.NewIdempotencyService(repository.NewIdempotencyRepository(db)), handlers.MaxBodySize(attachmentService)),
+	}, routes.Config{
+		RequestTimeout:      opts.RequestTimeout,
+		JWTKeys:             opts.JWTKeys,
+		APITokens:           apiTokenService,
+		Sessions:            sessionService,
+		RateLimitStore:      opts.RateLimitStore,
+		RateLimits:          opts.RateLimits,
+		DisableLegacyRoutes: opts.DisableLegacyRoutes,
+		LegacySunset:        opts.LegacySunset,
+		TrustedProxies:      opts.TrustedProxies,
+	})
+
+	return &App{DB: db, Hub: hub, Router: router, realtime: realtimeHandler}, nil
+}
+
+// Shutdown arrête les tâches de fond de l'instance puis ferme le pool de connexions.
+// Il est appelé après http.Server.Shutdown, une fois les requêtes en cours terminées.
+func (a *App) Shutdown(ctx context.Context) error {
+	// Les websockets sont des connexions détournées que le serveur HTTP n'attend pas
+	if err := a.realtime.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	sqlDB, err := a.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
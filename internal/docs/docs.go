@@ -0,0 +1,16 @@
+// Package docs contient la spécification OpenAPI de l'API et la page de documentation
+// qui l'affiche. La spécification est écrite à la main dans openapi.json et vérifiée
+// contre les réponses réelles par tests/openapi_test.go.
+package docs
+
+import _ "embed"
+
+// OpenAPI est la spécification OpenAPI 3.1 de l'API, au format JSON
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// UI est la page Swagger UI qui affiche la spécification servie sur openapi.json
+//
+//go:embed index.html
+var UI []byte
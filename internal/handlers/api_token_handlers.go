@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/middleware"
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APITokenHandler regroupe les handlers des tokens d'accès personnels
+type APITokenHandler struct {
+	tokens services.APITokenService
+}
+
+// NewAPITokenHandler cree les handlers des tokens d'accès à partir de leur service
+func NewAPITokenHandler(tokens services.APITokenService) *APITokenHandler {
+	return &APITokenHandler{tokens: tokens}
+}
+
+type apiTokenRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required"`
+	// ExpiresInDays vaut 30 jours s'il est absent
+	ExpiresInDays int `json:"expires_in_days"`
+}
+
+// apiTokenResponse est la vue publique d'un token, sans sa valeur ni son hash
+type apiTokenResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newAPITokenResponse(t *models.APIToken) apiTokenResponse {
+	return apiTokenResponse{
+		ID:         t.ID,
+		Name:       t.Name,
+		Scopes:     t.Scopes(),
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+// CreateAPIToken crée un token d'accès personnel POST /me/tokens
+// Le token n'est retourné que dans cette réponse.
+func (h *APITokenHandler) CreateAPIToken(c *gin.Context) {
+	uid, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var req apiTokenRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides."})
+		return
+	}
+
+	// Un token d'accès ne peut pas avoir plus de droits que la connexion qui le crée
+	for _, scope := range req.Scopes {
+		if models.ValidScope(scope) && !middleware.HasScope(c, scope) {
+			middleware.InsufficientScope(c, scope)
+			return
+		}
+	}
+
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	token, record, err := h.tokens.CreateToken(c.Request.Context(), uid, req.Name, req.Scopes, ttl)
+	if err != nil {
+		apiTokenError(c, err, "Echec de la création du token d'accès.")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Token d'accès créé ! Copiez-le maintenant, il ne sera plus affiché.",
+		"token":     token,
+		"api_token": newAPITokenResponse(record),
+	})
+}
+
+// GetAPITokens liste les tokens d'accès de l'utilisateur GET /me/tokens
+func (h *APITokenHandler) GetAPITokens(c *gin.Context) {
+	uid, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.tokens.ListTokens(c.Request.Context(), uid)
+	if err != nil {
+		apiTokenError(c, err, "Echec de la recuperation des tokens d'accès.")
+		return
+	}
+
+	out := make([]apiTokenResponse, len(tokens))
+	for i := range tokens {
+		out[i] = newAPITokenResponse(&tokens[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"api_tokens": out})
+}
+
+// DeleteAPIToken révoque un token d'accès DELETE /me/tokens/:id
+func (h *APITokenHandler) DeleteAPIToken(c *gin.Context) {
+	uid, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	tid, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de token invalide"})
+		return
+	}
+
+	if err := h.tokens.RevokeToken(c.Request.Context(), uid, uint(tid)); err != nil {
+		apiTokenError(c, err, "Echec de la révocation du token d'accès.")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Token d'accès révoqué."})
+}
+
+// apiTokenError traduit une erreur du service des tokens d'accès en réponse HTTP
+func apiTokenError(c *gin.Context, err error, message string) {
+	if requestCanceled(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrAPITokenNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token d'accès introuvable."})
+	case errors.Is(err, services.ErrInvalidAPITokenRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+		log.Println("Erreur sur les tokens d'accès:", err)
+	}
+}
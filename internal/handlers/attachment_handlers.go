@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"YoannLetacq/todo-api.git/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Marge accordée à l'enveloppe multipart au-delà de la taille du fichier
+const multipartOverhead = 1 << 20
+
+// AttachmentHandler regroupe les handlers des pièces jointes
+type AttachmentHandler struct {
+	attachments services.AttachmentService
+}
+
+// NewAttachmentHandler cree les handlers des pièces jointes à partir de leur service
+func NewAttachmentHandler(attachments services.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{attachments: attachments}
+}
+
+// GetAttachments liste les pièces jointes d'une tâche GET /tasks/:id/attachments
+func (h *AttachmentHandler) GetAttachments(c *gin.Context) {
+	uid, tid, ok := taskParams(c)
+	if !ok {
+		return
+	}
+
+	attachments, err := h.attachments.ListAttachments(c.Request.Context(), uid, tid)
+	if err != nil {
+		h.attachmentError(c, err, "Echec de la recuperation des pièces jointes.")
+		return
+	}
+
+	out := make([]attachmentResponse, len(attachments))
+	for i := range attachments {
+		out[i] = newAttachmentResponse(&attachments[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"attachments": out})
+}
+
+// UploadAttachment ajoute une pièce jointe POST /tasks/:id/attachments
+// Le fichier est envoyé dans le champ "file" d'un formulaire multipart.
+func (h *AttachmentHandler) UploadAttachment(c *gin.Context) {
+	uid, tid, ok := taskParams(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.attachments.MaxSize()+multipartOverhead)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			h.attachmentError(c, services.ErrAttachmentTooLarge, "")
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fichier manquant."})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fichier illisible."})
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.attachments.UploadAttachment(c.Request.Context(), uid, tid, header.Filename, file)
+	if err != nil {
+		h.attachmentError(c, err, "Echec de l'envoi de la pièce jointe.")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Pièce jointe ajoutée !", "attachment": newAttachmentResponse(attachment)})
+}
+
+// DownloadAttachment télécharge une pièce jointe GET /tasks/:id/attachments/:attachment_id
+func (h *AttachmentHandler) DownloadAttachment(c *gin.Context) {
+	uid, tid, ok := taskParams(c)
+	if !ok {
+		return
+	}
+
+	aid, err := strconv.ParseUint(c.Param("attachment_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de pièce jointe invalide"})
+		return
+	}
+
+	attachment, content, err := h.attachments.OpenAttachment(c.Request.Context(), uid, tid, uint(aid))
+	if err != nil {
+		h.attachmentError(c, err, "Echec du téléchargement de la pièce jointe.")
+		return
+	}
+	defer content.Close()
+
+	// FormatMediaType encode les noms non ASCII en filename* (RFC 6266)
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, content, map[string]string{
+		"Content-Disposition":    disposition,
+		"X-Content-Type-Options": "nosniff",
+		"ETag":                   `"` + attachment.SHA256 + `"`,
+	})
+}
+
+// DeleteAttachment supprime une pièce jointe DELETE /tasks/:id/attachments/:attachment_id
+func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
+	uid, tid, ok := taskParams(c)
+	if !ok {
+		return
+	}
+
+	aid, err := strconv.ParseUint(c.Param("attachment_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de pièce jointe invalide"})
+		return
+	}
+
+	if err := h.attachments.DeleteAttachment(c.Request.Context(), uid, tid, uint(aid)); err != nil {
+		h.attachmentError(c, err, "Echec de la suppression de la pièce jointe.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Pièce jointe supprimée."})
+}
+
+// attachmentError traduit une erreur du service de pièces jointes en réponse HTTP
+func (h *AttachmentHandler) attachmentError(c *gin.Context, err error, message string) {
+	if requestCanceled(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrTaskNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tâche introuvable."})
+	case errors.Is(err, services.ErrAttachmentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pièce jointe introuvable."})
+	case errors.Is(err, services.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cette tâche ne vous appartiens pas."})
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Fichier trop volumineux (max " + strconv.FormatInt(h.attachments.MaxSize(), 10) + " octets)."})
+	case errors.Is(err, services.ErrAttachmentEmpty):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fichier vide."})
+	case errors.Is(err, services.ErrUnsupportedMediaType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Type de fichier non autorisé (images, PDF ou texte)."})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+		log.Println("Erreur sur les pièces jointes:", err)
+	}
+}
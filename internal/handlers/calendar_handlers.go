@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"YoannLetacq/todo-api.git/internal/services"
+	"YoannLetacq/todo-api.git/internal/transfer"
+	"YoannLetacq/todo-api.git/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CalendarHandler regroupe les handlers du flux iCalendar
+type CalendarHandler struct {
+	calendar services.CalendarService
+}
+
+// NewCalendarHandler cree les handlers du flux calendrier à partir de leur service
+func NewCalendarHandler(calendar services.CalendarService) *CalendarHandler {
+	return &CalendarHandler{calendar: calendar}
+}
+
+// CreateCalendarFeed génère l'URL d'abonnement iCalendar POST /calendar/token
+// Un nouvel appel révoque l'URL précédente.
+func (h *CalendarHandler) CreateCalendarFeed(c *gin.Context) {
+	userID, err := ExtractUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non autorise." + err.Error()})
+		return
+	}
+
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "userID invalide"})
+		return
+	}
+
+	token, err := h.calendar.RotateFeedToken(c.Request.Context(), uint(uid))
+	if err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Echec de la création du flux calendrier."})
+		log.Println("Erreur lors de la création du flux calendrier:", err)
+		return
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	// Le flux est servi sous le même préfixe de version que cette route
+	feedPath := strings.TrimSuffix(c.Request.URL.Path, "/token") + "/feed/"
+	url := scheme + "://" + c.Request.Host + feedPath + token
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Flux calendrier créé !", "url": url})
+}
+
+// DeleteCalendarFeed révoque l'URL d'abonnement iCalendar DELETE /calendar/token
+func (h *CalendarHandler) DeleteCalendarFeed(c *gin.Context) {
+	userID, err := ExtractUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non autorise." + err.Error()})
+		return
+	}
+
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "userID invalide"})
+		return
+	}
+
+	if err := h.calendar.RevokeFeed(c.Request.Context(), uint(uid)); err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Echec de la révocation du flux calendrier."})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Flux calendrier révoqué."})
+}
+
+// GetCalendarFeed sert le flux iCalendar en lecture seule GET /calendar/feed/:token
+// Les tâches avec échéance sont des VEVENT, les autres des VTODO.
+// Le flux supporte If-None-Match pour que les clients puissent interroger souvent.
+func (h *CalendarHandler) GetCalendarFeed(c *gin.Context) {
+	userID, err := h.calendar.ResolveFeedToken(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flux introuvable."})
+		return
+	}
+
+	etag, err := h.calendar.FeedETag(c.Request.Context(), userID)
+	if err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Echec de la génération du flux."})
+		log.Println("Erreur lors du calcul de l'ETag du flux:", err)
+		return
+	}
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	tasks, err := h.calendar.GetFeedTasks(c.Request.Context(), userID)
+	if err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Echec de la génération du flux."})
+		log.Println("Erreur lors de la récupération des tâches du flux:", err)
+		return
+	}
+
+	c.Header("Content-Type", transfer.ContentType(transfer.FormatICS))
+	c.Status(http.StatusOK)
+
+	writer, err := utils.NewICalWriter(c.Writer, transfer.ProdID)
+	if err != nil {
+		return
+	}
+	for i := range tasks {
+		component := transfer.TaskToVTodo(&tasks[i])
+		if tasks[i].DueDate != nil {
+			component = transfer.TaskToVEvent(&tasks[i])
+		}
+		if err := writer.WriteComponent(component); err != nil {
+			return
+		}
+	}
+	writer.Close()
+}
+
+// etagMatches compare un header If-None-Match à l'ETag courant, comparaison faible (RFC 9110)
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
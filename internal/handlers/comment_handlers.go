@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"YoannLetacq/todo-api.git/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CommentHandler regroupe les handlers des commentaires
+type CommentHandler struct {
+	comments services.CommentService
+}
+
+// NewCommentHandler cree les handlers des commentaires à partir de leur service
+func NewCommentHandler(comments services.CommentService) *CommentHandler {
+	return &CommentHandler{comments: comments}
+}
+
+// commentRequest est le corps attendu pour créer ou modifier un commentaire
+type commentRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// GetComments liste les commentaires d'une tâche GET /tasks/:id/comments
+func (h *CommentHandler) GetComments(c *gin.Context) {
+	uid, tid, ok := taskParams(c)
+	if !ok {
+		return
+	}
+
+	comments, err := h.comments.ListComments(c.Request.Context(), uid, tid)
+	if err != nil {
+		commentError(c, err, "Echec de la recuperation des commentaires.")
+		return
+	}
+
+	out := make([]commentResponse, len(comments))
+	for i := range comments {
+		out[i] = newCommentResponse(&comments[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"comments": out})
+}
+
+// CreateComment ajoute un commentaire à une tâche POST /tasks/:id/comments
+// Les @username mentionnés qui ont accès à la tâche reçoivent une notification.
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	uid, tid, ok := taskParams(c)
+	if !ok {
+		return
+	}
+
+	var req commentRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides."})
+		return
+	}
+
+	comment, err := h.comments.AddComment(c.Request.Context(), uid, tid, req.Body)
+	if err != nil {
+		commentError(c, err, "Echec de la création du commentaire.")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Commentaire ajouté !", "comment": newCommentResponse(comment)})
+}
+
+// UpdateComment modifie un commentaire PUT /tasks/:id/comments/:comment_id
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	uid, tid, ok := taskParams(c)
+	if !ok {
+		return
+	}
+
+	cid, err := strconv.ParseUint(c.Param("comment_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de commentaire invalide"})
+		return
+	}
+
+	var req commentRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides."})
+		return
+	}
+
+	comment, err := h.comments.EditComment(c.Request.Context(), uid, tid, uint(cid), req.Body)
+	if err != nil {
+		commentError(c, err, "Echec de la mise à jour du commentaire.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Commentaire mis à jour !", "comment": newCommentResponse(comment)})
+}
+
+// DeleteComment supprime un commentaire DELETE /tasks/:id/comments/:comment_id
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	uid, tid, ok := taskParams(c)
+	if !ok {
+		return
+	}
+
+	cid, err := strconv.ParseUint(c.Param("comment_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de commentaire invalide"})
+		return
+	}
+
+	if err := h.comments.DeleteComment(c.Request.Context(), uid, tid, uint(cid)); err != nil {
+		commentError(c, err, "Echec de la suppression du commentaire.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Commentaire supprimé."})
+}
+
+// commentError traduit une erreur du service de commentaires en réponse HTTP
+func commentError(c *gin.Context, err error, message string) {
+	if requestCanceled(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrTaskNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tâche introuvable."})
+	case errors.Is(err, services.ErrCommentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Commentaire introuvable."})
+	case errors.Is(err, services.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Action non autorisée sur ce commentaire."})
+	case errors.Is(err, services.ErrInvalidComment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Le commentaire doit contenir entre 1 et 5000 caractères."})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+		log.Println("Erreur sur les commentaires:", err)
+	}
+}
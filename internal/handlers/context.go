@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"YoannLetacq/todo-api.git/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Statut non standard (nginx) d'une requête abandonnée par le client
+const statusClientClosedRequest = 499
+
+// requestCanceled répond à la place du handler quand l'erreur vient de l'expiration
+// ou de l'annulation du contexte de la requête : 504 si le délai est dépassé,
+// 499 sans corps si le client s'est déconnecté. Ces cas ne sont pas des erreurs
+// serveur et ne sont pas journalisés. Retourne false pour les autres erreurs.
+func requestCanceled(c *gin.Context, err error) bool {
+	ctxErr := c.Request.Context().Err()
+	if ctxErr == nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(ctxErr, context.DeadlineExceeded) || (ctxErr == nil && errors.Is(err, context.DeadlineExceeded)) {
+		c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{"error": "Délai de traitement dépassé."})
+		return true
+	}
+	c.AbortWithStatus(statusClientClosedRequest)
+	return true
+}
+
+// sessionUserID retourne l'utilisateur d'une requête authentifiée par une connexion.
+// Les tokens d'accès personnels sont refusés pour la gestion du compte (tokens d'accès,
+// double authentification) : un token volé ne doit pas permettre d'étendre ses droits.
+func sessionUserID(c *gin.Context) (uint, bool) {
+	userID, err := ExtractUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non autorise." + err.Error()})
+		return 0, false
+	}
+	if c.GetString(middleware.AuthTypeKey) == middleware.AuthTypeAPIToken {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cette action nécessite une connexion, les tokens d'accès ne sont pas acceptés."})
+		return 0, false
+	}
+
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "userID invalide"})
+		return 0, false
+	}
+	return uint(uid), true
+}
+
+// taskParams extrait l'utilisateur authentifié et l'ID de la tâche des routes
+// /tasks/:id/..., écrit la réponse d'erreur et retourne false en cas d'échec
+func taskParams(c *gin.Context) (uint, uint, bool) {
+	userID, err := ExtractUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non autorise." + err.Error()})
+		return 0, 0, false
+	}
+
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "userID invalide"})
+		return 0, 0, false
+	}
+
+	tid, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de tâche invalide"})
+		return 0, 0, false
+	}
+
+	return uint(uid), uint(tid), true
+}
@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"net/http"
+
+	"YoannLetacq/todo-api.git/internal/docs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPISpec retourne la spécification OpenAPI de l'API GET /openapi.json
+func OpenAPISpec(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Data(http.StatusOK, "application/json; charset=utf-8", docs.OpenAPI)
+}
+
+// DocsUI affiche la documentation interactive de l'API GET /docs
+func DocsUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", docs.UI)
+}
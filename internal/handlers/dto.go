@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/repository"
+	"YoannLetacq/todo-api.git/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// Les requêtes sont lues dans des DTO dédiés plutôt que dans les modèles GORM : un client
+// ne peut pas affecter l'ID, le propriétaire ou les dates d'un enregistrement. Les réponses
+// sont construites par les fonctions newXResponse, en snake_case et sans champ interne.
+
+// bindJSON lit le corps JSON de la requête dans obj puis valide ses tags binding.
+// Contrairement à ShouldBindJSON, un champ inconnu est refusé : une faute de frappe
+// dans le nom d'un champ n'est pas ignorée silencieusement. Le corps doit contenir
+// une seule valeur JSON, des données après celle-ci sont refusées.
+func bindJSON(c *gin.Context, obj interface{}) error {
+	if c.Request.Body == nil {
+		return errors.New("corps de la requête vide")
+	}
+	dec := json.NewDecoder(c.Request.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(obj); err != nil {
+		return err
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return errors.New("données en trop après le corps JSON")
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// registerRequest est le corps de POST /register
+type registerRequest struct {
+	Username string `json:"username" binding:"required"`
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// loginRequest est le corps de POST /login
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// taskRequest est le corps de création et de mise à jour d'une tâche
+type taskRequest struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	DueDate     *time.Time `json:"due_date"`
+}
+
+// apply copie les champs modifiables de la requête dans la tâche, le statut
+// n'est modifié que s'il est fourni
+func (r *taskRequest) apply(task *models.Task) {
+	task.Title = r.Title
+	task.Description = r.Description
+	task.DueDate = r.DueDate
+	if r.Status != "" {
+		task.Status = r.Status
+	}
+}
+
+// taskResponse est la vue publique d'une tâche
+type taskResponse struct {
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	DueDate     *time.Time `json:"due_date"`
+	UserID      uint       `json:"user_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func newTaskResponse(t *models.Task) taskResponse {
+	return taskResponse{
+		ID:          t.ID,
+		Title:       t.Title,
+		Description: t.Description,
+		Status:      t.Status,
+		DueDate:     t.DueDate,
+		UserID:      t.UserID,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+	}
+}
+
+func newTaskResponses(tasks []models.Task) []taskResponse {
+	out := make([]taskResponse, len(tasks))
+	for i := range tasks {
+		out[i] = newTaskResponse(&tasks[i])
+	}
+	return out
+}
+
+// bulkResultResponse est le résultat d'une opération d'un lot
+type bulkResultResponse struct {
+	Index int           `json:"index"`
+	Op    string        `json:"op"`
+	OK    bool          `json:"ok"`
+	Error string        `json:"error,omitempty"`
+	Task  *taskResponse `json:"task,omitempty"`
+}
+
+func newBulkResultResponses(results []services.BulkResult) []bulkResultResponse {
+	out := make([]bulkResultResponse, len(results))
+	for i, r := range results {
+		out[i] = bulkResultResponse{Index: r.Index, Op: r.Op, OK: r.OK, Error: r.Error}
+		if r.Task != nil {
+			task := newTaskResponse(r.Task)
+			out[i].Task = &task
+		}
+	}
+	return out
+}
+
+// searchResultResponse est une tâche trouvée par la recherche, avec son extrait surligné
+type searchResultResponse struct {
+	Task    taskResponse `json:"task"`
+	Rank    float64      `json:"rank"`
+	Snippet string       `json:"snippet"`
+}
+
+func newSearchResultResponses(results []repository.SearchResult) []searchResultResponse {
+	out := make([]searchResultResponse, len(results))
+	for i := range results {
+		out[i] = searchResultResponse{Task: newTaskResponse(&results[i].Task), Rank: results[i].Rank, Snippet: results[i].Snippet}
+	}
+	return out
+}
+
+// commentResponse est la vue publique d'un commentaire
+type commentResponse struct {
+	ID        uint       `json:"id"`
+	TaskID    uint       `json:"task_id"`
+	AuthorID  uint       `json:"author_id"`
+	Body      string     `json:"body"`
+	EditedAt  *time.Time `json:"edited_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func newCommentResponse(c *models.Comment) commentResponse {
+	return commentResponse{
+		ID:        c.ID,
+		TaskID:    c.TaskID,
+		AuthorID:  c.AuthorID,
+		Body:      c.Body,
+		EditedAt:  c.EditedAt,
+		CreatedAt: c.CreatedAt,
+	}
+}
+
+// attachmentResponse est la vue publique d'une pièce jointe, sans sa clé de stockage
+type attachmentResponse struct {
+	ID          uint      `json:"id"`
+	TaskID      uint      `json:"task_id"`
+	UploaderID  uint      `json:"uploader_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func newAttachmentResponse(a *models.Attachment) attachmentResponse {
+	return attachmentResponse{
+		ID:          a.ID,
+		TaskID:      a.TaskID,
+		UploaderID:  a.UploaderID,
+		Filename:    a.Filename,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		SHA256:      a.SHA256,
+		CreatedAt:   a.CreatedAt,
+	}
+}
+
+// notificationResponse est la vue publique d'une notification
+type notificationResponse struct {
+	ID        uint       `json:"id"`
+	Type      string     `json:"type"`
+	ActorID   uint       `json:"actor_id"`
+	TaskID    uint       `json:"task_id"`
+	CommentID uint       `json:"comment_id"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func newNotificationResponse(n *models.Notification) notificationResponse {
+	return notificationResponse{
+		ID:        n.ID,
+		Type:      n.Type,
+		ActorID:   n.ActorID,
+		TaskID:    n.TaskID,
+		CommentID: n.CommentID,
+		ReadAt:    n.ReadAt,
+		CreatedAt: n.CreatedAt,
+	}
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"YoannLetacq/todo-api.git/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader est l'en-tête qui identifie une requête rejouable
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marque une réponse rejouée depuis une requête précédente
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// IdempotencyHandler rend rejouables les requêtes qui modifient des données
+type IdempotencyHandler struct {
+	keys services.IdempotencyService
+	// maxBodySize est la taille maximale du corps lu pour calculer l'empreinte d'une requête
+	maxBodySize int64
+}
+
+// NewIdempotencyHandler cree le middleware d'idempotence à partir de son service.
+// maxBodySize est la taille maximale des corps de requête acceptés par les routes, voir MaxBodySize.
+func NewIdempotencyHandler(keys services.IdempotencyService, maxBodySize int64) *IdempotencyHandler {
+	return &IdempotencyHandler{keys: keys, maxBodySize: maxBodySize}
+}
+
+// MaxBodySize retourne la taille du plus grand corps de requête accepté par une route :
+// une pièce jointe avec son enveloppe multipart, ou un fichier d'import
+func MaxBodySize(attachments services.AttachmentService) int64 {
+	return max(attachments.MaxSize()+multipartOverhead, maxImportSize)
+}
+
+// recordingWriter conserve une copie du corps de la réponse pour la rejouer
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotent retourne le middleware des requêtes POST, PUT, PATCH et DELETE authentifiées
+// qui portent un en-tête Idempotency-Key, pour les routes montées sous basePath.
+// La première requête est exécutée et sa réponse conservée 24 h ; une requête suivante
+// avec la même clé, la même route et le même corps reçoit la même réponse sans être
+// exécutée, avec l'en-tête Idempotent-Replayed, et avec un autre corps elle est refusée
+// en 422. La route est comparée sans basePath : une requête retentée sous /api/v1 ou sous
+// l'ancien chemin sans préfixe est la même requête. Les réponses 5xx, 401, 403 et 429
+// ne sont pas conservées : la requête peut être retentée avec la même clé.
+func (h *IdempotencyHandler) Idempotent(basePath string) gin.HandlerFunc {
+	basePath = strings.TrimSuffix(basePath, "/")
+	return func(c *gin.Context) {
+		h.idempotent(c, basePath)
+	}
+}
+
+func (h *IdempotencyHandler) idempotent(c *gin.Context, basePath string) {
+	key := c.GetHeader(IdempotencyKeyHeader)
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		key = ""
+	}
+	if key == "" {
+		c.Next()
+		return
+	}
+	// Les clés sont propres à chaque utilisateur, le handler refuse les requêtes anonymes
+	userID, err := ExtractUserID(c)
+	if err != nil {
+		c.Next()
+		return
+	}
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "userID invalide"})
+		return
+	}
+
+	// Le corps est lu avant les limites propres à chaque route, il est donc borné par la plus grande
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, h.maxBodySize))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Corps de la requête trop volumineux."})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Corps de la requête illisible."})
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	k, err := h.keys.Reserve(c.Request.Context(), uint(uid), key, requestFingerprint(c, basePath, body))
+	if err != nil {
+		idempotencyError(c, err, "Echec de la vérification de la clé d'idempotence.")
+		return
+	}
+	if k.Completed() {
+		c.Header(IdempotentReplayedHeader, "true")
+		if k.ContentType != "" {
+			c.Header("Content-Type", k.ContentType)
+		}
+		c.Status(k.StatusCode)
+		_, _ = c.Writer.Write(k.ResponseBody)
+		c.Abort()
+		return
+	}
+
+	w := &recordingWriter{ResponseWriter: c.Writer}
+	c.Writer = w
+	c.Next()
+
+	// La réponse est enregistrée même si le client s'est déconnecté entre-temps :
+	// c'est précisément la requête qu'il va retenter
+	ctx := context.WithoutCancel(c.Request.Context())
+	status := w.Status()
+	if replayableStatus(status) {
+		err = h.keys.Complete(ctx, k.ID, status, w.Header().Get("Content-Type"), w.body.Bytes())
+	} else {
+		err = h.keys.Release(ctx, k.ID)
+	}
+	if err != nil {
+		log.Println("Erreur lors de l'enregistrement de la clé d'idempotence:", err)
+	}
+}
+
+// requestFingerprint identifie une requête par sa méthode, sa route sans basePath, les
+// valeurs de ses paramètres, sa query string et son corps
+func requestFingerprint(c *gin.Context, basePath string, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, c.Request.Method+" "+strings.TrimPrefix(c.FullPath(), basePath)+"\n")
+	for _, p := range c.Params {
+		io.WriteString(h, p.Key+"="+p.Value+"\n")
+	}
+	io.WriteString(h, c.Request.URL.RawQuery+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replayableStatus indique si une réponse est conservée pour être rejouée. Les erreurs
+// serveur, les requêtes interrompues et les refus d'authentification ou de quota ne
+// dépendent pas du corps de la requête et ne sont pas conservés.
+func replayableStatus(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests, statusClientClosedRequest:
+		return false
+	}
+	return status < http.StatusInternalServerError
+}
+
+// idempotencyError traduit une erreur du service d'idempotence en réponse HTTP
+func idempotencyError(c *gin.Context, err error, message string) {
+	if requestCanceled(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrInvalidIdempotencyKey):
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "En-tête Idempotency-Key invalide (1 à 255 caractères)."})
+	case errors.Is(err, services.ErrIdempotencyKeyMismatch):
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Cette clé d'idempotence a déjà été utilisée pour une requête différente."})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": message})
+		log.Println("Erreur sur les clés d'idempotence:", err)
+	}
+}
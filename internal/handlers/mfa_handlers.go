@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"YoannLetacq/todo-api.git/internal/services"
+	"YoannLetacq/todo-api.git/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MFAHandler regroupe les handlers de la double authentification TOTP
+type MFAHandler struct {
+	mfa   services.MFAService
+	login loginIssuer
+}
+
+// NewMFAHandler cree les handlers de double authentification à partir de leurs services
+// et des clés de signature des tokens
+func NewMFAHandler(mfa services.MFAService, sessions services.SessionService, keys *utils.JWTKeys) *MFAHandler {
+	return &MFAHandler{mfa: mfa, login: loginIssuer{keys: keys, sessions: sessions}}
+}
+
+type mfaCodeRequest struct {
+	// Code est un code TOTP à 6 chiffres ou un code de récupération
+	Code string `json:"code" binding:"required"`
+}
+
+// GetMFAStatus indique si la double authentification est active GET /me/mfa
+func (h *MFAHandler) GetMFAStatus(c *gin.Context) {
+	uid, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	enabled, remaining, err := h.mfa.Status(c.Request.Context(), uid)
+	if err != nil {
+		mfaError(c, err, "Echec de la recuperation de la double authentification.")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"totp_enabled": enabled, "recovery_codes_remaining": remaining})
+}
+
+// EnrollTOTP génère un secret TOTP à enregistrer dans l'application d'authentification POST /me/mfa/totp
+func (h *MFAHandler) EnrollTOTP(c *gin.Context) {
+	uid, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	secret, uri, err := h.mfa.EnrollTOTP(c.Request.Context(), uid)
+	if err != nil {
+		mfaError(c, err, "Echec de l'enrôlement TOTP.")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Secret TOTP généré, confirmez-le avec un premier code.",
+		"secret":      secret,
+		"otpauth_uri": uri,
+	})
+}
+
+// ConfirmTOTP active la double authentification avec un premier code POST /me/mfa/totp/confirm
+// Les codes de récupération ne sont retournés que dans cette réponse.
+func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
+	uid, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var req mfaCodeRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides."})
+		return
+	}
+
+	codes, err := h.mfa.ConfirmTOTP(c.Request.Context(), uid, req.Code)
+	if err != nil {
+		mfaError(c, err, "Echec de la confirmation TOTP.")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Double authentification activée ! Conservez ces codes de récupération, ils ne seront plus affichés.",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTOTP désactive la double authentification POST /me/mfa/disable
+func (h *MFAHandler) DisableTOTP(c *gin.Context) {
+	uid, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var req mfaCodeRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides."})
+		return
+	}
+
+	if err := h.mfa.DisableTOTP(c.Request.Context(), uid, req.Code); err != nil {
+		mfaError(c, err, "Echec de la désactivation de la double authentification.")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Double authentification désactivée."})
+}
+
+// VerifyLogin échange le mfa_token de l'étape du mot de passe et un code contre un JWT POST /login/mfa
+func (h *MFAHandler) VerifyLogin(c *gin.Context) {
+	var req struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Requête invalide"})
+		return
+	}
+
+	user, err := h.mfa.CompleteChallenge(c.Request.Context(), req.MFAToken, req.Code)
+	if errors.Is(err, services.ErrInvalidMFACode) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Code invalide ou déjà utilisé."})
+		return
+	}
+	if err != nil {
+		mfaError(c, err, "Echec de la connexion.")
+		return
+	}
+
+	h.login.respond(c, user)
+}
+
+// mfaError traduit une erreur du service de double authentification en réponse HTTP
+func mfaError(c *gin.Context, err error, message string) {
+	if requestCanceled(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrMFANotEnrolled):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Double authentification non configurée."})
+	case errors.Is(err, services.ErrMFAAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "Double authentification déjà activée."})
+	case errors.Is(err, services.ErrInvalidMFACode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code invalide ou déjà utilisé."})
+	case errors.Is(err, services.ErrMFALocked):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Trop de codes invalides, réessayez plus tard."})
+	case errors.Is(err, services.ErrInvalidMFAToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "mfa_token invalide ou expiré, veuillez vous reconnecter."})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+		log.Println("Erreur sur la double authentification:", err)
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"YoannLetacq/todo-api.git/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler regroupe les handlers des notifications
+type NotificationHandler struct {
+	notifications services.NotificationService
+}
+
+// NewNotificationHandler cree les handlers des notifications à partir de leur service
+func NewNotificationHandler(notifications services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notifications: notifications}
+}
+
+// GetNotifications liste les notifications de l'utilisateur GET /notifications
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID, err := ExtractUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non autorise." + err.Error()})
+		return
+	}
+
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "userID invalide"})
+		return
+	}
+
+	notifications, err := h.notifications.GetNotifications(c.Request.Context(), uint(uid))
+	if err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Echec de la recuperation des notifications."})
+		log.Println("Erreur lors de la récupération des notifications:", err)
+		return
+	}
+
+	out := make([]notificationResponse, len(notifications))
+	for i := range notifications {
+		out[i] = newNotificationResponse(&notifications[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"notifications": out})
+}
+
+// MarkNotificationRead marque une notification comme lue POST /notifications/:id/read
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	userID, err := ExtractUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non autorise." + err.Error()})
+		return
+	}
+
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "userID invalide"})
+		return
+	}
+
+	nid, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de notification invalide"})
+		return
+	}
+
+	err = h.notifications.MarkRead(c.Request.Context(), uint(uid), uint(nid))
+	if errors.Is(err, services.ErrNotificationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification introuvable."})
+		return
+	}
+	if err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Echec de la mise à jour de la notification."})
+		log.Println("Erreur lors de la mise à jour de la notification:", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification lue."})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/services"
+	"YoannLetacq/todo-api.git/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Cookie qui conserve le state, le nonce et le code_verifier entre la redirection et le callback
+	oidcLoginCookie = "oidc_login"
+	// Délai laissé à l'utilisateur pour se connecter chez le fournisseur
+	oidcLoginTTL = 10 * time.Minute
+)
+
+// OIDCHandler regroupe les handlers de connexion par un fournisseur d'identité externe
+type OIDCHandler struct {
+	oidc  services.OIDCService
+	login loginIssuer
+}
+
+// NewOIDCHandler cree les handlers OIDC à partir de leurs services et des clés de signature des tokens
+func NewOIDCHandler(oidc services.OIDCService, mfa services.MFAService, sessions services.SessionService, keys *utils.JWTKeys) *OIDCHandler {
+	return &OIDCHandler{oidc: oidc, login: loginIssuer{keys: keys, sessions: sessions, mfa: mfa}}
+}
+
+// Login redirige vers la page de connexion du fournisseur GET /auth/:provider/login
+func (h *OIDCHandler) Login(c *gin.Context) {
+	provider := c.Param("provider")
+	url, login, err := h.oidc.AuthCodeURL(c.Request.Context(), provider)
+	if err != nil {
+		h.oidcError(c, err)
+		return
+	}
+
+	data, err := json.Marshal(login)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Échec de la préparation de la connexion."})
+		return
+	}
+	// Le cookie n'est renvoyé qu'au callback de ce fournisseur. SameSite=Lax est
+	// nécessaire : le retour depuis le fournisseur est une navigation d'un autre site.
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcLoginCookie,
+		Value:    base64.RawURLEncoding.EncodeToString(data),
+		Path:     callbackPath(url, c.Request.URL.Path),
+		MaxAge:   int(oidcLoginTTL / time.Second),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	c.Redirect(http.StatusFound, url)
+}
+
+// callbackPath retourne le chemin du callback déclaré chez le fournisseur, lu dans le
+// redirect_uri de l'URL d'autorisation, ou à défaut le callback voisin de la route de login
+func callbackPath(authURL, loginPath string) string {
+	if u, err := neturl.Parse(authURL); err == nil {
+		if redirect, err := neturl.Parse(u.Query().Get("redirect_uri")); err == nil && redirect.Path != "" {
+			return redirect.Path
+		}
+	}
+	return strings.TrimSuffix(loginPath, "/login") + "/callback"
+}
+
+// Callback termine la connexion et retourne un JWT GET /auth/:provider/callback
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	provider := c.Param("provider")
+
+	// La connexion en cours n'est utilisable qu'une fois, quelle que soit l'issue
+	login, err := readOIDCLogin(c)
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcLoginCookie,
+		Path:     c.Request.URL.Path,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Connexion expirée ou inconnue, veuillez recommencer."})
+		return
+	}
+
+	state := c.Query("state")
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(login.State)) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Paramètre state invalide."})
+		return
+	}
+	if errCode := c.Query("error"); errCode != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Connexion refusée par le fournisseur d'identité : " + errCode})
+		return
+	}
+	code := c.Query("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Paramètre code manquant."})
+		return
+	}
+
+	user, err := h.oidc.Callback(c.Request.Context(), provider, code, login)
+	if err != nil {
+		h.oidcError(c, err)
+		return
+	}
+
+	// Le fournisseur ne remplace pas le second facteur : un compte lié par son email
+	// ne doit pas permettre de contourner la double authentification
+	h.login.firstFactor(c, user)
+}
+
+// readOIDCLogin décode la connexion en cours conservée dans le cookie
+func readOIDCLogin(c *gin.Context) (*services.OIDCLogin, error) {
+	value, err := c.Cookie(oidcLoginCookie)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var login services.OIDCLogin
+	if err := json.Unmarshal(data, &login); err != nil {
+		return nil, err
+	}
+	if login.State == "" || login.Nonce == "" || login.Verifier == "" {
+		return nil, errors.New("connexion incomplète")
+	}
+	return &login, nil
+}
+
+// oidcError traduit une erreur du service OIDC en réponse HTTP
+func (h *OIDCHandler) oidcError(c *gin.Context, err error) {
+	if requestCanceled(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrUnknownProvider):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fournisseur d'identité inconnu."})
+	case errors.Is(err, services.ErrEmailNotVerified):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Le fournisseur d'identité n'a pas vérifié votre email."})
+	case errors.Is(err, services.ErrOIDCLoginRejected), errors.Is(err, services.ErrInvalidIDToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Connexion refusée."})
+		log.Println("Connexion OIDC refusée:", err)
+	case errors.Is(err, services.ErrProviderUnavailable):
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Fournisseur d'identité indisponible."})
+		log.Println("Erreur du fournisseur OIDC:", err)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Échec de la connexion."})
+		log.Println("Erreur lors de la connexion OIDC:", err)
+	}
+}
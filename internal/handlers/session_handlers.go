@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/middleware"
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/services"
+	"YoannLetacq/todo-api.git/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// loginIssuer termine une connexion : il ouvre une session pour l'appareil
+// et retourne un JWT lié à cette session
+type loginIssuer struct {
+	keys     *utils.JWTKeys
+	sessions services.SessionService
+	// mfa exige le second facteur des utilisateurs qui l'ont activé, nil une fois ce facteur vérifié
+	mfa services.MFAService
+}
+
+// firstFactor termine la première étape d'une connexion, par mot de passe ou par un
+// fournisseur OIDC. Si la double authentification est active, la réponse n'est qu'un
+// mfa_token à échanger contre le JWT sur /login/mfa.
+func (l loginIssuer) firstFactor(c *gin.Context, user *models.User) {
+	if l.mfa != nil {
+		enabled, _, err := l.mfa.Status(c.Request.Context(), user.ID)
+		if err != nil {
+			if requestCanceled(c, err) {
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Échec de la connexion."})
+			log.Println("Erreur lors de la vérification de la double authentification:", err)
+			return
+		}
+		if enabled {
+			l.startMFALogin(c, user.ID)
+			return
+		}
+	}
+	l.respond(c, user)
+}
+
+// startMFALogin répond à la première étape de la connexion par un mfa_token de courte durée
+func (l loginIssuer) startMFALogin(c *gin.Context, userID uint) {
+	mfaToken, err := l.mfa.StartChallenge(c.Request.Context(), userID)
+	if err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Échec de la connexion."})
+		log.Println("Erreur lors de la création du challenge MFA:", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"mfa_required": true,
+		"mfa_token":    mfaToken,
+		"expires_in":   int(services.MFATokenTTL / time.Second),
+	})
+}
+
+// respond ouvre la session de l'utilisateur et répond avec son JWT
+func (l loginIssuer) respond(c *gin.Context, user *models.User) {
+	session, err := l.sessions.StartSession(c.Request.Context(), user.ID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Échec de la création de la session."})
+		log.Println("Erreur lors de la création de la session:", err)
+		return
+	}
+
+	token, err := l.keys.GenerateForSession(strconv.Itoa(int(user.ID)), user.Email, models.UserScopes, strconv.Itoa(int(session.ID)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Échec de la génération du token JWT"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"token": token})
+}
+
+// SessionConnections ferme les connexions déjà ouvertes d'une session révoquée,
+// comme les websockets de RealtimeHandler
+type SessionConnections interface {
+	CloseSession(userID, sessionID uint)
+	CloseOtherSessions(userID, currentID uint)
+}
+
+// SessionHandler regroupe les handlers des sessions de connexion
+type SessionHandler struct {
+	sessions    services.SessionService
+	connections SessionConnections
+}
+
+// NewSessionHandler cree les handlers des sessions à partir de leur service et des
+// connexions à fermer à la révocation
+func NewSessionHandler(sessions services.SessionService, connections SessionConnections) *SessionHandler {
+	return &SessionHandler{sessions: sessions, connections: connections}
+}
+
+// sessionResponse est la vue d'une session, current désigne celle de la requête
+type sessionResponse struct {
+	ID         uint      `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// currentSessionID retourne la session du JWT de la requête, 0 pour un JWT sans session
+func currentSessionID(c *gin.Context) uint {
+	value, _ := c.Get(middleware.SessionIDKey)
+	id, _ := value.(uint)
+	return id
+}
+
+// GetSessions liste les appareils connectés GET /me/sessions
+func (h *SessionHandler) GetSessions(c *gin.Context) {
+	uid, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.sessions.ListSessions(c.Request.Context(), uid)
+	if err != nil {
+		sessionError(c, err, "Echec de la recuperation des sessions.")
+		return
+	}
+
+	current := currentSessionID(c)
+	out := make([]sessionResponse, len(sessions))
+	for i, s := range sessions {
+		out[i] = sessionResponse{
+			ID:         s.ID,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    s.ID == current,
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": out})
+}
+
+// DeleteSession déconnecte un appareil DELETE /me/sessions/:id
+func (h *SessionHandler) DeleteSession(c *gin.Context) {
+	uid, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	sid, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de session invalide"})
+		return
+	}
+
+	if err := h.sessions.RevokeSession(c.Request.Context(), uid, uint(sid)); err != nil {
+		sessionError(c, err, "Echec de la révocation de la session.")
+		return
+	}
+	h.connections.CloseSession(uid, uint(sid))
+	c.JSON(http.StatusOK, gin.H{"message": "Session révoquée."})
+}
+
+// DeleteOtherSessions déconnecte tous les autres appareils DELETE /me/sessions
+func (h *SessionHandler) DeleteOtherSessions(c *gin.Context) {
+	uid, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	current := currentSessionID(c)
+	revoked, err := h.sessions.RevokeOtherSessions(c.Request.Context(), uid, current)
+	if err != nil {
+		sessionError(c, err, "Echec de la révocation des sessions.")
+		return
+	}
+	h.connections.CloseOtherSessions(uid, current)
+	c.JSON(http.StatusOK, gin.H{"message": "Autres sessions révoquées.", "revoked": revoked})
+}
+
+// sessionError traduit une erreur du service des sessions en réponse HTTP
+func sessionError(c *gin.Context, err error, message string) {
+	if requestCanceled(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session introuvable."})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+		log.Println("Erreur sur les sessions:", err)
+	}
+}
@@ -1,70 +1,64 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"YoannLetacq/todo-api.git/internal/middleware"
 	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/realtime"
 	"YoannLetacq/todo-api.git/internal/services"
-	"YoannLetacq/todo-api.git/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-var taskservices services.TaskService
+// TaskHandler regroupe les handlers des tâches. Les pièces jointes et le hub
+// servent au nettoyage et à la diffusion après une mutation, ils peuvent être nil.
+type TaskHandler struct {
+	tasks       services.TaskService
+	attachments services.AttachmentService
+	hub         realtime.Hub
+}
 
-// InitTaskHandlers permet d'injecter le service dans les handlers
-func InitTaskHandlers(s services.TaskService) {
-	taskservices = s
+// NewTaskHandler cree les handlers des tâches à partir de leurs dépendances
+func NewTaskHandler(tasks services.TaskService, attachments services.AttachmentService, hub realtime.Hub) *TaskHandler {
+	return &TaskHandler{tasks: tasks, attachments: attachments, hub: hub}
 }
 
-// extractuserID extrait le user_id du token JWT des headers.
+// ExtractUserID retourne le user_id du token vérifié par middleware.Authenticate,
+// ou l'erreur d'authentification de la requête.
 func ExtractUserID(c *gin.Context) (string, error) {
-	authHeader := c.GetHeader("Authorization")
-
-	if authHeader == "" {
-		return "", errors.New("Authorization Token manquant")
-	}
-
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		return "", errors.New("Token mal formé")
+	if err, ok := c.Get(middleware.AuthErrorKey); ok {
+		return "", err.(error)
 	}
 
-	tokenString := parts[1]
-	_, claims, err := utils.ParseToken(tokenString)
-	if err != nil {
-		return "", err
-	}
-
-	userID, ok := claims["user_id"]
-	if !ok || userID == "" {
-		return "", errors.New("Token invalide: user_id manquant")
+	userID := c.GetString(middleware.UserIDKey)
+	if userID == "" {
+		return "", middleware.ErrMissingToken
 	}
 	return userID, nil
 }
 
 // CreateTask crée un handler pour la création de tâches.
-func CreateTask(c *gin.Context) {
+func (h *TaskHandler) CreateTask(c *gin.Context) {
 	userID, err := ExtractUserID(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non autorise." + err.Error()})
 		return
 	}
 
-	var task models.Task
-
-	if err := c.ShouldBindJSON(&task); err != nil {
+	var req taskRequest
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides."})
 		return
 	}
 
-	if task.Status == "" {
-		task.Status = "todo"
-	}
+	task := models.Task{Status: models.StatusTodo}
+	req.apply(&task)
 
 	uid, err := strconv.ParseUint(userID, 10, 32)
 	if err != nil {
@@ -74,18 +68,22 @@ func CreateTask(c *gin.Context) {
 
 	task.UserID = uint(uid)
 
-	if err := taskservices.CreateTask(&task); err != nil {
+	if err := h.tasks.CreateTask(c.Request.Context(), uint(uid), &task); err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Echec de la création de la Task"})
 
 		log.Println("Erreur lors de la creation de la tache:", err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Task crée !", "task": task})
+	h.publishTaskEvent(realtime.EventTaskCreated, &task)
+	c.JSON(http.StatusCreated, gin.H{"message": "Task crée !", "task": newTaskResponse(&task)})
 }
 
 // GetTasks recupere toutes les tâches pour un utilisateur
-func GetTasks(c *gin.Context) {
+func (h *TaskHandler) GetTasks(c *gin.Context) {
 	userID, err := ExtractUserID(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non autorise." + err.Error()})
@@ -98,17 +96,20 @@ func GetTasks(c *gin.Context) {
 		return
 	}
 
-	tasks, err := taskservices.GetTasksByUser(uint(uid))
+	tasks, err := h.tasks.GetTasksByUser(c.Request.Context(), uint(uid))
 	if err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Echech de la recuperation des tâches."})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"tasks": tasks})
+	c.JSON(http.StatusOK, gin.H{"tasks": newTaskResponses(tasks)})
 }
 
 // GetTask recupere une tâche pour un utilisateur GET /tasks/:id
-func GetTask(c *gin.Context) {
+func (h *TaskHandler) GetTask(c *gin.Context) {
 	userID, err := ExtractUserID(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non autorise."})
@@ -128,8 +129,11 @@ func GetTask(c *gin.Context) {
 		return
 	}
 
-	task, err := taskservices.GetTaskByID(uint(tid))
+	task, err := h.tasks.GetTaskByID(c.Request.Context(), uint(tid))
 	if err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": "Tâche introuvable."})
 		return
 	}
@@ -139,11 +143,11 @@ func GetTask(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"task": task})
+	c.JSON(http.StatusOK, gin.H{"task": newTaskResponse(task)})
 }
 
 // UpdateTask met a jour une tâche, dont son status PUT /taks/update/:id
-func UpdateTask(c *gin.Context) {
+func (h *TaskHandler) UpdateTask(c *gin.Context) {
 	userID, err := ExtractUserID(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non autorise."})
@@ -163,8 +167,11 @@ func UpdateTask(c *gin.Context) {
 		return
 	}
 
-	task, err := taskservices.GetTaskByID(uint(tid))
+	task, err := h.tasks.GetTaskByID(c.Request.Context(), uint(tid))
 	if err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": "Tâche introuvable."})
 		return
 	}
@@ -174,28 +181,27 @@ func UpdateTask(c *gin.Context) {
 		return
 	}
 
-	var updateData models.Task
-	if err := c.ShouldBindJSON(&updateData); err != nil {
+	var req taskRequest
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides."})
 		return
 	}
+	req.apply(task)
 
-	task.Title = updateData.Title
-	task.Description = updateData.Description
-	if updateData.Status != "" {
-		task.Status = updateData.Status
-	}
-
-	if err := taskservices.UpdateTask(task); err != nil {
+	if err := h.tasks.UpdateTask(c.Request.Context(), uint(uid), task); err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Echec de la mise a jour de la Task", "detail": err.Error()})
 		log.Println("Erreur lors de la mise à jour de la tâche:", err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Task mise a jour !", "task": task})
+	h.publishTaskEvent(realtime.EventTaskUpdated, task)
+	c.JSON(http.StatusOK, gin.H{"message": "Task mise a jour !", "task": newTaskResponse(task)})
 }
 
 // DeleteTask supprime une tâche DELETE /tasks/delete/:id
-func DeleteTask(c *gin.Context) {
+func (h *TaskHandler) DeleteTask(c *gin.Context) {
 	userID, err := ExtractUserID(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non autorise."})
@@ -215,8 +221,11 @@ func DeleteTask(c *gin.Context) {
 		return
 	}
 
-	task, err := taskservices.GetTaskByID(uint(tid))
+	task, err := h.tasks.GetTaskByID(c.Request.Context(), uint(tid))
 	if err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": "Tâche introuvable."})
 		return
 	}
@@ -226,10 +235,221 @@ func DeleteTask(c *gin.Context) {
 		return
 	}
 
-	if err := taskservices.DeleteTask(task); err != nil {
+	if err := h.tasks.DeleteTask(c.Request.Context(), uint(uid), task); err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Echec de la suppression de la Task", "detail": err.Error()})
 		return
 	}
 
+	h.cleanupTaskAttachments(c.Request.Context(), task.ID)
+	h.publishTaskEvent(realtime.EventTaskDeleted, task)
 	c.JSON(http.StatusOK, gin.H{"message": "Task supprimée."})
 }
+
+// Nombre maximum d'opérations acceptées dans un lot
+const maxBulkOperations = 100
+
+// BulkTasks applique un lot d'opérations sur les tâches POST /tasks/bulk
+// Le mode "atomic" (par défaut) annule tout le lot à la première erreur,
+// le mode "best_effort" applique toutes les opérations valides.
+func (h *TaskHandler) BulkTasks(c *gin.Context) {
+	userID, err := ExtractUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non autorise." + err.Error()})
+		return
+	}
+
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "userID invalide"})
+		return
+	}
+
+	var req struct {
+		Mode       string                   `json:"mode"`
+		Operations []services.BulkOperation `json:"operations"`
+	}
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides."})
+		return
+	}
+
+	if req.Mode == "" {
+		req.Mode = "atomic"
+	}
+	if req.Mode != "atomic" && req.Mode != "best_effort" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Mode invalide, attendu atomic ou best_effort."})
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBulkOperations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Le lot doit contenir entre 1 et " + strconv.Itoa(maxBulkOperations) + " opérations."})
+		return
+	}
+	// La route exige tasks:write, les suppressions du lot exigent en plus tasks:delete
+	for _, op := range req.Operations {
+		if op.Op == services.BulkDelete && !middleware.HasScope(c, models.ScopeTasksDelete) {
+			middleware.InsufficientScope(c, models.ScopeTasksDelete)
+			return
+		}
+	}
+
+	results, err := h.tasks.BulkTasks(c.Request.Context(), uint(uid), req.Operations, req.Mode == "atomic")
+	if errors.Is(err, services.ErrBulkAborted) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Lot annulé.", "mode": req.Mode, "results": newBulkResultResponses(results)})
+		return
+	}
+	if err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Echec du traitement du lot."})
+		log.Println("Erreur lors du traitement du lot de tâches:", err)
+		return
+	}
+
+	for _, result := range results {
+		if !result.OK {
+			continue
+		}
+		switch result.Op {
+		case services.BulkCreate:
+			h.publishTaskEvent(realtime.EventTaskCreated, result.Task)
+		case services.BulkDelete:
+			h.cleanupTaskAttachments(c.Request.Context(), result.Task.ID)
+			h.publishTaskEvent(realtime.EventTaskDeleted, result.Task)
+		default:
+			h.publishTaskEvent(realtime.EventTaskUpdated, result.Task)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mode": req.Mode, "results": newBulkResultResponses(results)})
+}
+
+// Nombre de résultats de recherche par défaut et maximum
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchTasks recherche dans les tâches de l'utilisateur GET /tasks/search?q=
+// Les termes sont cherchés en préfixe et les extraits surlignés avec <mark>.
+func (h *TaskHandler) SearchTasks(c *gin.Context) {
+	userID, err := ExtractUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non autorise." + err.Error()})
+		return
+	}
+
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "userID invalide"})
+		return
+	}
+
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Paramètre q manquant."})
+		return
+	}
+
+	limit := defaultSearchLimit
+	if raw := c.Query("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit doit être compris entre 1 et " + strconv.Itoa(maxSearchLimit) + "."})
+			return
+		}
+	}
+
+	results, err := h.tasks.SearchTasks(c.Request.Context(), uint(uid), query, limit)
+	if err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Echec de la recherche."})
+		log.Println("Erreur lors de la recherche de tâches:", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"query": query, "results": newSearchResultResponses(results)})
+}
+
+// Taille de page par défaut et maximum de l'historique
+const (
+	defaultHistoryPageSize = 20
+	maxHistoryPageSize     = 100
+)
+
+// GetTaskHistory retourne l'historique paginé d'une tâche GET /tasks/:id/history?page=&page_size=
+func (h *TaskHandler) GetTaskHistory(c *gin.Context) {
+	userID, err := ExtractUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non autorise." + err.Error()})
+		return
+	}
+
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "userID invalide"})
+		return
+	}
+
+	tid, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de tâche invalide"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "page invalide."})
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultHistoryPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxHistoryPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "page_size doit être compris entre 1 et " + strconv.Itoa(maxHistoryPageSize) + "."})
+		return
+	}
+
+	history, total, err := h.tasks.GetTaskHistory(c.Request.Context(), uint(uid), uint(tid), page, pageSize)
+	if errors.Is(err, services.ErrHistoryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tâche introuvable."})
+		return
+	}
+	if err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Echec de la recuperation de l'historique."})
+		log.Println("Erreur lors de la récupération de l'historique:", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": history, "page": page, "page_size": pageSize, "total": total})
+}
+
+// publishTaskEvent diffuse une mutation de tâche dans la room de sa liste
+func (h *TaskHandler) publishTaskEvent(eventType string, task *models.Task) {
+	if h.hub == nil {
+		return
+	}
+	h.hub.Publish(realtime.Event{
+		Type:   eventType,
+		Room:   realtime.ListRoom(task.UserID),
+		UserID: task.UserID,
+		Data:   newTaskResponse(task),
+	})
+}
+
+// cleanupTaskAttachments supprime les pièces jointes d'une tâche supprimée.
+// La tâche est déjà supprimée, le nettoyage continue donc même si le client se déconnecte.
+func (h *TaskHandler) cleanupTaskAttachments(ctx context.Context, taskID uint) {
+	if h.attachments == nil {
+		return
+	}
+	if err := h.attachments.DeleteTaskAttachments(context.WithoutCancel(ctx), taskID); err != nil {
+		log.Println("Erreur lors du nettoyage des pièces jointes:", err)
+	}
+}
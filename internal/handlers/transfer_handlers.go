@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/realtime"
+	"YoannLetacq/todo-api.git/internal/transfer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Taille maximum d'un fichier d'import
+const maxImportSize = 5 << 20
+
+// ExportTasks exporte toutes les tâches de l'utilisateur GET /tasks/export?format=csv|json|ics
+func (h *TaskHandler) ExportTasks(c *gin.Context) {
+	userID, err := ExtractUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non autorise." + err.Error()})
+		return
+	}
+
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "userID invalide"})
+		return
+	}
+
+	format := c.DefaultQuery("format", transfer.FormatJSON)
+	if !transfer.IsSupported(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format invalide, attendu csv, json ou ics."})
+		return
+	}
+
+	c.Header("Content-Type", transfer.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="tasks.`+format+`"`)
+	c.Status(http.StatusOK)
+
+	encoder, err := transfer.NewEncoder(format, c.Writer)
+	if err != nil {
+		log.Println("Erreur lors de l'export des tâches:", err)
+		return
+	}
+
+	// Les en-têtes sont déjà envoyés, une erreur ne peut plus qu'interrompre le flux.
+	// Une déconnexion du client ou un délai dépassé n'est pas journalisé.
+	err = h.tasks.ExportTasks(c.Request.Context(), uint(uid), func(task *models.Task) error {
+		return encoder.Encode(task)
+	})
+	if err != nil {
+		if c.Request.Context().Err() == nil {
+			log.Println("Erreur lors de l'export des tâches:", err)
+		}
+		return
+	}
+	if err := encoder.Close(); err != nil {
+		log.Println("Erreur lors de l'export des tâches:", err)
+	}
+}
+
+// ImportTasks importe des tâches depuis un fichier CSV, JSON ou iCalendar POST /tasks/import
+// Paramètres: format=csv|json|ics, mapping=title=Nom,... pour le CSV, dry_run=true pour valider sans écrire.
+// Le fichier est envoyé brut dans le corps ou dans le champ "file" d'un formulaire multipart.
+func (h *TaskHandler) ImportTasks(c *gin.Context) {
+	userID, err := ExtractUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non autorise." + err.Error()})
+		return
+	}
+
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "userID invalide"})
+		return
+	}
+
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run invalide."})
+		return
+	}
+
+	mapping, err := transfer.ParseMapping(c.Query("mapping"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Mapping invalide.", "detail": err.Error()})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	var body io.Reader = c.Request.Body
+	filename := ""
+	if c.ContentType() == "multipart/form-data" {
+		header, err := c.FormFile("file")
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Fichier trop volumineux."})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Fichier manquant."})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Fichier illisible."})
+			return
+		}
+		defer file.Close()
+		body = file
+		filename = header.Filename
+	}
+
+	format := importFormat(c, filename)
+	if !transfer.IsSupported(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format invalide, attendu csv, json ou ics."})
+		return
+	}
+
+	rows, err := transfer.Decode(format, body, mapping)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Fichier trop volumineux."})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fichier invalide.", "detail": err.Error()})
+		return
+	}
+
+	var tasks []models.Task
+	rowErrors := []transfer.Row{}
+	for _, row := range rows {
+		if row.Error != "" {
+			rowErrors = append(rowErrors, row)
+			continue
+		}
+		tasks = append(tasks, row.Task)
+	}
+
+	imported := 0
+	if !dryRun && len(tasks) > 0 {
+		if err := h.tasks.ImportTasks(c.Request.Context(), uint(uid), tasks); err != nil {
+			if requestCanceled(c, err) {
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Echec de l'import des tâches."})
+			log.Println("Erreur lors de l'import des tâches:", err)
+			return
+		}
+		imported = len(tasks)
+		for i := range tasks {
+			h.publishTaskEvent(realtime.EventTaskCreated, &tasks[i])
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run":  dryRun,
+		"total":    len(rows),
+		"valid":    len(tasks),
+		"imported": imported,
+		"errors":   rowErrors,
+	})
+}
+
+// importFormat déduit le format du paramètre format, de l'extension du fichier ou du Content-Type
+func importFormat(c *gin.Context, filename string) string {
+	if format := c.Query("format"); format != "" {
+		return strings.ToLower(format)
+	}
+	if ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), "."); ext != "" {
+		if ext == "ical" || ext == "ifb" {
+			return transfer.FormatICS
+		}
+		return ext
+	}
+	switch c.ContentType() {
+	case "text/csv":
+		return transfer.FormatCSV
+	case "text/calendar":
+		return transfer.FormatICS
+	default:
+		return transfer.FormatJSON
+	}
+}
@@ -6,66 +6,76 @@ import (
 	"YoannLetacq/todo-api.git/internal/utils"
 	"log"
 	"net/http"
-	"strconv"
 
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/gin-gonic/gin"
 )
 
-var userService services.UserService
-
-func InitUserHandlers(s services.UserService) {
-	userService = s
+// UserHandler regroupe les handlers d'inscription et de connexion
+type UserHandler struct {
+	users services.UserService
+	keys  *utils.JWTKeys
+	login loginIssuer
 }
 
-func RegisterUser(c *gin.Context) {
-	var user models.User
+// NewUserHandler cree les handlers utilisateurs à partir de leurs services et des clés de signature des tokens
+func NewUserHandler(users services.UserService, mfa services.MFAService, sessions services.SessionService, keys *utils.JWTKeys) *UserHandler {
+	return &UserHandler{users: users, keys: keys, login: loginIssuer{keys: keys, sessions: sessions, mfa: mfa}}
+}
 
-	if err := c.ShouldBindJSON(&user); err != nil {
+func (h *UserHandler) RegisterUser(c *gin.Context) {
+	var req registerRequest
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Requête invalide"})
 		return
 	}
 
 	// Hachage du mot de passe
-	hashedPass, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hashedPass, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Échec du hachage du mot de passe."})
 		return
 	}
-	user.Password = string(hashedPass)
+	user := models.User{Username: req.Username, Email: req.Email, Password: string(hashedPass)}
 
-	if err := userService.RegisterUser(&user); err != nil {
+	if err := h.users.RegisterUser(c.Request.Context(), &user); err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Échec de l'inscription"})
-		log.Println("Erreur : échec de la création de l'utilisateur", user, err)
+		// Ne pas journaliser l'utilisateur entier, il contient le hash du mot de passe
+		log.Println("Erreur : échec de la création de l'utilisateur", user.Email, err)
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"message": "Utilisateur enregistré avec succès !"})
 }
 
-func LoginHandler(c *gin.Context) {
-	var req struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
+func (h *UserHandler) LoginHandler(c *gin.Context) {
+	var req loginRequest
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Requête invalide"})
 		return
 	}
 
-	user, err := userService.LoginUser(req.Email, req.Password)
+	user, err := h.users.LoginUser(c.Request.Context(), req.Email, req.Password)
 	if err != nil {
+		if requestCanceled(c, err) {
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non trouvé ou mot de passe invalide"})
 		return
 	}
 
-	// Générer le token JWT
-	token, err := utils.GenerateJWT(strconv.Itoa(int(user.ID)), user.Email)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Échec de la génération du token JWT"})
-		return
-	}
+	// Avec la double authentification, le mot de passe ne donne qu'un mfa_token
+	// à échanger contre le JWT sur /login/mfa
+	h.login.firstFactor(c, user)
+}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+// JWKS publie les clés publiques de vérification des tokens GET /.well-known/jwks.json
+// Les clés RS256 et EdDSA sont publiées, les secrets HS256 jamais.
+func (h *UserHandler) JWKS(c *gin.Context) {
+	// Mise en cache courte pour que les clients voient rapidement une nouvelle clé
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
 }
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/realtime"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Délai maximum pour écrire un message au client
+	wsWriteWait = 10 * time.Second
+	// Délai maximum entre deux pongs du client
+	wsPongWait = 60 * time.Second
+	// Fréquence des pings, inférieure à wsPongWait
+	wsPingPeriod = (wsPongWait * 9) / 10
+	// Taille maximum d'un message reçu du client
+	wsMaxMessageSize = 4096
+	// Nombre de messages en attente avant de couper un client trop lent
+	wsSendBuffer = 64
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// L'authentification se fait par JWT, l'origine n'est donc pas vérifiée
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// RealtimeHandler sert la websocket abonnée au hub temps réel.
+// Il garde la trace des connexions ouvertes pour pouvoir les fermer à l'arrêt du serveur,
+// http.Server.Shutdown ne s'occupant pas des connexions détournées par l'upgrade.
+type RealtimeHandler struct {
+	hub realtime.Hub
+
+	mu      sync.Mutex
+	clients map[*wsClient]struct{}
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// NewRealtimeHandler cree le handler websocket à partir du hub
+func NewRealtimeHandler(hub realtime.Hub) *RealtimeHandler {
+	return &RealtimeHandler{hub: hub, clients: make(map[*wsClient]struct{})}
+}
+
+// Shutdown refuse les nouvelles websockets, ferme celles ouvertes avec le code
+// 1001 (going away) et attend leur fin ou l'expiration du contexte
+func (h *RealtimeHandler) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closed = true
+	for client := range h.clients {
+		client.close(websocket.CloseGoingAway)
+	}
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// track enregistre une connexion ouverte, retourne false si le serveur s'arrête
+func (h *RealtimeHandler) track(client *wsClient) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return false
+	}
+	h.clients[client] = struct{}{}
+	h.wg.Add(1)
+	return true
+}
+
+func (h *RealtimeHandler) untrack(client *wsClient) {
+	h.mu.Lock()
+	delete(h.clients, client)
+	h.mu.Unlock()
+	h.wg.Done()
+}
+
+// CloseSession ferme les websockets ouvertes avec un JWT d'une session révoquée :
+// une websocket ouverte ne repasse pas par le middleware d'authentification
+func (h *RealtimeHandler) CloseSession(userID, sessionID uint) {
+	h.closeSessions(userID, func(sid uint) bool { return sid == sessionID })
+}
+
+// CloseOtherSessions ferme les websockets des sessions de l'utilisateur autres que currentID.
+// Celles ouvertes sans session (token d'accès personnel, JWT sans claim sid) restent ouvertes.
+func (h *RealtimeHandler) CloseOtherSessions(userID, currentID uint) {
+	h.closeSessions(userID, func(sid uint) bool { return sid != 0 && sid != currentID })
+}
+
+func (h *RealtimeHandler) closeSessions(userID uint, match func(sid uint) bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients {
+		if client.userID == userID && match(client.sessionID) {
+			client.close(websocket.ClosePolicyViolation)
+		}
+	}
+}
+
+func (h *RealtimeHandler) isClosed() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.closed
+}
+
+// wsMessage est un message envoyé par le client sur la websocket
+type wsMessage struct {
+	Action string `json:"action"`
+	Room   string `json:"room"`
+}
+
+// wsClient est une connexion websocket abonnée au hub
+type wsClient struct {
+	hub    realtime.Hub
+	conn   *websocket.Conn
+	userID uint
+	// sessionID est la session du JWT de connexion, 0 sans session
+	sessionID uint
+	send      chan realtime.Event
+	done      chan struct{}
+	closeOnce sync.Once
+	closeCode int
+}
+
+func (c *wsClient) UserID() uint {
+	return c.userID
+}
+
+// Send met un évènement en file sans bloquer. Si la file est pleine,
+// le client est considéré trop lent et sa connexion est fermée.
+func (c *wsClient) Send(ev realtime.Event) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- ev:
+		return true
+	default:
+		log.Println("Client websocket trop lent, déconnexion de l'utilisateur", c.userID)
+		c.close(websocket.CloseTryAgainLater)
+		return false
+	}
+}
+
+func (c *wsClient) close(code int) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		close(c.done)
+	})
+}
+
+// ServeWS ouvre une websocket authentifiée par JWT GET /ws
+// Le token peut être passé dans le header Authorization ou le paramètre ?token= (middleware.QueryToken)
+func (h *RealtimeHandler) ServeWS(c *gin.Context) {
+	if h.hub == nil || h.isClosed() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Temps réel indisponible."})
+		return
+	}
+
+	userID, err := ExtractUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non autorise." + err.Error()})
+		return
+	}
+
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "userID invalide"})
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// L'upgrader a déjà répondu au client
+		log.Println("Erreur lors de l'ouverture de la websocket:", err)
+		return
+	}
+
+	client := &wsClient{
+		hub:       h.hub,
+		conn:      conn,
+		userID:    uint(uid),
+		sessionID: currentSessionID(c),
+		send:      make(chan realtime.Event, wsSendBuffer),
+		done:      make(chan struct{}),
+	}
+	if !h.track(client) {
+		// Le serveur s'est arrêté pendant l'upgrade
+		client.close(websocket.CloseGoingAway)
+		client.writePump()
+		return
+	}
+	defer h.untrack(client)
+
+	writerDone := make(chan struct{})
+	go func() {
+		client.writePump()
+		close(writerDone)
+	}()
+	client.readPump()
+	<-writerDone
+}
+
+// readPump lit les messages du client jusqu'à la déconnexion
+func (c *wsClient) readPump() {
+	defer func() {
+		c.hub.UnsubscribeAll(c)
+		c.close(websocket.CloseNormalClosure)
+	}()
+
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Println("Erreur de lecture websocket:", err)
+			}
+			return
+		}
+
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.sendError("", "Message invalide.")
+			continue
+		}
+		c.handleMessage(msg)
+	}
+}
+
+// writePump envoie les évènements et les pings au client
+func (c *wsClient) writePump() {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case ev := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteJSON(ev); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-c.done:
+			msg := websocket.FormatCloseMessage(c.closeCode, "")
+			c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteWait))
+			return
+		}
+	}
+}
+
+// handleMessage traite les actions subscribe et unsubscribe
+func (c *wsClient) handleMessage(msg wsMessage) {
+	switch msg.Action {
+	case "subscribe":
+		if msg.Room != realtime.ListRoom(c.userID) {
+			c.sendError(msg.Room, "Cette liste ne vous appartiens pas.")
+			return
+		}
+		c.hub.Subscribe(msg.Room, c)
+		c.Send(realtime.Event{Type: "subscribed", Room: msg.Room, UserID: c.userID, Data: c.hub.Presence(msg.Room)})
+	case "unsubscribe":
+		c.hub.Unsubscribe(msg.Room, c)
+	default:
+		c.sendError(msg.Room, "Action inconnue.")
+	}
+}
+
+func (c *wsClient) sendError(room, message string) {
+	c.Send(realtime.Event{Type: "error", Room: room, UserID: c.userID, Data: message})
+}
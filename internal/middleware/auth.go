@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Clés du contexte gin renseignées par Authenticate
+const (
+	// UserIDKey contient le user_id du token vérifié
+	UserIDKey = "auth.user_id"
+	// AuthErrorKey contient l'erreur d'authentification si le token est absent ou invalide
+	AuthErrorKey = "auth.error"
+	// AuthTypeKey contient le type du token vérifié, AuthTypeJWT ou AuthTypeAPIToken
+	AuthTypeKey = "auth.type"
+	// ScopesKey contient les scopes du token vérifié ([]string)
+	ScopesKey = "auth.scopes"
+	// SessionIDKey contient l'ID de la session d'un JWT de connexion (uint)
+	SessionIDKey = "auth.session_id"
+)
+
+// Types de token acceptés par Authenticate
+const (
+	AuthTypeJWT      = "jwt"
+	AuthTypeAPIToken = "api_token"
+)
+
+// APITokenResolver vérifie un token d'accès personnel et retourne son utilisateur et ses scopes
+type APITokenResolver interface {
+	ResolveAPIToken(ctx context.Context, token string) (uint, []string, error)
+}
+
+// SessionResolver vérifie que la session d'un JWT n'a pas été révoquée
+type SessionResolver interface {
+	ValidateSession(ctx context.Context, userID, sessionID uint) error
+}
+
+// ErrMissingToken est l'erreur d'authentification d'une requête sans token
+var ErrMissingToken = errors.New("Authorization Token manquant")
+
+// Authenticate vérifie le token Bearer de la requête, JWT de connexion ou token d'accès
+// personnel, et place son user_id dans le contexte. Un JWT lié à une session (claim sid)
+// est refusé dès que la session est révoquée. Un JWT sans claim sid, émis avant les
+// sessions, ne peut pas être révoqué : il reste valide jusqu'à son expiration, au plus
+// utils.TokenTTL après le déploiement des sessions, la connexion n'en émettant plus.
+// La requête n'est pas rejetée : chaque handler décide avec handlers.ExtractUserID si
+// l'authentification est requise, ce qui laisse /register, /login et le flux calendrier publics.
+func Authenticate(keys *utils.JWTKeys, tokens APITokenResolver, sessions SessionResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.Set(AuthErrorKey, ErrMissingToken)
+			c.Next()
+			return
+		}
+
+		parts := strings.Split(authHeader, " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			c.Set(AuthErrorKey, errors.New("Token mal formé"))
+			c.Next()
+			return
+		}
+
+		// Un JWT est formé de trois parties séparées par des points, un token d'accès n'en a pas
+		if tokens != nil && strings.Count(parts[1], ".") != 2 {
+			userID, scopes, err := tokens.ResolveAPIToken(c.Request.Context(), parts[1])
+			if err != nil {
+				c.Set(AuthErrorKey, err)
+				c.Next()
+				return
+			}
+			c.Set(UserIDKey, strconv.FormatUint(uint64(userID), 10))
+			c.Set(AuthTypeKey, AuthTypeAPIToken)
+			c.Set(ScopesKey, scopes)
+			c.Next()
+			return
+		}
+
+		_, claims, err := keys.Parse(parts[1])
+		if err != nil {
+			c.Set(AuthErrorKey, err)
+			c.Next()
+			return
+		}
+		if sid, present := claims["sid"]; present {
+			sessionID, err := checkSession(c, sessions, claims["user_id"], sid)
+			if err != nil {
+				c.Set(AuthErrorKey, err)
+				c.Next()
+				return
+			}
+			c.Set(SessionIDKey, sessionID)
+		}
+		// Un JWT sans claim scope, émis par une connexion, a les droits de l'utilisateur
+		scopes := models.UserScopes
+		if scope, present := claims["scope"]; present {
+			scopes = strings.Fields(scope)
+		}
+		c.Set(UserIDKey, claims["user_id"])
+		c.Set(AuthTypeKey, AuthTypeJWT)
+		c.Set(ScopesKey, scopes)
+		c.Next()
+	}
+}
+
+// checkSession vérifie la session désignée par le claim sid et retourne son ID
+func checkSession(c *gin.Context, sessions SessionResolver, userID, sid string) (uint, error) {
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		return 0, errors.New("user_id invalide dans le token")
+	}
+	sessionID, err := strconv.ParseUint(sid, 10, 32)
+	if err != nil {
+		return 0, errors.New("sid invalide dans le token")
+	}
+	if sessions != nil {
+		if err := sessions.ValidateSession(c.Request.Context(), uint(uid), uint(sessionID)); err != nil {
+			return 0, err
+		}
+	}
+	return uint(sessionID), nil
+}
+
+// HasScope indique si le token de la requête a le scope demandé
+func HasScope(c *gin.Context, scope string) bool {
+	value, _ := c.Get(ScopesKey)
+	scopes, _ := value.([]string)
+	return models.HasScope(scopes, scope)
+}
+
+// InsufficientScope refuse la requête en 403 pour un token qui n'a pas le scope demandé
+func InsufficientScope(c *gin.Context, scope string) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+		"error":          "insufficient_scope",
+		"required_scope": scope,
+		"message":        "Ce token n'a pas le scope " + scope + ".",
+	})
+}
+
+// RequireScope refuse en 403 insufficient_scope une requête authentifiée dont le token
+// n'a pas tous les scopes demandés. Une requête non authentifiée est laissée au handler,
+// qui répondra 401.
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, authenticated := c.Get(ScopesKey); !authenticated {
+			c.Next()
+			return
+		}
+		for _, scope := range scopes {
+			if !HasScope(c, scope) {
+				InsufficientScope(c, scope)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// QueryToken utilise le paramètre ?token= comme token Bearer quand le header
+// Authorization est absent, les navigateurs ne pouvant pas l'envoyer sur une websocket
+func QueryToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query("token"); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		c.Next()
+	}
+}
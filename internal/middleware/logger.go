@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Paramètres de requête masqués dans les logs
+var sensitiveQueryParams = []string{"token", "access_token"}
+
+// Préfixes des routes dont le dernier segment est un secret, ex: le flux calendrier
+var sensitivePathPrefixes = []string{"/calendar/feed/", "/api/v1/calendar/feed/"}
+
+// Logger journalise les requêtes comme gin.Logger, sans écrire les tokens
+// passés dans l'URL (websocket ?token=, flux calendrier)
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithConfig(gin.LoggerConfig{
+		Formatter: func(param gin.LogFormatterParams) string {
+			param.Path = redactPath(param.Path)
+			return defaultLogFormatter(param)
+		},
+	})
+}
+
+// defaultLogFormatter reprend le format de gin.Logger, qui n'est pas exporté
+func defaultLogFormatter(param gin.LogFormatterParams) string {
+	var statusColor, methodColor, resetColor string
+	if param.IsOutputColor() {
+		statusColor = param.StatusCodeColor()
+		methodColor = param.MethodColor()
+		resetColor = param.ResetColor()
+	}
+
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, param.StatusCode, resetColor,
+		param.Latency,
+		param.ClientIP,
+		methodColor, param.Method, resetColor,
+		param.Path,
+		param.ErrorMessage,
+	)
+}
+
+// redactPath masque les secrets d'un chemin de requête avec sa query string
+func redactPath(path string) string {
+	p, rawQuery, hasQuery := strings.Cut(path, "?")
+	for _, prefix := range sensitivePathPrefixes {
+		if strings.HasPrefix(p, prefix) && len(p) > len(prefix) {
+			p = prefix + "***"
+		}
+	}
+	if !hasQuery {
+		return p
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return p + "?***"
+	}
+	for _, name := range sensitiveQueryParams {
+		if query.Has(name) {
+			query.Set(name, "***")
+		}
+	}
+	return p + "?" + query.Encode()
+}
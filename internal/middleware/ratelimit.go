@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit limite les requêtes selon la politique p. Le seau est celui de l'utilisateur
+// authentifié, à défaut celui de l'IP ; il doit donc être placé après Authenticate.
+// Les en-têtes RateLimit-* décrivent le quota restant, une requête au-delà reçoit 429
+// avec Retry-After. Un store nil désactive la limite.
+func RateLimit(store ratelimit.Store, p ratelimit.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if store == nil {
+			c.Next()
+			return
+		}
+		limit(c, store, p, rateLimitKey(c, p))
+	}
+}
+
+// RateLimitByIP limite les requêtes selon la politique p, toujours par IP même si la
+// requête est authentifiée : un client qui dispose de plusieurs tokens ne doit pas
+// obtenir un seau par token sur l'inscription et la connexion.
+func RateLimitByIP(store ratelimit.Store, p ratelimit.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if store == nil {
+			c.Next()
+			return
+		}
+		limit(c, store, p, ipRateLimitKey(c, p))
+	}
+}
+
+// RateLimitByMethod applique read aux requêtes GET et HEAD, write aux autres
+func RateLimitByMethod(store ratelimit.Store, read, write ratelimit.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if store == nil {
+			c.Next()
+			return
+		}
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead:
+			limit(c, store, read, rateLimitKey(c, read))
+		default:
+			limit(c, store, write, rateLimitKey(c, write))
+		}
+	}
+}
+
+func limit(c *gin.Context, store ratelimit.Store, p ratelimit.Policy, key string) {
+	res, err := store.Take(c.Request.Context(), key, p, time.Now())
+	if err != nil {
+		// Une panne du stockage des quotas ne doit pas rendre l'API indisponible
+		log.Println("Erreur de la limitation des requêtes:", err)
+		c.Next()
+		return
+	}
+
+	h := c.Writer.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(p.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+	h.Set("RateLimit-Policy", p.Header())
+
+	if !res.Allowed {
+		h.Set("Retry-After", ceilSeconds(res.RetryAfter))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Trop de requêtes, réessayez plus tard."})
+		return
+	}
+	c.Next()
+}
+
+// rateLimitKey retourne le seau de la requête : par utilisateur authentifié, à défaut par IP
+func rateLimitKey(c *gin.Context, p ratelimit.Policy) string {
+	if userID := c.GetString(UserIDKey); userID != "" {
+		return p.Name + ":user:" + userID
+	}
+	return ipRateLimitKey(c, p)
+}
+
+// ipRateLimitKey retourne le seau de l'IP du client
+func ipRateLimitKey(c *gin.Context, p ratelimit.Policy) string {
+	return p.Name + ":ip:" + c.ClientIP()
+}
+
+// ceilSeconds arrondit un délai à la seconde supérieure pour les en-têtes
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
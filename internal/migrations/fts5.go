@@ -0,0 +1,9 @@
+//go:build sqlite_fts5
+
+package migrations
+
+// Avec le tag sqlite_fts5, SQLite dispose de FTS5 : l'index de recherche plein texte
+// des tâches est créé par les migrations de sql/sqlite_fts5
+func init() {
+	optionalDirs[dialectSQLite] = append(optionalDirs[dialectSQLite], "sqlite_fts5")
+}
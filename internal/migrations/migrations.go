@@ -0,0 +1,366 @@
+// Package migrations applique les migrations SQL versionnées embarquées dans le binaire.
+//
+// Chaque dialecte a son dossier sql/<dialecte> contenant des paires
+// NNNN_nom.up.sql / NNNN_nom.down.sql. Les versions appliquées sont enregistrées
+// dans la table schema_migrations. Les migrations qui dépendent d'une option de
+// compilation sont dans un dossier à part, chargé seulement avec son tag
+// (sql/sqlite_fts5 avec le tag sqlite_fts5).
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql
+var files embed.FS
+
+// Dialectes supportés, d'après gorm.Dialector.Name()
+const (
+	dialectSQLite   = "sqlite"
+	dialectPostgres = "postgres"
+)
+
+// Clé du verrou consultatif Postgres (pg_advisory_lock) qui sérialise les migrations
+const advisoryLockID = 73611842
+
+// Dossiers de migrations chargés en plus de sql/<dialecte>, renseignés selon les tags de compilation
+var optionalDirs = map[string][]string{}
+
+// ErrUnsupportedDialect est retournée pour une base qui n'est ni SQLite ni Postgres
+var ErrUnsupportedDialect = errors.New("dialecte de base de données non supporté")
+
+// Migration est une version du schéma avec ses scripts de montée et de descente
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status est l'état d'une migration, AppliedAt vaut nil si elle est en attente
+type Status struct {
+	Version   uint
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator applique les migrations d'un dialecte sur une base
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []Migration
+}
+
+// New charge les migrations correspondant au dialecte de la base
+func New(db *gorm.DB) (*Migrator, error) {
+	dialect := db.Dialector.Name()
+	if dialect != dialectSQLite && dialect != dialectPostgres {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDialect, dialect)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: sqlDB, dialect: dialect, migrations: migrations}, nil
+}
+
+// Up applique toutes les migrations en attente, dans l'ordre des versions.
+// Retourne les migrations appliquées, aucune si le schéma est à jour.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			err := m.apply(ctx, conn, mig.Up,
+				`INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`,
+				mig.Version, mig.Name, time.Now().UTC())
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", mig.Version, mig.Name, err)
+			}
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// Down annule les steps dernières migrations appliquées, de la plus récente à la plus ancienne.
+// Retourne les migrations annulées.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]uint, 0, len(done))
+		for v := range done {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for i := 0; i < steps && i < len(versions); i++ {
+			mig, ok := m.find(versions[i])
+			if !ok {
+				return fmt.Errorf("migration %04d appliquée mais absente du binaire", versions[i])
+			}
+			err := m.apply(ctx, conn, mig.Down, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", mig.Version, mig.Name, err)
+			}
+			reverted = append(reverted, mig)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reverted, nil
+}
+
+// Status retourne l'état de chaque migration connue, dans l'ordre des versions.
+// Il ne fait que lire schema_migrations, sans prendre le verrou des migrations :
+// il n'attend pas une migration en cours et ne bloque pas les écritures.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	exists, err := m.tableExists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[uint]time.Time)
+	if exists {
+		if done, err = appliedVersions(ctx, m.db); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		st := Status{Version: mig.Version, Name: mig.Name}
+		if at, ok := done[mig.Version]; ok {
+			at := at
+			st.AppliedAt = &at
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+// tableExists indique si la table schema_migrations existe, sans la créer
+func (m *Migrator) tableExists(ctx context.Context) (bool, error) {
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`
+	if m.dialect == dialectPostgres {
+		query = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_migrations'`
+	}
+	var count int
+	if err := m.db.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// withLock exécute fn sur une connexion dédiée en excluant les autres migrations concurrentes.
+// Postgres utilise un verrou consultatif de session. SQLite n'en a pas : toute l'opération
+// est faite dans une transaction BEGIN IMMEDIATE qui prend le verrou d'écriture de la base,
+// les autres processus attendent alors le busy timeout du driver.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Le verrou doit être libéré même si la requête a été annulée
+	release := context.WithoutCancel(ctx)
+
+	if m.dialect == dialectPostgres {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockID); err != nil {
+			return fmt.Errorf("verrou des migrations: %w", err)
+		}
+		defer conn.ExecContext(release, `SELECT pg_advisory_unlock($1)`, advisoryLockID)
+
+		if err := ensureTable(ctx, conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	}
+
+	if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+		return fmt.Errorf("verrou des migrations: %w", err)
+	}
+	if err := ensureTable(ctx, conn); err != nil {
+		conn.ExecContext(release, `ROLLBACK`)
+		return err
+	}
+	if err := fn(conn); err != nil {
+		conn.ExecContext(release, `ROLLBACK`)
+		return err
+	}
+	_, err = conn.ExecContext(ctx, `COMMIT`)
+	return err
+}
+
+// apply exécute un script de migration puis la requête qui met à jour schema_migrations.
+// Sous Postgres chaque migration a sa propre transaction, sous SQLite elles partagent
+// celle ouverte par withLock.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, script, record string, args ...interface{}) error {
+	if m.dialect != dialectPostgres {
+		if _, err := conn.ExecContext(ctx, script); err != nil {
+			return err
+		}
+		_, err := conn.ExecContext(ctx, record, args...)
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) find(version uint) (Migration, bool) {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig, true
+		}
+	}
+	return Migration{}, false
+}
+
+// ensureTable crée la table de suivi des versions si besoin
+func ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		applied_at timestamp NOT NULL
+	)`)
+	return err
+}
+
+// queryer est une connexion dédiée ou le pool de connexions
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// appliedVersions retourne les versions appliquées avec leur date d'application
+func appliedVersions(ctx context.Context, conn queryer) (map[uint]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := make(map[uint]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		done[uint(version)] = at
+	}
+	return done, rows.Err()
+}
+
+// load lit les scripts embarqués d'un dialecte et de ses dossiers optionnels,
+// et vérifie que chaque version a un script up et un script down
+func load(dialect string) ([]Migration, error) {
+	byVersion := make(map[uint]*Migration)
+	for _, name := range append([]string{dialect}, optionalDirs[dialect]...) {
+		if err := loadDir(path.Join("sql", name), byVersion); err != nil {
+			return nil, err
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s incomplète: scripts up et down requis", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// loadDir ajoute à byVersion les scripts d'un dossier embarqué
+func loadDir(dir string, byVersion map[uint]*Migration) error {
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			return fmt.Errorf("fichier de migration invalide: %s", name)
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		prefix, label, ok := strings.Cut(base, "_")
+		version, err := strconv.ParseUint(prefix, 10, 32)
+		if !ok || err != nil || version == 0 {
+			return fmt.Errorf("fichier de migration invalide: %s", name)
+		}
+
+		content, err := fs.ReadFile(files, path.Join(dir, name))
+		if err != nil {
+			return err
+		}
+
+		mig, exists := byVersion[uint(version)]
+		if !exists {
+			mig = &Migration{Version: uint(version), Name: label}
+			byVersion[uint(version)] = mig
+		} else if mig.Name != label {
+			return fmt.Errorf("version %04d utilisée par deux migrations: %s et %s", version, mig.Name, label)
+		}
+		if direction == "up" {
+			mig.Up = string(content)
+		} else {
+			mig.Down = string(content)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+-- Schéma initial, identique à celui produit par AutoMigrate.
+-- Les IF NOT EXISTS permettent d'adopter une base créée avant les migrations versionnées.
+CREATE TABLE IF NOT EXISTS users (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	username text NOT NULL,
+	email text NOT NULL,
+	password text NOT NULL,
+	CONSTRAINT uni_users_username UNIQUE (username),
+	CONSTRAINT uni_users_email UNIQUE (email)
+);
+
+CREATE TABLE IF NOT EXISTS tasks (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	title text NOT NULL,
+	description text,
+	status text DEFAULT 'todo',
+	due_date timestamptz,
+	user_id bigint NOT NULL,
+	CONSTRAINT fk_users_task FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	user_id bigint NOT NULL,
+	token_hash text NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_feeds_token_hash ON calendar_feeds(token_hash);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_feeds_user_id ON calendar_feeds(user_id);
+
+CREATE TABLE IF NOT EXISTS task_events (
+	id bigserial PRIMARY KEY,
+	task_id bigint NOT NULL,
+	user_id bigint NOT NULL,
+	actor_id bigint NOT NULL,
+	action text NOT NULL,
+	changes text,
+	created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_task_events_user_id ON task_events(user_id);
+CREATE INDEX IF NOT EXISTS idx_task_events_task_id ON task_events(task_id);
+
+CREATE TABLE IF NOT EXISTS comments (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	task_id bigint NOT NULL,
+	author_id bigint NOT NULL,
+	body text NOT NULL,
+	edited_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_comments_task_id ON comments(task_id);
+
+CREATE TABLE IF NOT EXISTS notifications (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	user_id bigint NOT NULL,
+	type text NOT NULL,
+	actor_id bigint,
+	task_id bigint,
+	comment_id bigint,
+	read_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id);
+
+CREATE TABLE IF NOT EXISTS attachments (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	task_id bigint NOT NULL,
+	uploader_id bigint NOT NULL,
+	filename text NOT NULL,
+	content_type text NOT NULL,
+	size bigint NOT NULL,
+	sha256 varchar(64) NOT NULL,
+	storage_key text NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attachments_storage_key ON attachments(storage_key);
+CREATE INDEX IF NOT EXISTS idx_attachments_task_id ON attachments(task_id);
@@ -0,0 +1,16 @@
+-- Tokens d'accès personnels, seul le hash du token est conservé
+CREATE TABLE api_tokens (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	user_id bigint NOT NULL,
+	name text NOT NULL,
+	token_hash text NOT NULL,
+	scopes text NOT NULL,
+	expires_at timestamptz NOT NULL,
+	last_used_at timestamptz,
+	CONSTRAINT fk_api_tokens_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE UNIQUE INDEX idx_api_tokens_token_hash ON api_tokens(token_hash);
+CREATE INDEX idx_api_tokens_user_id ON api_tokens(user_id);
@@ -0,0 +1,14 @@
+-- Sessions de connexion, référencées par le claim sid des JWT
+CREATE TABLE sessions (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	user_id bigint NOT NULL,
+	user_agent text,
+	ip text,
+	last_seen_at timestamptz NOT NULL,
+	expires_at timestamptz NOT NULL,
+	CONSTRAINT fk_sessions_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
@@ -0,0 +1,9 @@
+-- Seaux de jetons de la limitation des requêtes, partagés entre les instances (RATE_LIMIT_STORE=sql)
+CREATE TABLE rate_limit_buckets (
+	bucket_key text PRIMARY KEY,
+	tokens double precision NOT NULL,
+	refilled_at timestamptz NOT NULL,
+	full_at timestamptz NOT NULL,
+	version bigint NOT NULL DEFAULT 0
+);
+CREATE INDEX idx_rate_limit_buckets_full_at ON rate_limit_buckets(full_at);
@@ -0,0 +1,18 @@
+-- Clés d'idempotence des requêtes et réponses rejouées, conservées 24 h
+CREATE TABLE idempotency_keys (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	user_id bigint NOT NULL,
+	idempotency_key text NOT NULL,
+	fingerprint text NOT NULL,
+	status_code bigint NOT NULL DEFAULT 0,
+	content_type text,
+	response_body bytea,
+	locked_until timestamptz NOT NULL,
+	expires_at timestamptz NOT NULL,
+	CONSTRAINT fk_idempotency_keys_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE UNIQUE INDEX idx_idempotency_keys_user_key ON idempotency_keys(user_id, idempotency_key);
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,7 @@
+-- Index de recherche plein texte des tâches : colonne tsvector générée et index GIN
+-- Les IF NOT EXISTS adoptent l'index créé au démarrage par les versions précédentes.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+	setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+	setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_tasks_search_vector ON tasks USING GIN (search_vector);
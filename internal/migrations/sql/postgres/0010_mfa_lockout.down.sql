@@ -0,0 +1,2 @@
+ALTER TABLE user_totps DROP COLUMN locked_until;
+ALTER TABLE user_totps DROP COLUMN failed_attempts;
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS attachments;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS task_events;
+DROP TABLE IF EXISTS calendar_feeds;
+DROP TABLE IF EXISTS tasks_fts;
+DROP TABLE IF EXISTS tasks;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,14 @@
+-- Comptes des fournisseurs OIDC liés aux utilisateurs
+CREATE TABLE user_identities (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	updated_at datetime,
+	deleted_at datetime,
+	user_id integer NOT NULL,
+	provider text NOT NULL,
+	subject text NOT NULL,
+	email text,
+	CONSTRAINT fk_user_identities_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE UNIQUE INDEX idx_user_identities_provider_subject ON user_identities(provider, subject);
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
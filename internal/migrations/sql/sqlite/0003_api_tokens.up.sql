@@ -0,0 +1,16 @@
+-- Tokens d'accès personnels, seul le hash du token est conservé
+CREATE TABLE api_tokens (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	updated_at datetime,
+	deleted_at datetime,
+	user_id integer NOT NULL,
+	name text NOT NULL,
+	token_hash text NOT NULL,
+	scopes text NOT NULL,
+	expires_at datetime NOT NULL,
+	last_used_at datetime,
+	CONSTRAINT fk_api_tokens_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE UNIQUE INDEX idx_api_tokens_token_hash ON api_tokens(token_hash);
+CREATE INDEX idx_api_tokens_user_id ON api_tokens(user_id);
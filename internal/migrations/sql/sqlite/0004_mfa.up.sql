@@ -0,0 +1,40 @@
+-- Double authentification TOTP, codes de récupération et connexions en attente du second facteur
+CREATE TABLE user_totps (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	updated_at datetime,
+	deleted_at datetime,
+	user_id integer NOT NULL,
+	secret text NOT NULL,
+	confirmed_at datetime,
+	last_used_step integer NOT NULL DEFAULT 0,
+	CONSTRAINT fk_user_totps_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE UNIQUE INDEX idx_user_totps_user_id ON user_totps(user_id);
+
+CREATE TABLE recovery_codes (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	updated_at datetime,
+	deleted_at datetime,
+	user_id integer NOT NULL,
+	code_hash text NOT NULL,
+	used_at datetime,
+	CONSTRAINT fk_recovery_codes_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes(user_id);
+CREATE INDEX idx_recovery_codes_code_hash ON recovery_codes(code_hash);
+
+CREATE TABLE mfa_challenges (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	updated_at datetime,
+	deleted_at datetime,
+	user_id integer NOT NULL,
+	token_hash text NOT NULL,
+	expires_at datetime NOT NULL,
+	attempts integer NOT NULL DEFAULT 0,
+	CONSTRAINT fk_mfa_challenges_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE UNIQUE INDEX idx_mfa_challenges_token_hash ON mfa_challenges(token_hash);
+CREATE INDEX idx_mfa_challenges_user_id ON mfa_challenges(user_id);
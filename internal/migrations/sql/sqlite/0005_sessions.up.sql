@@ -0,0 +1,14 @@
+-- Sessions de connexion, référencées par le claim sid des JWT
+CREATE TABLE sessions (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	updated_at datetime,
+	deleted_at datetime,
+	user_id integer NOT NULL,
+	user_agent text,
+	ip text,
+	last_seen_at datetime NOT NULL,
+	expires_at datetime NOT NULL,
+	CONSTRAINT fk_sessions_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
@@ -0,0 +1,18 @@
+-- Clés d'idempotence des requêtes et réponses rejouées, conservées 24 h
+CREATE TABLE idempotency_keys (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	updated_at datetime,
+	deleted_at datetime,
+	user_id integer NOT NULL,
+	idempotency_key text NOT NULL,
+	fingerprint text NOT NULL,
+	status_code integer NOT NULL DEFAULT 0,
+	content_type text,
+	response_body blob,
+	locked_until datetime NOT NULL,
+	expires_at datetime NOT NULL,
+	CONSTRAINT fk_idempotency_keys_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE UNIQUE INDEX idx_idempotency_keys_user_key ON idempotency_keys(user_id, idempotency_key);
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
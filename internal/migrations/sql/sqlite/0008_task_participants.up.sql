@@ -0,0 +1,11 @@
+-- Participants d'une tâche : utilisateurs mentionnés par son propriétaire,
+-- qui peuvent lire et commenter la tâche
+CREATE TABLE task_participants (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	task_id integer NOT NULL,
+	user_id integer NOT NULL,
+	CONSTRAINT fk_task_participants_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE UNIQUE INDEX idx_task_participants_task_user ON task_participants(task_id, user_id);
+CREATE INDEX idx_task_participants_user_id ON task_participants(user_id);
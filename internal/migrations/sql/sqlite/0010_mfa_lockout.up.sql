@@ -0,0 +1,4 @@
+-- Codes TOTP invalides consécutifs d'un utilisateur, tous mfa_token confondus,
+-- et blocage temporaire de la vérification au-delà du maximum
+ALTER TABLE user_totps ADD COLUMN failed_attempts integer NOT NULL DEFAULT 0;
+ALTER TABLE user_totps ADD COLUMN locked_until datetime;
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Token d'accès personnel d'un utilisateur, pour les scripts et la CI.
+// Le token n'est stocké que haché, il n'est visible qu'à sa création.
+type APIToken struct {
+	gorm.Model
+	UserID    uint   `gorm:"not null;index" json:"-"`
+	Name      string `gorm:"not null" json:"name"`
+	TokenHash string `gorm:"uniqueIndex;not null" json:"-"`
+	// ScopeList contient les scopes séparés par des espaces
+	ScopeList  string     `gorm:"column:scopes;not null" json:"-"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// Scopes retourne les scopes du token
+func (t *APIToken) Scopes() []string {
+	return strings.Fields(t.ScopeList)
+}
+
+// Expired indique si le token a expiré à la date now
+func (t *APIToken) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,15 @@
+package models
+
+import "github.com/jinzhu/gorm"
+
+// Pièce jointe d'une tâche, le contenu est conservé dans le BlobStore sous StorageKey
+type Attachment struct {
+	gorm.Model
+	TaskID      uint   `gorm:"index;not null" json:"task_id"`
+	UploaderID  uint   `gorm:"not null" json:"uploader_id"`
+	Filename    string `gorm:"not null" json:"filename"`
+	ContentType string `gorm:"not null" json:"content_type"`
+	Size        int64  `gorm:"not null" json:"size"`
+	SHA256      string `gorm:"column:sha256;size:64;not null" json:"sha256"`
+	StorageKey  string `gorm:"uniqueIndex;not null" json:"-"`
+}
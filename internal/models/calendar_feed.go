@@ -0,0 +1,10 @@
+package models
+
+import "github.com/jinzhu/gorm"
+
+// Flux iCalendar d'un utilisateur, le token n'est stocké que haché
+type CalendarFeed struct {
+	gorm.Model
+	UserID    uint   `gorm:"uniqueIndex;not null" json:"user_id"`
+	TokenHash string `gorm:"uniqueIndex;not null" json:"-"`
+}
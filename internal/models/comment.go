@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Commentaire d'une tâche, seul son auteur peut le modifier
+type Comment struct {
+	gorm.Model
+	TaskID   uint       `gorm:"index;not null" json:"task_id"`
+	AuthorID uint       `gorm:"not null" json:"author_id"`
+	Body     string     `gorm:"type:text;not null" json:"body"`
+	EditedAt *time.Time `json:"edited_at"`
+}
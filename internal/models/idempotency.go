@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Clé d'idempotence d'une requête d'un utilisateur (en-tête Idempotency-Key).
+// La réponse de la première requête est conservée et rejouée pour les requêtes
+// suivantes avec la même clé, au lieu de les exécuter de nouveau.
+type IdempotencyKey struct {
+	gorm.Model
+	UserID uint   `gorm:"not null;uniqueIndex:idx_idempotency_keys_user_key"`
+	Key    string `gorm:"column:idempotency_key;not null;uniqueIndex:idx_idempotency_keys_user_key"`
+	// Fingerprint est le hash de la méthode, du chemin et du corps de la requête
+	Fingerprint string `gorm:"not null"`
+	// StatusCode vaut 0 tant que la première requête est en cours
+	StatusCode   int
+	ContentType  string
+	ResponseBody []byte
+	// LockedUntil borne l'exécution de la première requête : passé ce délai,
+	// une requête interrompue (arrêt de l'instance) peut être reprise
+	LockedUntil time.Time `gorm:"not null"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+}
+
+// Completed indique si la réponse de la première requête est enregistrée
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
+
+// Expired indique si la clé a expiré à la date now
+func (k *IdempotencyKey) Expired(now time.Time) bool {
+	return !now.Before(k.ExpiresAt)
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Secret TOTP d'un utilisateur. La double authentification n'est active qu'une fois
+// l'enrôlement confirmé par un premier code.
+type UserTOTP struct {
+	gorm.Model
+	UserID uint `gorm:"not null;uniqueIndex" json:"-"`
+	// Secret est en clair : il est nécessaire pour calculer les codes attendus
+	Secret      string     `gorm:"not null" json:"-"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+	// LastUsedStep est la dernière période TOTP acceptée, un code ne sert qu'une fois
+	LastUsedStep int64 `gorm:"not null;default:0" json:"-"`
+	// FailedAttempts compte les codes invalides consécutifs, tous mfa_token confondus
+	FailedAttempts int `gorm:"not null;default:0" json:"-"`
+	// LockedUntil bloque la vérification des codes après trop de codes invalides
+	LockedUntil *time.Time `json:"-"`
+}
+
+// Enabled indique si l'enrôlement est confirmé
+func (t *UserTOTP) Enabled() bool {
+	return t.ConfirmedAt != nil
+}
+
+// Code de récupération à usage unique, utilisable à la place d'un code TOTP.
+// Seul son hash est stocké.
+type RecoveryCode struct {
+	gorm.Model
+	UserID   uint       `gorm:"not null;index" json:"-"`
+	CodeHash string     `gorm:"not null;index" json:"-"`
+	UsedAt   *time.Time `json:"used_at"`
+}
+
+// Connexion en attente du second facteur, désignée par le mfa_token retourné
+// à l'étape du mot de passe. Seul le hash du mfa_token est stocké.
+type MFAChallenge struct {
+	gorm.Model
+	UserID    uint      `gorm:"not null;index" json:"-"`
+	TokenHash string    `gorm:"not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time `gorm:"not null" json:"-"`
+	// Attempts compte les codes invalides, le challenge est supprimé au-delà du maximum
+	Attempts int `gorm:"not null;default:0" json:"-"`
+}
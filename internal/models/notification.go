@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Types de notifications
+const (
+	NotificationMention = "mention"
+)
+
+// Notification destinée à un utilisateur, ex: mention dans un commentaire
+type Notification struct {
+	gorm.Model
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	Type      string     `gorm:"not null" json:"type"`
+	ActorID   uint       `json:"actor_id"`
+	TaskID    uint       `json:"task_id"`
+	CommentID uint       `json:"comment_id"`
+	ReadAt    *time.Time `json:"read_at"`
+}
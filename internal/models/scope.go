@@ -0,0 +1,40 @@
+package models
+
+// Scopes des tokens, portés par le claim scope des JWT et par les tokens d'accès personnels
+const (
+	// ScopeTasksRead permet de lire les tâches
+	ScopeTasksRead = "tasks:read"
+	// ScopeTasksWrite permet de créer et de modifier les tâches
+	ScopeTasksWrite = "tasks:write"
+	// ScopeTasksDelete permet de supprimer les tâches, leurs commentaires et leurs pièces jointes
+	ScopeTasksDelete = "tasks:delete"
+	// ScopeAdmin accorde tous les autres scopes
+	ScopeAdmin = "admin"
+)
+
+// Scopes liste les scopes connus, dans l'ordre d'affichage
+var Scopes = []string{ScopeTasksRead, ScopeTasksWrite, ScopeTasksDelete, ScopeAdmin}
+
+// UserScopes sont les scopes d'une connexion par mot de passe ou OIDC, et ceux
+// d'un JWT émis sans claim scope
+var UserScopes = []string{ScopeTasksRead, ScopeTasksWrite, ScopeTasksDelete}
+
+// ValidScope indique si un scope est connu
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope indique si les scopes accordés couvrent le scope demandé
+func HasScope(granted []string, scope string) bool {
+	for _, s := range granted {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
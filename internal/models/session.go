@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Session de connexion d'un utilisateur sur un appareil. Les JWT émis à la connexion
+// portent son ID dans le claim sid et sont refusés dès qu'elle est supprimée.
+type Session struct {
+	gorm.Model
+	UserID     uint      `gorm:"not null;index" json:"-"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `gorm:"column:ip" json:"ip"`
+	LastSeenAt time.Time `gorm:"not null" json:"last_seen_at"`
+	// ExpiresAt est l'expiration du JWT émis avec la session
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+}
+
+// Expired indique si la session a expiré à la date now
+func (s *Session) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
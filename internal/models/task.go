@@ -1,12 +1,24 @@
 package models
 
-import "github.com/jinzhu/gorm"
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Status possibles d'une tâche
+const (
+	StatusTodo       = "todo"
+	StatusInProgress = "in progress"
+	StatusDone       = "done"
+)
 
 // Tâche de l'Utilisateur
 type Task struct {
 	gorm.Model
-	Title       string `gorm:"not null" json:"title"`
-	Description string `json:"description"`
-	Status      string `gorm:"default:'todo'" json:"status"`
-	UserID      uint   `gorm:"not null"  json:"user_id"`
+	Title       string     `gorm:"not null" json:"title"`
+	Description string     `json:"description"`
+	Status      string     `gorm:"default:'todo'" json:"status"`
+	DueDate     *time.Time `json:"due_date"`
+	UserID      uint       `gorm:"not null"  json:"user_id"`
 }
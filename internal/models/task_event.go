@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Actions enregistrées dans l'historique d'une tâche
+const (
+	TaskEventCreated = "created"
+	TaskEventUpdated = "updated"
+	TaskEventDeleted = "deleted"
+)
+
+// Évènement de l'historique d'une tâche, en ajout seul.
+// UserID est le propriétaire de la tâche, ActorID l'auteur de la modification.
+// Changes contient le diff JSON des champs modifiés: {"champ": {"old": ..., "new": ...}}
+type TaskEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	TaskID    uint      `gorm:"index;not null" json:"task_id"`
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	ActorID   uint      `gorm:"not null" json:"actor_id"`
+	Action    string    `gorm:"not null" json:"action"`
+	Changes   string    `gorm:"type:text" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
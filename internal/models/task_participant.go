@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Participant d'une tâche : un utilisateur mentionné par le propriétaire de la tâche,
+// qui peut ensuite la consulter et la commenter
+type TaskParticipant struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	TaskID    uint `gorm:"not null;uniqueIndex:idx_task_participants_task_user"`
+	UserID    uint `gorm:"not null;uniqueIndex:idx_task_participants_task_user;index"`
+}
@@ -10,5 +10,4 @@ type User struct {
 	Username string `gorm:"unique;not null" json:"username"`
 	Email    string `gorm:"unique;not null" json:"email"`
 	Password string `gorm:"not null" json:"-"`
-	Task     []Task `gorm:"foreignKey:UserID"`
 }
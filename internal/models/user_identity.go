@@ -0,0 +1,14 @@
+package models
+
+import "github.com/jinzhu/gorm"
+
+// Identité d'un utilisateur chez un fournisseur OIDC externe.
+// Le couple (Provider, Subject) identifie le compte de façon stable, même si l'email change.
+type UserIdentity struct {
+	gorm.Model
+	UserID   uint   `gorm:"not null;index" json:"user_id"`
+	Provider string `gorm:"not null;uniqueIndex:idx_user_identities_provider_subject" json:"provider"`
+	Subject  string `gorm:"not null;uniqueIndex:idx_user_identities_provider_subject" json:"-"`
+	// Email est l'adresse vérifiée fournie lors de la première connexion
+	Email string `json:"email"`
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Intervalle entre deux suppressions des seaux pleins en mémoire
+const memorySweepInterval = time.Minute
+
+// Seaux conservés en mémoire, propres à l'instance
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	Bucket
+	fullAt time.Time
+}
+
+// NewMemoryStore retourne un Store en mémoire. Avec plusieurs instances derrière
+// un répartiteur de charge, chaque instance applique son propre quota.
+func NewMemoryStore() Store {
+	return &memoryStore{buckets: make(map[string]*memoryBucket)}
+}
+
+// Take consomme un jeton du seau key
+func (s *memoryStore) Take(ctx context.Context, key string, p Policy, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{Bucket: NewBucket(p, now)}
+		s.buckets[key] = b
+	}
+	res := b.Take(p, now)
+	b.fullAt = b.FullAt(p)
+	return res, nil
+}
+
+// sweep supprime les seaux redevenus pleins, pour que la mémoire ne grandisse pas
+// avec le nombre de clients passés
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"YoannLetacq/todo-api.git/config"
+
+	"gorm.io/gorm"
+)
+
+// Policy est le quota d'une classe de routes : Limit requêtes par Window.
+// Le seau contient au plus Limit jetons et se remplit en continu de Limit jetons par Window,
+// une rafale de Limit requêtes est donc possible après une période d'inactivité.
+type Policy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+// rate est le nombre de jetons ajoutés par seconde
+func (p Policy) rate() float64 {
+	return float64(p.Limit) / p.Window.Seconds()
+}
+
+// Header retourne la valeur de l'en-tête RateLimit-Policy, ex: 60;w=60
+func (p Policy) Header() string {
+	return fmt.Sprintf("%d;w=%d", p.Limit, int(math.Ceil(p.Window.Seconds())))
+}
+
+// Policies regroupe les quotas des classes de routes
+type Policies struct {
+	// Auth s'applique à l'inscription et à la connexion
+	Auth Policy
+	// Read s'applique aux requêtes GET
+	Read Policy
+	// Write s'applique aux requêtes qui modifient des données
+	Write Policy
+}
+
+// PoliciesFromConfig retourne les quotas configurés
+func PoliciesFromConfig(cfg config.RateLimitConfig) Policies {
+	return Policies{
+		Auth:  Policy{Name: "auth", Limit: cfg.AuthLimit, Window: cfg.AuthWindow},
+		Read:  Policy{Name: "read", Limit: cfg.ReadLimit, Window: cfg.ReadWindow},
+		Write: Policy{Name: "write", Limit: cfg.WriteLimit, Window: cfg.WriteWindow},
+	}
+}
+
+// Result est la décision pour une requête et l'état du seau après elle
+type Result struct {
+	Allowed bool
+	// Remaining est le nombre de requêtes encore possibles immédiatement
+	Remaining int
+	// Reset est le délai avant que le seau soit de nouveau plein
+	Reset time.Duration
+	// RetryAfter est le délai avant la prochaine requête possible, nul si Allowed
+	RetryAfter time.Duration
+}
+
+// Store conserve les seaux de jetons. Take consomme un jeton du seau key
+// selon la politique p et retourne la décision.
+type Store interface {
+	Take(ctx context.Context, key string, p Policy, now time.Time) (Result, error)
+}
+
+// NewStore retourne le Store configuré : "memory" propre à l'instance,
+// ou "sql" partagé entre les instances par la base db
+func NewStore(cfg config.RateLimitConfig, db *gorm.DB) (Store, error) {
+	switch cfg.Store {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "sql":
+		return NewSQLStore(db), nil
+	default:
+		return nil, fmt.Errorf("stockage des quotas inconnu: %q", cfg.Store)
+	}
+}
+
+// Bucket est l'état d'un seau de jetons, commun à toutes les implémentations de Store
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// NewBucket retourne un seau plein
+func NewBucket(p Policy, now time.Time) Bucket {
+	return Bucket{Tokens: float64(p.Limit), UpdatedAt: now}
+}
+
+// Take remplit le seau pour le temps écoulé puis consomme un jeton s'il y en a un
+func (b *Bucket) Take(p Policy, now time.Time) Result {
+	capacity, rate := float64(p.Limit), p.rate()
+	if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(capacity, b.Tokens+elapsed*rate)
+		b.UpdatedAt = now
+	}
+
+	res := Result{}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.Tokens) / rate)
+	}
+	res.Remaining = int(math.Floor(b.Tokens))
+	res.Reset = seconds((capacity - b.Tokens) / rate)
+	return res
+}
+
+// FullAt retourne la date à laquelle le seau sera de nouveau plein. Un seau plein
+// équivaut à un seau absent, il peut alors être supprimé du Store.
+func (b *Bucket) FullAt(p Policy) time.Time {
+	return b.UpdatedAt.Add(seconds((float64(p.Limit) - b.Tokens) / p.rate()))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// Nombre d'essais d'une mise à jour concurrente d'un même seau
+	sqlMaxAttempts = 5
+	// Intervalle entre deux suppressions des seaux pleins en base
+	sqlSweepInterval = time.Minute
+)
+
+// ErrContention est retournée quand un seau est modifié par trop de requêtes concurrentes
+var ErrContention = errors.New("seau de quota modifié par trop de requêtes concurrentes")
+
+// Ligne de la table rate_limit_buckets. Version permet une mise à jour optimiste :
+// une écriture n'aboutit que si la ligne n'a pas changé depuis sa lecture,
+// sans verrou de ligne, identique sous SQLite et PostgreSQL.
+type bucketRow struct {
+	BucketKey  string `gorm:"primaryKey"`
+	Tokens     float64
+	RefilledAt time.Time
+	// FullAt est la date à laquelle le seau sera de nouveau plein, la ligne peut alors être supprimée
+	FullAt  time.Time
+	Version int64
+}
+
+func (bucketRow) TableName() string {
+	return "rate_limit_buckets"
+}
+
+// Seaux conservés en base, partagés entre les instances
+type sqlStore struct {
+	db *gorm.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewSQLStore retourne un Store qui conserve les seaux dans la table rate_limit_buckets
+func NewSQLStore(db *gorm.DB) Store {
+	return &sqlStore{db: db}
+}
+
+// Take consomme un jeton du seau key, en réessayant si une autre instance l'a modifié entre-temps
+func (s *sqlStore) Take(ctx context.Context, key string, p Policy, now time.Time) (Result, error) {
+	db := s.db.WithContext(ctx)
+	if err := s.sweep(db, now); err != nil {
+		return Result{}, err
+	}
+
+	for attempt := 0; attempt < sqlMaxAttempts; attempt++ {
+		var row bucketRow
+		err := db.Where("bucket_key = ?", key).Take(&row).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			b := NewBucket(p, now)
+			res := b.Take(p, now)
+			row = bucketRow{BucketKey: key, Tokens: b.Tokens, RefilledAt: b.UpdatedAt, FullAt: b.FullAt(p)}
+			created := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&row)
+			if created.Error != nil {
+				return Result{}, created.Error
+			}
+			if created.RowsAffected == 1 {
+				return res, nil
+			}
+			// Une autre instance a créé le seau, il est relu
+			continue
+		}
+		if err != nil {
+			return Result{}, err
+		}
+
+		b := Bucket{Tokens: row.Tokens, UpdatedAt: row.RefilledAt}
+		res := b.Take(p, now)
+		updated := db.Model(&bucketRow{}).Where("bucket_key = ? AND version = ?", key, row.Version).Updates(map[string]interface{}{
+			"tokens":      b.Tokens,
+			"refilled_at": b.UpdatedAt,
+			"full_at":     b.FullAt(p),
+			"version":     row.Version + 1,
+		})
+		if updated.Error != nil {
+			return Result{}, updated.Error
+		}
+		if updated.RowsAffected == 1 {
+			return res, nil
+		}
+	}
+	return Result{}, ErrContention
+}
+
+// sweep supprime les seaux redevenus pleins, au plus une fois par minute et par instance
+func (s *sqlStore) sweep(db *gorm.DB, now time.Time) error {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < sqlSweepInterval {
+		s.mu.Unlock()
+		return nil
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+	return db.Where("full_at <= ?", now).Delete(&bucketRow{}).Error
+}
@@ -0,0 +1,144 @@
+package realtime
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Types d'évènements diffusés dans les rooms
+const (
+	EventTaskCreated   = "task.created"
+	EventTaskUpdated   = "task.updated"
+	EventTaskDeleted   = "task.deleted"
+	EventPresenceJoin  = "presence.join"
+	EventPresenceLeave = "presence.leave"
+)
+
+// Event est un message diffusé aux abonnés d'une room
+type Event struct {
+	Type   string      `json:"type"`
+	Room   string      `json:"room"`
+	UserID uint        `json:"user_id"`
+	Data   interface{} `json:"data,omitempty"`
+}
+
+// Subscriber représente une connexion abonnée à une ou plusieurs rooms.
+// Send ne doit jamais bloquer : il retourne false si le message n'a pas pu être mis en file.
+type Subscriber interface {
+	UserID() uint
+	Send(ev Event) bool
+}
+
+// Hub distribue les évènements aux abonnés de chaque room.
+// L'implémentation par défaut est en mémoire, une implémentation adossée
+// à un broker (Redis, NATS...) pourra respecter la même interface.
+type Hub interface {
+	Subscribe(room string, sub Subscriber)
+	Unsubscribe(room string, sub Subscriber)
+	UnsubscribeAll(sub Subscriber)
+	Publish(ev Event)
+	Presence(room string) []uint
+}
+
+// ListRoom retourne le nom de la room associée à la liste de tâches d'un utilisateur
+func ListRoom(userID uint) string {
+	return fmt.Sprintf("list:%d", userID)
+}
+
+// Implémentation en mémoire de l'interface Hub
+type memoryHub struct {
+	mu    sync.RWMutex
+	rooms map[string]map[Subscriber]struct{}
+}
+
+// NewHub retourne un Hub en mémoire, limité au processus courant
+func NewHub() Hub {
+	return &memoryHub{
+		rooms: make(map[string]map[Subscriber]struct{}),
+	}
+}
+
+// Abonne une connexion à une room et annonce sa présence
+func (h *memoryHub) Subscribe(room string, sub Subscriber) {
+	h.mu.Lock()
+	members, ok := h.rooms[room]
+	if !ok {
+		members = make(map[Subscriber]struct{})
+		h.rooms[room] = members
+	}
+	_, already := members[sub]
+	members[sub] = struct{}{}
+	h.mu.Unlock()
+
+	if !already {
+		h.Publish(Event{Type: EventPresenceJoin, Room: room, UserID: sub.UserID()})
+	}
+}
+
+// Désabonne une connexion d'une room et annonce son départ
+func (h *memoryHub) Unsubscribe(room string, sub Subscriber) {
+	if h.remove(room, sub) {
+		h.Publish(Event{Type: EventPresenceLeave, Room: room, UserID: sub.UserID()})
+	}
+}
+
+// Désabonne une connexion de toutes ses rooms, typiquement à la déconnexion
+func (h *memoryHub) UnsubscribeAll(sub Subscriber) {
+	h.mu.RLock()
+	var rooms []string
+	for room, members := range h.rooms {
+		if _, ok := members[sub]; ok {
+			rooms = append(rooms, room)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, room := range rooms {
+		h.Unsubscribe(room, sub)
+	}
+}
+
+// Diffuse un évènement à tous les abonnés de sa room
+func (h *memoryHub) Publish(ev Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.rooms[ev.Room] {
+		sub.Send(ev)
+	}
+}
+
+// Retourne les utilisateurs présents dans une room, sans doublon
+func (h *memoryHub) Presence(room string) []uint {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	seen := make(map[uint]struct{})
+	users := []uint{}
+	for sub := range h.rooms[room] {
+		if _, ok := seen[sub.UserID()]; ok {
+			continue
+		}
+		seen[sub.UserID()] = struct{}{}
+		users = append(users, sub.UserID())
+	}
+	return users
+}
+
+func (h *memoryHub) remove(room string, sub Subscriber) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	members, ok := h.rooms[room]
+	if !ok {
+		return false
+	}
+	if _, ok := members[sub]; !ok {
+		return false
+	}
+	delete(members, sub)
+	if len(members) == 0 {
+		delete(h.rooms, room)
+	}
+	return true
+}
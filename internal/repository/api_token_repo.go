@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type APITokenRepository interface {
+	CreateToken(ctx context.Context, token *models.APIToken) error
+	GetTokensByUser(ctx context.Context, userID uint) ([]models.APIToken, error)
+	GetTokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error)
+	TouchToken(ctx context.Context, id uint, usedAt time.Time) error
+	DeleteToken(ctx context.Context, userID, id uint) (bool, error)
+}
+
+// Implémentation par défaut de l'interface APITokenRepository
+type apiTokenRepository struct {
+	db *gorm.DB
+}
+
+// Retourne une instance de APITokenRepository
+func NewAPITokenRepository(db *gorm.DB) APITokenRepository {
+	return &apiTokenRepository{db: db}
+}
+
+// Enregistre un nouveau token
+func (r *apiTokenRepository) CreateToken(ctx context.Context, token *models.APIToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+// Retourne les tokens d'un utilisateur, les plus récents en premier
+func (r *apiTokenRepository) GetTokensByUser(ctx context.Context, userID uint) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// Retourne le token correspondant au hash d'un token
+func (r *apiTokenRepository) GetTokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	var token models.APIToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Enregistre la date de dernière utilisation sans toucher à updated_at
+func (r *apiTokenRepository) TouchToken(ctx context.Context, id uint, usedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.APIToken{}).Where("id = ?", id).UpdateColumn("last_used_at", usedAt).Error
+}
+
+// Supprime un token de l'utilisateur, retourne false s'il n'existe pas
+func (r *apiTokenRepository) DeleteToken(ctx context.Context, userID, id uint) (bool, error) {
+	res := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.APIToken{})
+	return res.RowsAffected > 0, res.Error
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+
+	"YoannLetacq/todo-api.git/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type AttachmentRepository interface {
+	CreateAttachment(ctx context.Context, attachment *models.Attachment) error
+	GetAttachmentsByTask(ctx context.Context, taskID uint) ([]models.Attachment, error)
+	GetAttachmentByID(ctx context.Context, attachmentID uint) (*models.Attachment, error)
+	DeleteAttachment(ctx context.Context, attachment *models.Attachment) error
+	DeleteAttachmentsByTask(ctx context.Context, taskID uint) ([]models.Attachment, error)
+}
+
+// Implémentation par défaut de l'interface AttachmentRepository
+type attachmentRepository struct {
+	db *gorm.DB
+}
+
+// Retourne une instance de AttachmentRepository
+func NewAttachmentRepository(db *gorm.DB) AttachmentRepository {
+	return &attachmentRepository{db: db}
+}
+
+// Enregistre une pièce jointe
+func (r *attachmentRepository) CreateAttachment(ctx context.Context, attachment *models.Attachment) error {
+	return r.db.WithContext(ctx).Create(attachment).Error
+}
+
+// Retourne les pièces jointes d'une tâche, de la plus ancienne à la plus récente
+func (r *attachmentRepository) GetAttachmentsByTask(ctx context.Context, taskID uint) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := r.db.WithContext(ctx).Where("task_id = ?", taskID).Order("created_at, id").Find(&attachments).Error
+	return attachments, err
+}
+
+// Retourne une pièce jointe par son ID
+func (r *attachmentRepository) GetAttachmentByID(ctx context.Context, attachmentID uint) (*models.Attachment, error) {
+	var attachment models.Attachment
+	err := r.db.WithContext(ctx).Where("id = ?", attachmentID).First(&attachment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// Supprime une pièce jointe
+func (r *attachmentRepository) DeleteAttachment(ctx context.Context, attachment *models.Attachment) error {
+	return r.db.WithContext(ctx).Delete(attachment).Error
+}
+
+// Supprime les pièces jointes d'une tâche et les retourne pour nettoyer leurs blobs
+func (r *attachmentRepository) DeleteAttachmentsByTask(ctx context.Context, taskID uint) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("task_id = ?", taskID).Find(&attachments).Error; err != nil {
+			return err
+		}
+		if len(attachments) == 0 {
+			return nil
+		}
+		return tx.Where("task_id = ?", taskID).Delete(&models.Attachment{}).Error
+	})
+	return attachments, err
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+
+	"YoannLetacq/todo-api.git/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type CalendarFeedRepository interface {
+	ReplaceFeed(ctx context.Context, feed *models.CalendarFeed) error
+	DeleteFeedByUser(ctx context.Context, userID uint) error
+	GetFeedByTokenHash(ctx context.Context, tokenHash string) (*models.CalendarFeed, error)
+}
+
+// Implémentation par défaut de l'interface CalendarFeedRepository
+type calendarFeedRepository struct {
+	db *gorm.DB
+}
+
+// Retourne une instance de CalendarFeedRepository
+func NewCalendarFeedRepository(db *gorm.DB) CalendarFeedRepository {
+	return &calendarFeedRepository{db: db}
+}
+
+// Remplace le flux existant de l'utilisateur, l'ancien token cesse de fonctionner
+func (r *calendarFeedRepository) ReplaceFeed(ctx context.Context, feed *models.CalendarFeed) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", feed.UserID).Delete(&models.CalendarFeed{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(feed).Error
+	})
+}
+
+// Supprime le flux d'un utilisateur
+func (r *calendarFeedRepository) DeleteFeedByUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.CalendarFeed{}).Error
+}
+
+// Retourne le flux correspondant au hash d'un token
+func (r *calendarFeedRepository) GetFeedByTokenHash(ctx context.Context, tokenHash string) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&feed).Error
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
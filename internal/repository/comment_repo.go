@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+
+	"YoannLetacq/todo-api.git/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CommentRepository interface {
+	CreateComment(ctx context.Context, comment *models.Comment, participants []models.TaskParticipant, notifications []models.Notification) error
+	GetCommentsByTask(ctx context.Context, taskID uint) ([]models.Comment, error)
+	GetCommentByID(ctx context.Context, commentID uint) (*models.Comment, error)
+	UpdateComment(ctx context.Context, comment *models.Comment, participants []models.TaskParticipant, notifications []models.Notification) error
+	DeleteComment(ctx context.Context, comment *models.Comment) error
+}
+
+// Implémentation par défaut de l'interface CommentRepository
+type commentRepository struct {
+	db *gorm.DB
+}
+
+// Retourne une instance de CommentRepository
+func NewCommentRepository(db *gorm.DB) CommentRepository {
+	return &commentRepository{db: db}
+}
+
+// Crée un commentaire, les participants ajoutés par ses mentions et leurs notifications
+// dans une transaction
+func (r *commentRepository) CreateComment(ctx context.Context, comment *models.Comment, participants []models.TaskParticipant, notifications []models.Notification) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+		if err := addParticipants(tx, participants); err != nil {
+			return err
+		}
+		return createNotifications(tx, comment, notifications)
+	})
+}
+
+// Retourne les commentaires d'une tâche, du plus ancien au plus récent
+func (r *commentRepository) GetCommentsByTask(ctx context.Context, taskID uint) ([]models.Comment, error) {
+	var comments []models.Comment
+	err := r.db.WithContext(ctx).Where("task_id = ?", taskID).Order("created_at, id").Find(&comments).Error
+	return comments, err
+}
+
+// Retourne un commentaire par son ID
+func (r *commentRepository) GetCommentByID(ctx context.Context, commentID uint) (*models.Comment, error) {
+	var comment models.Comment
+	err := r.db.WithContext(ctx).Where("id = ?", commentID).First(&comment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// Met à jour un commentaire, ajoute les participants et crée les notifications des nouvelles mentions
+func (r *commentRepository) UpdateComment(ctx context.Context, comment *models.Comment, participants []models.TaskParticipant, notifications []models.Notification) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(comment).Error; err != nil {
+			return err
+		}
+		if err := addParticipants(tx, participants); err != nil {
+			return err
+		}
+		return createNotifications(tx, comment, notifications)
+	})
+}
+
+// Supprime un commentaire
+func (r *commentRepository) DeleteComment(ctx context.Context, comment *models.Comment) error {
+	return r.db.WithContext(ctx).Delete(comment).Error
+}
+
+// addParticipants ajoute des participants, un utilisateur déjà participant est ignoré
+func addParticipants(tx *gorm.DB, participants []models.TaskParticipant) error {
+	if len(participants) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&participants).Error
+}
+
+func createNotifications(tx *gorm.DB, comment *models.Comment, notifications []models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	for i := range notifications {
+		notifications[i].CommentID = comment.ID
+	}
+	return tx.Create(&notifications).Error
+}
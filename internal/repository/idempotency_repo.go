@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository interface {
+	CreateKey(ctx context.Context, key *models.IdempotencyKey) (bool, error)
+	GetKey(ctx context.Context, userID uint, key string) (*models.IdempotencyKey, error)
+	LockKey(ctx context.Context, id uint, now, lockedUntil time.Time) (bool, error)
+	CompleteKey(ctx context.Context, id uint, statusCode int, contentType string, body []byte) error
+	DeleteKey(ctx context.Context, id uint) error
+	DeleteExpiredKeys(ctx context.Context, now time.Time) error
+}
+
+// Implémentation par défaut de l'interface IdempotencyRepository
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+// Retourne une instance de IdempotencyRepository
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// Enregistre une nouvelle clé, retourne false si l'utilisateur a déjà cette clé.
+// L'index unique (user_id, idempotency_key) garantit qu'une seule requête concurrente la crée.
+func (r *idempotencyRepository) CreateKey(ctx context.Context, key *models.IdempotencyKey) (bool, error) {
+	res := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+	return res.RowsAffected > 0, res.Error
+}
+
+// Retourne une clé de l'utilisateur
+func (r *idempotencyRepository) GetKey(ctx context.Context, userID uint, key string) (*models.IdempotencyKey, error) {
+	var k models.IdempotencyKey
+	err := r.db.WithContext(ctx).Where("user_id = ? AND idempotency_key = ?", userID, key).First(&k).Error
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// Reprend une clé dont la requête n'a pas abouti avant la fin de son verrou.
+// Retourne false si la requête a abouti ou si une autre requête l'a reprise entre-temps.
+func (r *idempotencyRepository) LockKey(ctx context.Context, id uint, now, lockedUntil time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.IdempotencyKey{}).
+		Where("id = ? AND status_code = 0 AND locked_until <= ?", id, now).
+		UpdateColumn("locked_until", lockedUntil)
+	return res.RowsAffected > 0, res.Error
+}
+
+// Enregistre la réponse de la requête
+func (r *idempotencyRepository) CompleteKey(ctx context.Context, id uint, statusCode int, contentType string, body []byte) error {
+	return r.db.WithContext(ctx).Model(&models.IdempotencyKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status_code":   statusCode,
+		"content_type":  contentType,
+		"response_body": body,
+	}).Error
+}
+
+// Supprime une clé, la requête peut alors être retentée
+func (r *idempotencyRepository) DeleteKey(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.IdempotencyKey{}).Error
+}
+
+// Supprime les clés expirées de tous les utilisateurs
+func (r *idempotencyRepository) DeleteExpiredKeys(ctx context.Context, now time.Time) error {
+	return r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&models.IdempotencyKey{}).Error
+}
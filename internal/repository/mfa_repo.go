@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type MFARepository interface {
+	GetTOTP(ctx context.Context, userID uint) (*models.UserTOTP, error)
+	SaveTOTP(ctx context.Context, totp *models.UserTOTP) error
+	ConfirmTOTP(ctx context.Context, totp *models.UserTOTP, step int64, codes []models.RecoveryCode) error
+	DeleteTOTP(ctx context.Context, userID uint) error
+	UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID uint, codeHash string, usedAt time.Time) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID uint) (int64, error)
+	AddCodeAttempt(ctx context.Context, userID uint, maxFailures int, now time.Time) (bool, error)
+	LockCodeAttempts(ctx context.Context, userID uint, maxFailures int, until time.Time) error
+	ResetCodeAttempts(ctx context.Context, userID uint) error
+	CreateChallenge(ctx context.Context, challenge *models.MFAChallenge) error
+	GetChallengeByHash(ctx context.Context, tokenHash string) (*models.MFAChallenge, error)
+	AddChallengeAttempt(ctx context.Context, id uint, maxAttempts int) (bool, error)
+	DeleteChallenge(ctx context.Context, id uint) (bool, error)
+}
+
+// Implémentation par défaut de l'interface MFARepository
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+// Retourne une instance de MFARepository
+func NewMFARepository(db *gorm.DB) MFARepository {
+	return &mfaRepository{db: db}
+}
+
+// Retourne le secret TOTP d'un utilisateur, confirmé ou non
+func (r *mfaRepository) GetTOTP(ctx context.Context, userID uint) (*models.UserTOTP, error) {
+	var totp models.UserTOTP
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&totp).Error
+	if err != nil {
+		return nil, err
+	}
+	return &totp, nil
+}
+
+// Crée ou remplace le secret TOTP d'un utilisateur
+func (r *mfaRepository) SaveTOTP(ctx context.Context, totp *models.UserTOTP) error {
+	return r.db.WithContext(ctx).Save(totp).Error
+}
+
+// Confirme l'enrôlement et remplace les codes de récupération dans une même transaction.
+// Le code de confirmation consomme sa période TOTP.
+func (r *mfaRepository) ConfirmTOTP(ctx context.Context, totp *models.UserTOTP, step int64, codes []models.RecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		res := tx.Model(&models.UserTOTP{}).Where("id = ? AND confirmed_at IS NULL", totp.ID).
+			Updates(map[string]interface{}{"confirmed_at": now, "last_used_step": step})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Where("user_id = ?", totp.UserID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		totp.ConfirmedAt = &now
+		totp.LastUsedStep = step
+		return tx.Create(&codes).Error
+	})
+}
+
+// Supprime le secret TOTP et les codes de récupération d'un utilisateur
+func (r *mfaRepository) DeleteTOTP(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.UserTOTP{}).Error
+	})
+}
+
+// Consomme une période TOTP, retourne false si elle (ou une plus récente) a déjà servi.
+// La condition dans l'UPDATE rend la vérification sûre entre requêtes concurrentes.
+func (r *mfaRepository) UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.UserTOTP{}).
+		Where("user_id = ? AND confirmed_at IS NOT NULL AND last_used_step < ?", userID, step).
+		UpdateColumn("last_used_step", step)
+	return res.RowsAffected > 0, res.Error
+}
+
+// Consomme un code de récupération, retourne false s'il est inconnu ou déjà utilisé
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string, usedAt time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		UpdateColumn("used_at", usedAt)
+	return res.RowsAffected > 0, res.Error
+}
+
+// Compte les codes de récupération encore utilisables
+func (r *mfaRepository) CountRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// Compte un code essayé par l'utilisateur, retourne false si la vérification est bloquée
+// ou si le nombre maximum de codes invalides consécutifs est atteint
+func (r *mfaRepository) AddCodeAttempt(ctx context.Context, userID uint, maxFailures int, now time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.UserTOTP{}).
+		Where("user_id = ? AND failed_attempts < ? AND (locked_until IS NULL OR locked_until <= ?)", userID, maxFailures, now).
+		UpdateColumn("failed_attempts", gorm.Expr("failed_attempts + 1"))
+	return res.RowsAffected > 0, res.Error
+}
+
+// Bloque la vérification des codes jusqu'à until si le nombre maximum de codes
+// invalides est atteint, le compteur repart alors de zéro
+func (r *mfaRepository) LockCodeAttempts(ctx context.Context, userID uint, maxFailures int, until time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.UserTOTP{}).
+		Where("user_id = ? AND failed_attempts >= ?", userID, maxFailures).
+		UpdateColumns(map[string]interface{}{"failed_attempts": 0, "locked_until": until}).Error
+}
+
+// Remet à zéro les codes invalides après un code accepté
+func (r *mfaRepository) ResetCodeAttempts(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Model(&models.UserTOTP{}).
+		Where("user_id = ?", userID).
+		UpdateColumn("failed_attempts", 0).Error
+}
+
+// Enregistre une connexion en attente du second facteur
+func (r *mfaRepository) CreateChallenge(ctx context.Context, challenge *models.MFAChallenge) error {
+	return r.db.WithContext(ctx).Create(challenge).Error
+}
+
+// Retourne la connexion en attente correspondant au hash d'un mfa_token
+func (r *mfaRepository) GetChallengeByHash(ctx context.Context, tokenHash string) (*models.MFAChallenge, error) {
+	var challenge models.MFAChallenge
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&challenge).Error
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// Compte un code invalide, retourne false si le nombre maximum d'essais est atteint
+func (r *mfaRepository) AddChallengeAttempt(ctx context.Context, id uint, maxAttempts int) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.MFAChallenge{}).
+		Where("id = ? AND attempts < ?", id, maxAttempts).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	return res.RowsAffected > 0, res.Error
+}
+
+// Supprime une connexion en attente, retourne false si elle a déjà été supprimée
+func (r *mfaRepository) DeleteChallenge(ctx context.Context, id uint) (bool, error) {
+	res := r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.MFAChallenge{})
+	return res.RowsAffected > 0, res.Error
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Nombre maximum de notifications retournées
+const maxNotifications = 100
+
+type NotificationRepository interface {
+	GetNotificationsByUser(ctx context.Context, userID uint) ([]models.Notification, error)
+	MarkNotificationRead(ctx context.Context, userID, notificationID uint) (bool, error)
+}
+
+// Implémentation par défaut de l'interface NotificationRepository
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+// Retourne une instance de NotificationRepository
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+// Retourne les dernières notifications d'un utilisateur, les plus récentes d'abord
+func (r *notificationRepository) GetNotificationsByUser(ctx context.Context, userID uint) ([]models.Notification, error) {
+	var notifications []models.Notification
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC, id DESC").Limit(maxNotifications).Find(&notifications).Error
+	return notifications, err
+}
+
+// Marque une notification de l'utilisateur comme lue, retourne false si elle n'existe pas
+func (r *notificationRepository) MarkNotificationRead(ctx context.Context, userID, notificationID uint) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", notificationID, userID).
+		Update("read_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return true, nil
+	}
+
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Notification{}).Where("id = ? AND user_id = ?", notificationID, userID).Count(&count).Error
+	return count > 0, err
+}
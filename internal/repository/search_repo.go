@@ -0,0 +1,293 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"html"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+
+	"YoannLetacq/todo-api.git/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Nombre maximum de termes pris en compte dans une recherche
+const maxSearchTerms = 8
+
+// Marqueurs de surlignage internes, remplacés par <mark> après échappement HTML
+const (
+	markStart = "\x02"
+	markStop  = "\x03"
+)
+
+// SearchResult est une tâche trouvée, son score et un extrait surligné
+type SearchResult struct {
+	Task    models.Task `json:"task"`
+	Rank    float64     `json:"rank"`
+	Snippet string      `json:"snippet"`
+}
+
+// Searcher recherche les tâches en texte intégral.
+// L'index est créé par les migrations (0009_search), Check vérifie seulement qu'il existe.
+type Searcher interface {
+	Check() error
+	Search(ctx context.Context, userID uint, query string, limit int) ([]SearchResult, error)
+}
+
+// NewSearcher retourne le Searcher adapté à la base : tsvector pour Postgres,
+// FTS5 pour SQLite. Il ne modifie pas le schéma. Si l'index FTS5 est absent
+// (SQLite compilé sans le tag sqlite_fts5), une recherche LIKE moins performante
+// est utilisée.
+func NewSearcher(db *gorm.DB) (Searcher, error) {
+	var searcher Searcher = &ftsSearcher{db: db}
+	if db.Dialector.Name() == "postgres" {
+		searcher = &postgresSearcher{db: db}
+	}
+
+	err := searcher.Check()
+	if errors.Is(err, errNoFTSIndex) {
+		log.Println("Avertissement: index FTS5 absent, recherche par LIKE utilisée")
+		searcher = &likeSearcher{db: db}
+		err = searcher.Check()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return searcher, nil
+}
+
+// errNoFTSIndex est retournée par ftsSearcher.Check quand la table tasks_fts n'existe pas
+var errNoFTSIndex = errors.New("index FTS5 absent")
+
+// searchRow est une ligne de résultat brute avant mise en forme
+type searchRow struct {
+	models.Task
+	Rank    float64
+	Snippet string
+}
+
+func toSearchResults(rows []searchRow) []SearchResult {
+	results := make([]SearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = SearchResult{Task: row.Task, Rank: row.Rank, Snippet: formatSnippet(row.Snippet)}
+	}
+	return results
+}
+
+// formatSnippet échappe l'extrait puis remplace les marqueurs par des balises <mark>
+func formatSnippet(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.ReplaceAll(snippet, markStart, "<mark>")
+	return strings.ReplaceAll(snippet, markStop, "</mark>")
+}
+
+// searchTerms découpe une requête en termes alphanumériques en minuscules
+func searchTerms(query string) []string {
+	terms := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	return terms
+}
+
+// Recherche SQLite FTS5 sur une table externe synchronisée par triggers
+type ftsSearcher struct {
+	db *gorm.DB
+}
+
+func (s *ftsSearcher) Check() error {
+	if !s.db.Migrator().HasTable("tasks_fts") {
+		return errNoFTSIndex
+	}
+	return nil
+}
+
+func (s *ftsSearcher) Search(ctx context.Context, userID uint, query string, limit int) ([]SearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	// Chaque terme est cherché en préfixe : "term"*
+	match := make([]string, len(terms))
+	for i, term := range terms {
+		match[i] = `"` + term + `"*`
+	}
+
+	var rows []searchRow
+	err := s.db.WithContext(ctx).Raw(`
+		SELECT tasks.*, -bm25(tasks_fts, 10.0, 1.0) AS rank,
+			snippet(tasks_fts, -1, ?, ?, '…', 12) AS snippet
+		FROM tasks_fts JOIN tasks ON tasks.id = tasks_fts.rowid
+		WHERE tasks_fts MATCH ? AND tasks.user_id = ?
+		ORDER BY rank DESC LIMIT ?`,
+		markStart, markStop, strings.Join(match, " "), userID, limit,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return toSearchResults(rows), nil
+}
+
+// Recherche Postgres sur une colonne tsvector générée et indexée en GIN
+type postgresSearcher struct {
+	db *gorm.DB
+}
+
+func (s *postgresSearcher) Check() error {
+	if !s.db.Migrator().HasColumn(&models.Task{}, "search_vector") {
+		return errors.New("colonne tasks.search_vector absente: appliquer les migrations (migrate up)")
+	}
+	return nil
+}
+
+func (s *postgresSearcher) Search(ctx context.Context, userID uint, query string, limit int) ([]SearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	// Chaque terme est cherché en préfixe : term:*
+	tsquery := make([]string, len(terms))
+	for i, term := range terms {
+		tsquery[i] = term + ":*"
+	}
+
+	var rows []searchRow
+	err := s.db.WithContext(ctx).Raw(`
+		SELECT tasks.*, ts_rank(search_vector, q) AS rank,
+			ts_headline('simple', coalesce(title, '') || ' — ' || coalesce(description, ''), q, ?) AS snippet
+		FROM tasks, to_tsquery('simple', ?) AS q
+		WHERE search_vector @@ q AND user_id = ?
+		ORDER BY rank DESC LIMIT ?`,
+		"StartSel="+markStart+", StopSel="+markStop+", MaxWords=20, MinWords=5",
+		strings.Join(tsquery, " & "), userID, limit,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return toSearchResults(rows), nil
+}
+
+// Recherche de secours par LIKE, classée en Go
+type likeSearcher struct {
+	db *gorm.DB
+}
+
+func (s *likeSearcher) Check() error {
+	return nil
+}
+
+func (s *likeSearcher) Search(ctx context.Context, userID uint, query string, limit int) ([]SearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	db := s.db.WithContext(ctx).Where("user_id = ?", userID)
+	for _, term := range terms {
+		pattern := "%" + term + "%"
+		db = db.Where("(LOWER(title) LIKE ? OR LOWER(description) LIKE ?)", pattern, pattern)
+	}
+
+	var tasks []models.Task
+	if err := db.Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	rows := make([]searchRow, len(tasks))
+	for i, task := range tasks {
+		rows[i] = searchRow{Task: task, Rank: likeRank(task, terms), Snippet: likeSnippet(task, terms)}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Rank > rows[j].Rank })
+	if len(rows) > limit {
+		rows = rows[:limit]
+	}
+	return toSearchResults(rows), nil
+}
+
+// likeRank favorise les termes trouvés dans le titre puis en début de mot
+func likeRank(task models.Task, terms []string) float64 {
+	title := strings.ToLower(task.Title)
+	description := strings.ToLower(task.Description)
+	rank := 0.0
+	for _, term := range terms {
+		if strings.Contains(title, term) {
+			rank += 2
+		}
+		if strings.Contains(description, term) {
+			rank++
+		}
+		if strings.HasPrefix(title, term) || strings.Contains(title, " "+term) {
+			rank++
+		}
+	}
+	return rank
+}
+
+// likeSnippet extrait le texte autour du premier terme trouvé et marque les occurrences
+func likeSnippet(task models.Task, terms []string) string {
+	text := task.Title
+	if task.Description != "" {
+		text += " — " + task.Description
+	}
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	type span struct{ start, end int }
+	var spans []span
+	for _, term := range terms {
+		t := []rune(term)
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) == term {
+				// Le surlignage couvre le mot entier, comme pour FTS5
+				end := i + len(t)
+				for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end])) {
+					end++
+				}
+				spans = append(spans, span{i, end})
+				i = end - 1
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return ""
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	from := spans[0].start - 40
+	if from < 0 {
+		from = 0
+	}
+	to := spans[0].end + 80
+	if to > len(runes) {
+		to = len(runes)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, sp := range spans {
+		if sp.start < pos || sp.end > to {
+			continue
+		}
+		b.WriteString(string(runes[pos:sp.start]))
+		b.WriteString(markStart + string(runes[sp.start:sp.end]) + markStop)
+		pos = sp.end
+	}
+	b.WriteString(string(runes[pos:to]))
+	if to < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
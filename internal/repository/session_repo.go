@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *models.Session) error
+	GetSession(ctx context.Context, userID, id uint) (*models.Session, error)
+	GetActiveSessions(ctx context.Context, userID uint, now time.Time) ([]models.Session, error)
+	TouchSession(ctx context.Context, id uint, seenAt time.Time) error
+	DeleteSession(ctx context.Context, userID, id uint) (bool, error)
+	DeleteOtherSessions(ctx context.Context, userID, keepID uint) (int64, error)
+	DeleteExpiredSessions(ctx context.Context, userID uint, now time.Time) error
+}
+
+// Implémentation par défaut de l'interface SessionRepository
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+// Retourne une instance de SessionRepository
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+// Enregistre une nouvelle session
+func (r *sessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+// Retourne une session de l'utilisateur
+func (r *sessionRepository) GetSession(ctx context.Context, userID, id uint) (*models.Session, error) {
+	var session models.Session
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Retourne les sessions non expirées de l'utilisateur, les plus récemment actives en premier
+func (r *sessionRepository) GetActiveSessions(ctx context.Context, userID uint, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.WithContext(ctx).Where("user_id = ? AND expires_at > ?", userID, now).
+		Order("last_seen_at DESC").Order("id DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// Enregistre la date de dernière activité sans toucher à updated_at
+func (r *sessionRepository) TouchSession(ctx context.Context, id uint, seenAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).Where("id = ?", id).UpdateColumn("last_seen_at", seenAt).Error
+}
+
+// Supprime une session de l'utilisateur, retourne false si elle n'existe pas
+func (r *sessionRepository) DeleteSession(ctx context.Context, userID, id uint) (bool, error) {
+	res := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.Session{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// Supprime les sessions de l'utilisateur sauf keepID (0 pour toutes) et retourne leur nombre
+func (r *sessionRepository) DeleteOtherSessions(ctx context.Context, userID, keepID uint) (int64, error) {
+	res := r.db.WithContext(ctx).Where("user_id = ? AND id <> ?", userID, keepID).Delete(&models.Session{})
+	return res.RowsAffected, res.Error
+}
+
+// Supprime les sessions expirées de l'utilisateur
+func (r *sessionRepository) DeleteExpiredSessions(ctx context.Context, userID uint, now time.Time) error {
+	return r.db.WithContext(ctx).Where("user_id = ? AND expires_at <= ?", userID, now).Delete(&models.Session{}).Error
+}
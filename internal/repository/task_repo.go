@@ -1,54 +1,221 @@
 package repository
 
 import (
-	"YoannLetacq/todo-api.git/config"
+	"context"
+	"errors"
+	"fmt"
+
 	"YoannLetacq/todo-api.git/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Actions possibles dans un lot d'opérations
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
 )
 
+// ErrBatchTaskFailed est l'erreur d'une opération d'un lot non appliquée parce qu'une
+// opération précédente sur la même tâche a échoué
+var ErrBatchTaskFailed = errors.New("opération précédente sur la tâche en échec")
+
+// BatchOperation est une opération unitaire d'un lot appliqué par ApplyBatch.
+// Event, s'il est fourni, est ajouté à l'historique dans la même transaction.
+type BatchOperation struct {
+	Action string
+	Task   *models.Task
+	Event  *models.TaskEvent
+}
+
 type TaskRepository interface {
-	CreateTask(task *models.Task) error
-	GetTasksByUser(userID uint) ([]models.Task, error)
-	GetTaskByID(taskID uint) (*models.Task, error)
-	UpdateTask(task *models.Task) error
-	DeleteTask(task *models.Task) error
+	CreateTask(ctx context.Context, task *models.Task, event *models.TaskEvent) error
+	GetTasksByUser(ctx context.Context, userID uint) ([]models.Task, error)
+	GetTaskByID(ctx context.Context, taskID uint) (*models.Task, error)
+	UpdateTask(ctx context.Context, task *models.Task, event *models.TaskEvent) error
+	DeleteTask(ctx context.Context, task *models.Task, event *models.TaskEvent) error
+	GetTasksByIDs(ctx context.Context, taskIDs []uint) ([]models.Task, error)
+	ApplyBatch(ctx context.Context, ops []BatchOperation, atomic bool) ([]error, error)
+	StreamTasksByUser(ctx context.Context, userID uint, fn func(task *models.Task) error) error
+	GetTaskStampsByUser(ctx context.Context, userID uint) ([]models.Task, error)
+	GetTaskEvents(ctx context.Context, taskID, userID uint, offset, limit int) ([]models.TaskEvent, int64, error)
+	IsTaskParticipant(ctx context.Context, taskID, userID uint) (bool, error)
 }
 
 // Implemetation par défaut de l'interface TaskRepository
-type taskRepository struct{}
+type taskRepository struct {
+	db *gorm.DB
+}
 
 // Retourne une instance de TaskRepository
-func NewTaskRepository() TaskRepository {
-	return &taskRepository{}
+func NewTaskRepository(db *gorm.DB) TaskRepository {
+	return &taskRepository{db: db}
 }
 
-// Créer une nouvelle tâche
-func (t *taskRepository) CreateTask(task *models.Task) error {
-	return config.DB.Create(task).Error
+// Créer une nouvelle tâche et son évènement d'historique
+func (t *taskRepository) CreateTask(ctx context.Context, task *models.Task, event *models.TaskEvent) error {
+	return t.applyInTransaction(ctx, BatchOperation{Action: BatchCreate, Task: task, Event: event})
 }
 
 // Retourne toutes les tâches d'un utilisateur
-func (t *taskRepository) GetTasksByUser(userID uint) ([]models.Task, error) {
+func (t *taskRepository) GetTasksByUser(ctx context.Context, userID uint) ([]models.Task, error) {
 	var tasks []models.Task
-	err := config.DB.Where("user_id = ?", userID).Find(&tasks).Error
+	err := t.db.WithContext(ctx).Where("user_id = ?", userID).Find(&tasks).Error
+	return tasks, err
+}
+
+// Parcourt les tâches d'un utilisateur par lots, sans les charger toutes en mémoire
+func (t *taskRepository) StreamTasksByUser(ctx context.Context, userID uint, fn func(task *models.Task) error) error {
+	var batch []models.Task
+	return t.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").FindInBatches(&batch, 100, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+// Retourne seulement l'ID et la date de mise à jour des tâches d'un utilisateur,
+// suffisant pour savoir si ses tâches ont changé
+func (t *taskRepository) GetTaskStampsByUser(ctx context.Context, userID uint) ([]models.Task, error) {
+	var tasks []models.Task
+	err := t.db.WithContext(ctx).Select("id", "updated_at").Where("user_id = ?", userID).Order("id").Find(&tasks).Error
 	return tasks, err
 }
 
 // Retourne une tâche par son ID
-func (t *taskRepository) GetTaskByID(taskID uint) (*models.Task, error) {
+func (t *taskRepository) GetTaskByID(ctx context.Context, taskID uint) (*models.Task, error) {
 	var task models.Task
-	err := config.DB.Where("id = ?", taskID).First(&task).Error
+	err := t.db.WithContext(ctx).Where("id = ?", taskID).First(&task).Error
 	if err != nil {
 		return nil, err
 	}
 	return &task, nil
 }
 
-// Met à jour une tâche
-func (t *taskRepository) UpdateTask(task *models.Task) error {
-	return config.DB.Save(task).Error
+// Indique si un utilisateur participe à une tâche
+func (t *taskRepository) IsTaskParticipant(ctx context.Context, taskID, userID uint) (bool, error) {
+	var count int64
+	err := t.db.WithContext(ctx).Model(&models.TaskParticipant{}).Where("task_id = ? AND user_id = ?", taskID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+// Met à jour une tâche et enregistre son évènement d'historique
+func (t *taskRepository) UpdateTask(ctx context.Context, task *models.Task, event *models.TaskEvent) error {
+	return t.applyInTransaction(ctx, BatchOperation{Action: BatchUpdate, Task: task, Event: event})
 }
 
-// Supprime une tâche
-func (t *taskRepository) DeleteTask(task *models.Task) error {
-	return config.DB.Delete(task).Error
+// Supprime une tâche et enregistre son évènement d'historique
+func (t *taskRepository) DeleteTask(ctx context.Context, task *models.Task, event *models.TaskEvent) error {
+	return t.applyInTransaction(ctx, BatchOperation{Action: BatchDelete, Task: task, Event: event})
+}
+
+// Retourne une page de l'historique d'une tâche, du plus récent au plus ancien, et le total
+func (t *taskRepository) GetTaskEvents(ctx context.Context, taskID, userID uint, offset, limit int) ([]models.TaskEvent, int64, error) {
+	var total int64
+	query := t.db.WithContext(ctx).Model(&models.TaskEvent{}).Where("task_id = ? AND user_id = ?", taskID, userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []models.TaskEvent
+	err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&events).Error
+	return events, total, err
+}
+
+func (t *taskRepository) applyInTransaction(ctx context.Context, op BatchOperation) error {
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return applyBatchOperation(tx, op)
+	})
+}
+
+// Retourne les tâches correspondant à une liste d'IDs en une seule requête
+func (t *taskRepository) GetTasksByIDs(ctx context.Context, taskIDs []uint) ([]models.Task, error) {
+	var tasks []models.Task
+	if len(taskIDs) == 0 {
+		return tasks, nil
+	}
+	err := t.db.WithContext(ctx).Where("id IN ?", taskIDs).Find(&tasks).Error
+	return tasks, err
+}
+
+// Applique un lot d'opérations dans une seule transaction.
+// En mode atomique, la première erreur annule tout le lot. Sinon chaque opération
+// est isolée dans un savepoint et seules celles en échec sont annulées. Les opérations
+// suivantes sur une tâche dont une opération a échoué ont été préparées à partir de
+// ses modifications annulées : elles ne sont pas appliquées et reçoivent ErrBatchTaskFailed.
+// Le premier retour contient l'erreur de chaque opération, le second une erreur de transaction.
+func (t *taskRepository) ApplyBatch(ctx context.Context, ops []BatchOperation, atomic bool) ([]error, error) {
+	errs := make([]error, len(ops))
+	failed := make(map[uint]bool)
+
+	err := t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, op := range ops {
+			if atomic {
+				if err := applyBatchOperation(tx, op); err != nil {
+					errs[i] = err
+					return err
+				}
+				continue
+			}
+
+			if op.Action != BatchCreate && failed[op.Task.ID] {
+				errs[i] = ErrBatchTaskFailed
+				continue
+			}
+			savepoint := fmt.Sprintf("bulk_%d", i)
+			if err := tx.SavePoint(savepoint).Error; err != nil {
+				return err
+			}
+			if err := applyBatchOperation(tx, op); err != nil {
+				errs[i] = err
+				if op.Action != BatchCreate {
+					failed[op.Task.ID] = true
+				}
+				if err := tx.RollbackTo(savepoint).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+
+	return errs, err
+}
+
+func applyBatchOperation(tx *gorm.DB, op BatchOperation) error {
+	var err error
+	switch op.Action {
+	case BatchCreate:
+		err = tx.Create(op.Task).Error
+	case BatchUpdate:
+		err = tx.Save(op.Task).Error
+	case BatchDelete:
+		if err = deleteTaskDiscussion(tx, op.Task.ID); err == nil {
+			err = tx.Delete(op.Task).Error
+		}
+	default:
+		err = fmt.Errorf("action de lot inconnue: %s", op.Action)
+	}
+	if err != nil || op.Event == nil {
+		return err
+	}
+
+	op.Event.TaskID = op.Task.ID
+	return tx.Create(op.Event).Error
+}
+
+// deleteTaskDiscussion supprime les commentaires d'une tâche, leurs notifications
+// et les participants de la tâche
+func deleteTaskDiscussion(tx *gorm.DB, taskID uint) error {
+	if err := tx.Where("task_id = ?", taskID).Delete(&models.Notification{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("task_id = ?", taskID).Delete(&models.Comment{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("task_id = ?", taskID).Delete(&models.TaskParticipant{}).Error
 }
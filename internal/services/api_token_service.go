@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// APITokenPrefix distingue un token d'accès personnel d'un JWT et permet
+	// aux outils de détection de secrets de le repérer
+	APITokenPrefix = "todo_pat_"
+	// Durée de validité par défaut et maximale d'un token d'accès personnel
+	DefaultAPITokenTTL = 30 * 24 * time.Hour
+	MaxAPITokenTTL     = 365 * 24 * time.Hour
+	// Intervalle minimum entre deux mises à jour de last_used_at, pour ne pas
+	// écrire en base à chaque requête d'un script
+	apiTokenTouchInterval = time.Minute
+	maxAPITokenNameLength = 100
+)
+
+var (
+	// ErrInvalidAPIToken est retournée pour un token inconnu, révoqué ou expiré
+	ErrInvalidAPIToken = errors.New("token d'accès invalide ou expiré")
+	// ErrAPITokenNotFound est retournée quand le token à révoquer n'existe pas
+	ErrAPITokenNotFound = errors.New("token d'accès introuvable")
+	// ErrInvalidAPITokenRequest est retournée pour un nom, des scopes ou une durée invalides
+	ErrInvalidAPITokenRequest = errors.New("demande de token d'accès invalide")
+)
+
+type APITokenService interface {
+	CreateToken(ctx context.Context, userID uint, name string, scopes []string, ttl time.Duration) (string, *models.APIToken, error)
+	ListTokens(ctx context.Context, userID uint) ([]models.APIToken, error)
+	RevokeToken(ctx context.Context, userID, tokenID uint) error
+	ResolveAPIToken(ctx context.Context, token string) (uint, []string, error)
+}
+
+type apiTokenService struct {
+	tokens repository.APITokenRepository
+}
+
+// NewAPITokenService cree une nouvelle instance de APITokenService
+func NewAPITokenService(tokens repository.APITokenRepository) APITokenService {
+	return &apiTokenService{tokens: tokens}
+}
+
+// CreateToken génère un token d'accès personnel. Le token n'est retourné qu'ici,
+// seul son hash est conservé. Une durée nulle vaut DefaultAPITokenTTL.
+func (s *apiTokenService) CreateToken(ctx context.Context, userID uint, name string, scopes []string, ttl time.Duration) (string, *models.APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxAPITokenNameLength {
+		return "", nil, fmt.Errorf("%w: le nom doit contenir entre 1 et 100 caractères", ErrInvalidAPITokenRequest)
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return "", nil, err
+	}
+	if ttl == 0 {
+		ttl = DefaultAPITokenTTL
+	}
+	if ttl < 0 || ttl > MaxAPITokenTTL {
+		return "", nil, fmt.Errorf("%w: la durée de validité doit être comprise entre 1 et 365 jours", ErrInvalidAPITokenRequest)
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, err
+	}
+	token := APITokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	record := &models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashAPIToken(token),
+		ScopeList: strings.Join(scopes, " "),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.tokens.CreateToken(ctx, record); err != nil {
+		return "", nil, err
+	}
+	return token, record, nil
+}
+
+// normalizeScopes vérifie les scopes demandés et les trie dans l'ordre de models.Scopes
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: au moins un scope est requis", ErrInvalidAPITokenRequest)
+	}
+	requested := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		if !models.ValidScope(scope) {
+			return nil, fmt.Errorf("%w: scope inconnu: %s", ErrInvalidAPITokenRequest, scope)
+		}
+		requested[scope] = true
+	}
+	var out []string
+	for _, scope := range models.Scopes {
+		if requested[scope] {
+			out = append(out, scope)
+		}
+	}
+	return out, nil
+}
+
+// ListTokens retourne les tokens de l'utilisateur, sans leur valeur
+func (s *apiTokenService) ListTokens(ctx context.Context, userID uint) ([]models.APIToken, error) {
+	return s.tokens.GetTokensByUser(ctx, userID)
+}
+
+// RevokeToken supprime un token de l'utilisateur, il cesse aussitôt de fonctionner
+func (s *apiTokenService) RevokeToken(ctx context.Context, userID, tokenID uint) error {
+	deleted, err := s.tokens.DeleteToken(ctx, userID, tokenID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// ResolveAPIToken retourne l'utilisateur et les scopes d'un token d'accès valide
+// et enregistre sa date d'utilisation
+func (s *apiTokenService) ResolveAPIToken(ctx context.Context, token string) (uint, []string, error) {
+	if !strings.HasPrefix(token, APITokenPrefix) {
+		return 0, nil, ErrInvalidAPIToken
+	}
+	record, err := s.tokens.GetTokenByHash(ctx, hashAPIToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil, ErrInvalidAPIToken
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	now := time.Now()
+	if record.Expired(now) {
+		return 0, nil, ErrInvalidAPIToken
+	}
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) >= apiTokenTouchInterval {
+		if err := s.tokens.TouchToken(ctx, record.ID, now); err != nil {
+			return 0, nil, err
+		}
+	}
+	return record.UserID, record.Scopes(), nil
+}
+
+// Un token de 256 bits aléatoires n'a pas besoin d'un hachage lent comme bcrypt
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/repository"
+	"YoannLetacq/todo-api.git/internal/storage"
+)
+
+// Taille maximale par défaut d'une pièce jointe
+const DefaultMaxAttachmentSize = 10 << 20
+
+var (
+	// ErrAttachmentNotFound est retournée quand la pièce jointe n'existe pas sur cette tâche
+	ErrAttachmentNotFound = errors.New("pièce jointe introuvable")
+	// ErrAttachmentTooLarge est retournée quand le fichier dépasse la taille maximale
+	ErrAttachmentTooLarge = errors.New("pièce jointe trop volumineuse")
+	// ErrAttachmentEmpty est retournée pour un fichier vide
+	ErrAttachmentEmpty = errors.New("pièce jointe vide")
+	// ErrUnsupportedMediaType est retournée quand le type détecté n'est pas autorisé
+	ErrUnsupportedMediaType = errors.New("type de fichier non autorisé")
+)
+
+// Types MIME autorisés, détectés à partir du contenu et non de l'en-tête du client
+var allowedAttachmentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+}
+
+type AttachmentService interface {
+	MaxSize() int64
+	ListAttachments(ctx context.Context, userID, taskID uint) ([]models.Attachment, error)
+	UploadAttachment(ctx context.Context, userID, taskID uint, filename string, r io.Reader) (*models.Attachment, error)
+	OpenAttachment(ctx context.Context, userID, taskID, attachmentID uint) (*models.Attachment, io.ReadCloser, error)
+	DeleteAttachment(ctx context.Context, userID, taskID, attachmentID uint) error
+	DeleteTaskAttachments(ctx context.Context, taskID uint) error
+}
+
+type attachmentService struct {
+	repo    repository.AttachmentRepository
+	tasks   repository.TaskRepository
+	blobs   storage.BlobStore
+	maxSize int64
+}
+
+// NewAttachmentService cree une nouvelle instance de AttachmentService
+func NewAttachmentService(repo repository.AttachmentRepository, tasks repository.TaskRepository, blobs storage.BlobStore, maxSize int64) AttachmentService {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxAttachmentSize
+	}
+	return &attachmentService{
+		repo:    repo,
+		tasks:   tasks,
+		blobs:   blobs,
+		maxSize: maxSize,
+	}
+}
+
+// MaxSize retourne la taille maximale acceptée pour une pièce jointe
+func (s *attachmentService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// ListAttachments retourne les pièces jointes d'une tâche accessible à l'utilisateur
+func (s *attachmentService) ListAttachments(ctx context.Context, userID, taskID uint) ([]models.Attachment, error) {
+	if err := s.checkAccess(ctx, userID, taskID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetAttachmentsByTask(ctx, taskID)
+}
+
+// UploadAttachment enregistre un fichier sur une tâche.
+// Le fichier est d'abord copié dans un fichier temporaire pour vérifier sa taille,
+// calculer son SHA-256 et détecter son type avant d'être envoyé au BlobStore.
+func (s *attachmentService) UploadAttachment(ctx context.Context, userID, taskID uint, filename string, r io.Reader) (*models.Attachment, error) {
+	if err := s.checkAccess(ctx, userID, taskID); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if size > s.maxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+	if size == 0 {
+		return nil, ErrAttachmentEmpty
+	}
+
+	head := make([]byte, 512)
+	n, err := tmp.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	contentType := http.DetectContentType(head[:n])
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !allowedAttachmentTypes[mediaType] {
+		return nil, ErrUnsupportedMediaType
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	key, err := attachmentKey(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.blobs.Put(ctx, key, tmp, size, contentType); err != nil {
+		return nil, err
+	}
+
+	attachment := &models.Attachment{
+		TaskID:      taskID,
+		UploaderID:  userID,
+		Filename:    cleanFilename(filename),
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		StorageKey:  key,
+	}
+	if err := s.repo.CreateAttachment(ctx, attachment); err != nil {
+		// Le blob ne doit pas rester orphelin si l'enregistrement échoue
+		if delErr := s.blobs.Delete(context.WithoutCancel(ctx), key); delErr != nil {
+			log.Println("Erreur lors de la suppression du blob orphelin:", delErr)
+		}
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// OpenAttachment retourne une pièce jointe et un lecteur sur son contenu, à fermer par l'appelant
+func (s *attachmentService) OpenAttachment(ctx context.Context, userID, taskID, attachmentID uint) (*models.Attachment, io.ReadCloser, error) {
+	attachment, err := s.taskAttachment(ctx, userID, taskID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := s.blobs.Get(ctx, attachment.StorageKey)
+	if errors.Is(err, storage.ErrBlobNotFound) {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+// DeleteAttachment supprime une pièce jointe et son blob, réservé à l'utilisateur
+// qui l'a envoyée et au propriétaire de la tâche
+func (s *attachmentService) DeleteAttachment(ctx context.Context, userID, taskID, attachmentID uint) error {
+	attachment, err := s.taskAttachment(ctx, userID, taskID, attachmentID)
+	if err != nil {
+		return err
+	}
+	if attachment.UploaderID != userID {
+		task, err := s.tasks.GetTaskByID(ctx, taskID)
+		if err != nil {
+			return ErrTaskNotFound
+		}
+		if task.UserID != userID {
+			return ErrForbidden
+		}
+	}
+	if err := s.repo.DeleteAttachment(ctx, attachment); err != nil {
+		return err
+	}
+	return s.blobs.Delete(ctx, attachment.StorageKey)
+}
+
+// DeleteTaskAttachments supprime toutes les pièces jointes d'une tâche supprimée.
+// Tous les blobs sont traités, la première erreur rencontrée est retournée.
+func (s *attachmentService) DeleteTaskAttachments(ctx context.Context, taskID uint) error {
+	attachments, err := s.repo.DeleteAttachmentsByTask(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	var firstErr error
+	for _, attachment := range attachments {
+		if err := s.blobs.Delete(ctx, attachment.StorageKey); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (s *attachmentService) checkAccess(ctx context.Context, userID, taskID uint) error {
+	task, err := s.tasks.GetTaskByID(ctx, taskID)
+	if err != nil {
+		return ErrTaskNotFound
+	}
+	ok, err := canAccessTask(ctx, s.tasks, userID, task)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// taskAttachment charge une pièce jointe d'une tâche accessible à l'utilisateur
+func (s *attachmentService) taskAttachment(ctx context.Context, userID, taskID, attachmentID uint) (*models.Attachment, error) {
+	if err := s.checkAccess(ctx, userID, taskID); err != nil {
+		return nil, err
+	}
+	attachment, err := s.repo.GetAttachmentByID(ctx, attachmentID)
+	if err != nil || attachment.TaskID != taskID {
+		return nil, ErrAttachmentNotFound
+	}
+	return attachment, nil
+}
+
+// attachmentKey génère une clé de stockage aléatoire rangée par tâche
+func attachmentKey(taskID uint) (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("tasks/%d/%s", taskID, hex.EncodeToString(raw)), nil
+}
+
+// cleanFilename garde le nom de base du fichier sans caractères de contrôle
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == "/" || name == "" {
+		return "fichier"
+	}
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[:255])
+	}
+	return name
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/repository"
+)
+
+// Version du rendu du flux, à incrémenter si le format iCalendar change
+const calendarFeedVersion = "v1"
+
+type CalendarService interface {
+	RotateFeedToken(ctx context.Context, userID uint) (string, error)
+	RevokeFeed(ctx context.Context, userID uint) error
+	ResolveFeedToken(ctx context.Context, token string) (uint, error)
+	FeedETag(ctx context.Context, userID uint) (string, error)
+	GetFeedTasks(ctx context.Context, userID uint) ([]models.Task, error)
+}
+
+type calendarService struct {
+	feeds repository.CalendarFeedRepository
+	tasks repository.TaskRepository
+}
+
+// NewCalendarService cree une nouvelle instance de CalendarService
+func NewCalendarService(feeds repository.CalendarFeedRepository, tasks repository.TaskRepository) CalendarService {
+	return &calendarService{
+		feeds: feeds,
+		tasks: tasks,
+	}
+}
+
+// RotateFeedToken génère un nouveau token de flux et révoque le précédent.
+// Le token n'est retourné qu'ici, seul son hash est conservé.
+func (s *calendarService) RotateFeedToken(ctx context.Context, userID uint) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	feed := &models.CalendarFeed{UserID: userID, TokenHash: hashFeedToken(token)}
+	if err := s.feeds.ReplaceFeed(ctx, feed); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RevokeFeed désactive le flux d'un utilisateur
+func (s *calendarService) RevokeFeed(ctx context.Context, userID uint) error {
+	return s.feeds.DeleteFeedByUser(ctx, userID)
+}
+
+// ResolveFeedToken retourne l'utilisateur propriétaire d'un token de flux
+func (s *calendarService) ResolveFeedToken(ctx context.Context, token string) (uint, error) {
+	feed, err := s.feeds.GetFeedByTokenHash(ctx, hashFeedToken(token))
+	if err != nil {
+		return 0, err
+	}
+	return feed.UserID, nil
+}
+
+// FeedETag calcule l'ETag du flux à partir des seuls IDs et dates de mise à jour,
+// sans charger ni rendre les tâches
+func (s *calendarService) FeedETag(ctx context.Context, userID uint) (string, error) {
+	stamps, err := s.tasks.GetTaskStampsByUser(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s;", calendarFeedVersion)
+	for _, t := range stamps {
+		fmt.Fprintf(h, "%d:%d;", t.ID, t.UpdatedAt.UnixNano())
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`, nil
+}
+
+// GetFeedTasks retourne les tâches à publier dans le flux
+func (s *calendarService) GetFeedTasks(ctx context.Context, userID uint) ([]models.Task, error) {
+	return s.tasks.GetTasksByUser(ctx, userID)
+}
+
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
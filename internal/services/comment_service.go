@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/repository"
+)
+
+// Longueur maximale d'un commentaire
+const maxCommentLength = 5000
+
+var (
+	// ErrTaskNotFound est retournée quand la tâche commentée n'existe pas
+	ErrTaskNotFound = errors.New("tâche introuvable")
+	// ErrCommentNotFound est retournée quand le commentaire n'existe pas sur cette tâche
+	ErrCommentNotFound = errors.New("commentaire introuvable")
+	// ErrForbidden est retournée quand l'utilisateur n'a pas le droit d'agir sur la ressource
+	ErrForbidden = errors.New("action non autorisée")
+	// ErrInvalidComment est retournée pour un commentaire vide ou trop long
+	ErrInvalidComment = errors.New("commentaire invalide")
+)
+
+// Une mention est un @username précédé d'un début de texte ou d'un séparateur,
+// ce qui exclut les adresses email
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([\w.-]{1,50})`)
+
+type CommentService interface {
+	ListComments(ctx context.Context, userID, taskID uint) ([]models.Comment, error)
+	AddComment(ctx context.Context, userID, taskID uint, body string) (*models.Comment, error)
+	EditComment(ctx context.Context, userID, taskID, commentID uint, body string) (*models.Comment, error)
+	DeleteComment(ctx context.Context, userID, taskID, commentID uint) error
+}
+
+type commentService struct {
+	comments repository.CommentRepository
+	tasks    repository.TaskRepository
+	users    repository.UserRepository
+}
+
+// NewCommentService cree une nouvelle instance de CommentService
+func NewCommentService(comments repository.CommentRepository, tasks repository.TaskRepository, users repository.UserRepository) CommentService {
+	return &commentService{
+		comments: comments,
+		tasks:    tasks,
+		users:    users,
+	}
+}
+
+// ListComments retourne les commentaires d'une tâche accessible à l'utilisateur
+func (s *commentService) ListComments(ctx context.Context, userID, taskID uint) ([]models.Comment, error) {
+	if _, err := s.accessibleTask(ctx, userID, taskID); err != nil {
+		return nil, err
+	}
+	return s.comments.GetCommentsByTask(ctx, taskID)
+}
+
+// AddComment ajoute un commentaire et notifie les utilisateurs mentionnés
+func (s *commentService) AddComment(ctx context.Context, userID, taskID uint, body string) (*models.Comment, error) {
+	body = strings.TrimSpace(body)
+	if body == "" || len(body) > maxCommentLength {
+		return nil, ErrInvalidComment
+	}
+
+	task, err := s.accessibleTask(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	participants, notifications, err := s.mentions(ctx, userID, task, body, "")
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &models.Comment{TaskID: taskID, AuthorID: userID, Body: body}
+	if err := s.comments.CreateComment(ctx, comment, participants, notifications); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// EditComment modifie un commentaire, réservé à son auteur.
+// Seules les nouvelles mentions donnent lieu à une notification.
+func (s *commentService) EditComment(ctx context.Context, userID, taskID, commentID uint, body string) (*models.Comment, error) {
+	body = strings.TrimSpace(body)
+	if body == "" || len(body) > maxCommentLength {
+		return nil, ErrInvalidComment
+	}
+
+	task, err := s.accessibleTask(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.taskComment(ctx, taskID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.AuthorID != userID {
+		return nil, ErrForbidden
+	}
+
+	participants, notifications, err := s.mentions(ctx, userID, task, body, comment.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	comment.Body = body
+	comment.EditedAt = &now
+	if err := s.comments.UpdateComment(ctx, comment, participants, notifications); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// DeleteComment supprime un commentaire, réservé à son auteur et au propriétaire de la tâche
+func (s *commentService) DeleteComment(ctx context.Context, userID, taskID, commentID uint) error {
+	task, err := s.accessibleTask(ctx, userID, taskID)
+	if err != nil {
+		return err
+	}
+
+	comment, err := s.taskComment(ctx, taskID, commentID)
+	if err != nil {
+		return err
+	}
+	if comment.AuthorID != userID && task.UserID != userID {
+		return ErrForbidden
+	}
+	return s.comments.DeleteComment(ctx, comment)
+}
+
+// accessibleTask charge une tâche et vérifie que l'utilisateur y a accès
+func (s *commentService) accessibleTask(ctx context.Context, userID, taskID uint) (*models.Task, error) {
+	task, err := s.tasks.GetTaskByID(ctx, taskID)
+	if err != nil {
+		return nil, ErrTaskNotFound
+	}
+	ok, err := canAccessTask(ctx, s.tasks, userID, task)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrForbidden
+	}
+	return task, nil
+}
+
+// taskComment charge un commentaire et vérifie qu'il appartient à la tâche
+func (s *commentService) taskComment(ctx context.Context, taskID, commentID uint) (*models.Comment, error) {
+	comment, err := s.comments.GetCommentByID(ctx, commentID)
+	if err != nil || comment.TaskID != taskID {
+		return nil, ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+// mentions traite les mentions de body absentes de previous. Une mention du propriétaire
+// de la tâche en donne l'accès : l'utilisateur mentionné en devient participant et il est
+// notifié. Une mention d'un participant ne notifie que les utilisateurs ayant déjà accès
+// à la tâche. L'auteur n'est jamais notifié de ses propres mentions.
+func (s *commentService) mentions(ctx context.Context, authorID uint, task *models.Task, body, previous string) ([]models.TaskParticipant, []models.Notification, error) {
+	known := make(map[string]bool)
+	for _, name := range parseMentions(previous) {
+		known[name] = true
+	}
+	var names []string
+	for _, name := range parseMentions(body) {
+		if !known[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil, nil
+	}
+
+	users, err := s.users.GetUsersByUsernames(ctx, names)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var participants []models.TaskParticipant
+	var notifications []models.Notification
+	for _, user := range users {
+		if user.ID == authorID {
+			continue
+		}
+		if authorID == task.UserID {
+			participants = append(participants, models.TaskParticipant{TaskID: task.ID, UserID: user.ID})
+		} else {
+			ok, err := canAccessTask(ctx, s.tasks, user.ID, task)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		notifications = append(notifications, models.Notification{
+			UserID:  user.ID,
+			Type:    models.NotificationMention,
+			ActorID: authorID,
+			TaskID:  task.ID,
+		})
+	}
+	return participants, notifications, nil
+}
+
+// parseMentions retourne les usernames mentionnés, sans doublon et dans l'ordre d'apparition
+func parseMentions(body string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		// Un point final appartient à la phrase, pas au username
+		name := strings.TrimRight(match[1], ".-")
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// canAccessTask indique si un utilisateur peut consulter une tâche, ses commentaires
+// et ses pièces jointes : son propriétaire et les participants qu'il a mentionnés
+func canAccessTask(ctx context.Context, tasks repository.TaskRepository, userID uint, task *models.Task) (bool, error) {
+	if task.UserID == userID {
+		return true, nil
+	}
+	return tasks.IsTaskParticipant(ctx, task.ID, userID)
+}
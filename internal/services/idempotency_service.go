@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// IdempotencyKeyTTL est la durée de conservation d'une clé et de sa réponse
+	IdempotencyKeyTTL = 24 * time.Hour
+	// Durée maximale d'exécution de la première requête : au-delà, elle est considérée
+	// interrompue et une requête avec la même clé peut la reprendre
+	idempotencyLockTTL = time.Minute
+	// Intervalle entre deux vérifications d'une clé dont la première requête est en cours
+	idempotencyPollInterval = 50 * time.Millisecond
+	// Intervalle minimum entre deux suppressions des clés expirées
+	idempotencyPurgeInterval = time.Minute
+	maxIdempotencyKeyLength  = 255
+)
+
+var (
+	// ErrInvalidIdempotencyKey est retournée pour une clé vide ou trop longue
+	ErrInvalidIdempotencyKey = errors.New("clé d'idempotence invalide")
+	// ErrIdempotencyKeyMismatch est retournée quand la clé a déjà servi pour une requête différente
+	ErrIdempotencyKeyMismatch = errors.New("clé d'idempotence déjà utilisée pour une autre requête")
+)
+
+type IdempotencyService interface {
+	Reserve(ctx context.Context, userID uint, key, fingerprint string) (*models.IdempotencyKey, error)
+	Complete(ctx context.Context, id uint, statusCode int, contentType string, body []byte) error
+	Release(ctx context.Context, id uint) error
+}
+
+type idempotencyService struct {
+	keys repository.IdempotencyRepository
+
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+// NewIdempotencyService cree une nouvelle instance de IdempotencyService
+func NewIdempotencyService(keys repository.IdempotencyRepository) IdempotencyService {
+	return &idempotencyService{keys: keys}
+}
+
+// Reserve réserve la clé de l'utilisateur pour une requête d'empreinte fingerprint.
+// Si la clé est nouvelle, elle est retournée sans réponse (Completed faux) et la requête
+// doit être exécutée puis terminée par Complete ou Release. Si la clé a déjà une réponse,
+// elle est retournée pour être rejouée. Si une requête avec la même clé est en cours,
+// éventuellement sur une autre instance, Reserve attend qu'elle se termine : les requêtes
+// concurrentes avec une même clé sont ainsi exécutées l'une après l'autre.
+func (s *idempotencyService) Reserve(ctx context.Context, userID uint, key, fingerprint string) (*models.IdempotencyKey, error) {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return nil, ErrInvalidIdempotencyKey
+	}
+	if err := s.purge(ctx, time.Now()); err != nil {
+		return nil, err
+	}
+
+	for {
+		now := time.Now()
+		k := &models.IdempotencyKey{
+			UserID:      userID,
+			Key:         key,
+			Fingerprint: fingerprint,
+			LockedUntil: now.Add(idempotencyLockTTL),
+			ExpiresAt:   now.Add(IdempotencyKeyTTL),
+		}
+		created, err := s.keys.CreateKey(ctx, k)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			return k, nil
+		}
+
+		existing, err := s.keys.GetKey(ctx, userID, key)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// La requête en cours a échoué et libéré la clé
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if existing.Expired(now) {
+			if err := s.keys.DeleteKey(ctx, existing.ID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if existing.Fingerprint != fingerprint {
+			return nil, ErrIdempotencyKeyMismatch
+		}
+		if existing.Completed() {
+			return existing, nil
+		}
+
+		if !now.Before(existing.LockedUntil) {
+			lockedUntil := now.Add(idempotencyLockTTL)
+			locked, err := s.keys.LockKey(ctx, existing.ID, now, lockedUntil)
+			if err != nil {
+				return nil, err
+			}
+			if locked {
+				existing.LockedUntil = lockedUntil
+				return existing, nil
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(idempotencyPollInterval):
+		}
+	}
+}
+
+// Complete enregistre la réponse de la requête, rejouée pour les requêtes suivantes avec la même clé
+func (s *idempotencyService) Complete(ctx context.Context, id uint, statusCode int, contentType string, body []byte) error {
+	return s.keys.CompleteKey(ctx, id, statusCode, contentType, body)
+}
+
+// Release libère la clé d'une requête qui n'a pas abouti, pour qu'elle puisse être retentée
+func (s *idempotencyService) Release(ctx context.Context, id uint) error {
+	return s.keys.DeleteKey(ctx, id)
+}
+
+// purge supprime les clés expirées, au plus une fois par minute
+func (s *idempotencyService) purge(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	if now.Sub(s.lastPurge) < idempotencyPurgeInterval {
+		s.mu.Unlock()
+		return nil
+	}
+	s.lastPurge = now
+	s.mu.Unlock()
+	return s.keys.DeleteExpiredKeys(ctx, now)
+}
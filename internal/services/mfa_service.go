@@ -0,0 +1,319 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/repository"
+	"YoannLetacq/todo-api.git/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// TOTPIssuer est le nom affiché par l'application d'authentification
+	TOTPIssuer = "Todo API"
+	// MFATokenTTL est le délai laissé pour saisir le code après le mot de passe
+	MFATokenTTL = 5 * time.Minute
+	// Nombre de codes essayés avec un même mfa_token avant qu'il soit invalidé
+	maxMFAAttempts = 5
+	// Nombre de codes invalides consécutifs d'un utilisateur, tous mfa_token confondus,
+	// avant que la vérification soit bloquée pendant MFALockout
+	maxMFAFailures = 10
+	// MFALockout est la durée du blocage après maxMFAFailures codes invalides
+	MFALockout = 15 * time.Minute
+	// Nombre de codes de récupération générés à la confirmation de l'enrôlement
+	recoveryCodeCount = 10
+	recoveryCodeSize  = 10
+)
+
+var (
+	// ErrMFANotEnrolled est retournée quand l'utilisateur n'a pas de TOTP à confirmer ou désactiver
+	ErrMFANotEnrolled = errors.New("double authentification non configurée")
+	// ErrMFAAlreadyEnabled est retournée pour un enrôlement alors que le TOTP est déjà actif
+	ErrMFAAlreadyEnabled = errors.New("double authentification déjà activée")
+	// ErrInvalidMFACode est retournée pour un code TOTP ou de récupération invalide ou déjà utilisé
+	ErrInvalidMFACode = errors.New("code de double authentification invalide")
+	// ErrMFALocked est retournée quand la vérification des codes est bloquée après
+	// trop de codes invalides
+	ErrMFALocked = errors.New("double authentification bloquée après trop de codes invalides")
+	// ErrInvalidMFAToken est retournée pour un mfa_token inconnu, expiré, déjà utilisé
+	// ou invalidé après trop d'essais
+	ErrInvalidMFAToken = errors.New("mfa_token invalide ou expiré")
+)
+
+// recoveryCodeEncoding produit des codes sans caractères ambigus à recopier
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type MFAService interface {
+	EnrollTOTP(ctx context.Context, userID uint) (string, string, error)
+	ConfirmTOTP(ctx context.Context, userID uint, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userID uint, code string) error
+	Status(ctx context.Context, userID uint) (bool, int64, error)
+	StartChallenge(ctx context.Context, userID uint) (string, error)
+	CompleteChallenge(ctx context.Context, mfaToken, code string) (*models.User, error)
+}
+
+type mfaService struct {
+	mfa   repository.MFARepository
+	users repository.UserRepository
+}
+
+// NewMFAService cree une nouvelle instance de MFAService
+func NewMFAService(mfa repository.MFARepository, users repository.UserRepository) MFAService {
+	return &mfaService{mfa: mfa, users: users}
+}
+
+// EnrollTOTP génère un nouveau secret TOTP et retourne le secret et son URI otpauth.
+// Le TOTP n'est actif qu'après ConfirmTOTP, un nouvel enrôlement remplace le secret non confirmé.
+func (s *mfaService) EnrollTOTP(ctx context.Context, userID uint) (string, string, error) {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+	totp, err := s.mfa.GetTOTP(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		totp = &models.UserTOTP{UserID: userID}
+	} else if err != nil {
+		return "", "", err
+	}
+	if totp.Enabled() {
+		return "", "", ErrMFAAlreadyEnabled
+	}
+
+	if totp.Secret, err = utils.NewTOTPSecret(); err != nil {
+		return "", "", err
+	}
+	if err := s.mfa.SaveTOTP(ctx, totp); err != nil {
+		return "", "", err
+	}
+	return totp.Secret, utils.TOTPURI(TOTPIssuer, user.Email, totp.Secret), nil
+}
+
+// ConfirmTOTP active le TOTP avec un premier code valide et retourne les codes de
+// récupération. Ils ne sont retournés qu'ici, seul leur hash est conservé.
+func (s *mfaService) ConfirmTOTP(ctx context.Context, userID uint, code string) ([]string, error) {
+	totp, err := s.mfa.GetTOTP(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if totp.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = newRecoveryCode(); err != nil {
+			return nil, err
+		}
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(codes[i])}
+	}
+	if err := s.mfa.ConfirmTOTP(ctx, totp, step, records); err != nil {
+		// Une confirmation concurrente a déjà activé le TOTP
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP désactive la double authentification après vérification d'un code
+func (s *mfaService) DisableTOTP(ctx context.Context, userID uint, code string) error {
+	totp, err := s.enabledTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.verifyCode(ctx, totp, code); err != nil {
+		return err
+	}
+	return s.mfa.DeleteTOTP(ctx, userID)
+}
+
+// Status indique si la double authentification est active et le nombre de codes
+// de récupération restants
+func (s *mfaService) Status(ctx context.Context, userID uint) (bool, int64, error) {
+	totp, err := s.enabledTOTP(ctx, userID)
+	if errors.Is(err, ErrMFANotEnrolled) {
+		return false, 0, nil
+	}
+	if err != nil {
+		return false, 0, err
+	}
+	remaining, err := s.mfa.CountRecoveryCodes(ctx, totp.UserID)
+	return true, remaining, err
+}
+
+// StartChallenge ouvre une connexion en attente du second facteur et retourne son mfa_token
+func (s *mfaService) StartChallenge(ctx context.Context, userID uint) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	challenge := &models.MFAChallenge{
+		UserID:    userID,
+		TokenHash: hashMFAToken(token),
+		ExpiresAt: time.Now().Add(MFATokenTTL),
+	}
+	if err := s.mfa.CreateChallenge(ctx, challenge); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// CompleteChallenge vérifie le code TOTP ou de récupération d'une connexion en attente
+// et retourne son utilisateur. Le mfa_token n'est utilisable qu'une fois et est invalidé
+// après maxMFAAttempts codes.
+func (s *mfaService) CompleteChallenge(ctx context.Context, mfaToken, code string) (*models.User, error) {
+	challenge, err := s.mfa.GetChallengeByHash(ctx, hashMFAToken(mfaToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidMFAToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(challenge.ExpiresAt) {
+		if _, err := s.mfa.DeleteChallenge(ctx, challenge.ID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidMFAToken
+	}
+
+	// L'essai est compté avant la vérification, des requêtes concurrentes ne peuvent
+	// donc pas dépasser le nombre maximum de codes essayés
+	allowed, err := s.mfa.AddChallengeAttempt(ctx, challenge.ID, maxMFAAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		if _, err := s.mfa.DeleteChallenge(ctx, challenge.ID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidMFAToken
+	}
+
+	// Le TOTP a pu être désactivé depuis l'étape du mot de passe
+	totp, err := s.enabledTOTP(ctx, challenge.UserID)
+	if errors.Is(err, ErrMFANotEnrolled) {
+		return nil, ErrInvalidMFAToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyCode(ctx, totp, code); err != nil {
+		return nil, err
+	}
+
+	deleted, err := s.mfa.DeleteChallenge(ctx, challenge.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		return nil, ErrInvalidMFAToken
+	}
+	return s.users.GetUserByID(ctx, challenge.UserID)
+}
+
+func (s *mfaService) enabledTOTP(ctx context.Context, userID uint) (*models.UserTOTP, error) {
+	totp, err := s.mfa.GetTOTP(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !totp.Enabled() {
+		return nil, ErrMFANotEnrolled
+	}
+	return totp, nil
+}
+
+// verifyCode accepte un code TOTP à 6 chiffres ou un code de récupération, chacun une seule fois.
+// Les codes invalides sont comptés par utilisateur : un nouveau mfa_token ne donne pas
+// de nouveaux essais, et la vérification est bloquée pendant MFALockout après maxMFAFailures.
+func (s *mfaService) verifyCode(ctx context.Context, totp *models.UserTOTP, code string) error {
+	// Comme pour le mfa_token, l'essai est compté avant la vérification
+	now := time.Now()
+	allowed, err := s.mfa.AddCodeAttempt(ctx, totp.UserID, maxMFAFailures, now)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrMFALocked
+	}
+
+	err = s.checkCode(ctx, totp, code)
+	if errors.Is(err, ErrInvalidMFACode) {
+		if err := s.mfa.LockCodeAttempts(ctx, totp.UserID, maxMFAFailures, now.Add(MFALockout)); err != nil {
+			return err
+		}
+		return ErrInvalidMFACode
+	}
+	if err != nil {
+		return err
+	}
+	return s.mfa.ResetCodeAttempts(ctx, totp.UserID)
+}
+
+// checkCode consomme le code TOTP ou de récupération s'il est valide
+func (s *mfaService) checkCode(ctx context.Context, totp *models.UserTOTP, code string) error {
+	if step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now()); ok {
+		used, err := s.mfa.UseTOTPStep(ctx, totp.UserID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := s.mfa.UseRecoveryCode(ctx, totp.UserID, hashRecoveryCode(code), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// newRecoveryCode génère un code de 80 bits lisible, au format xxxx-xxxx-xxxx-xxxx
+func newRecoveryCode() (string, error) {
+	raw := make([]byte, recoveryCodeSize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(recoveryCodeEncoding.EncodeToString(raw))
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16], nil
+}
+
+// hashRecoveryCode ignore la casse, les tirets et les espaces saisis par l'utilisateur.
+// 80 bits aléatoires n'ont pas besoin d'un hachage lent comme bcrypt.
+func hashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func hashMFAToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
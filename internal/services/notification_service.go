@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/repository"
+)
+
+// ErrNotificationNotFound est retournée quand la notification n'existe pas pour l'utilisateur
+var ErrNotificationNotFound = errors.New("notification introuvable")
+
+type NotificationService interface {
+	GetNotifications(ctx context.Context, userID uint) ([]models.Notification, error)
+	MarkRead(ctx context.Context, userID, notificationID uint) error
+}
+
+type notificationService struct {
+	repo repository.NotificationRepository
+}
+
+// NewNotificationService cree une nouvelle instance de NotificationService
+func NewNotificationService(repo repository.NotificationRepository) NotificationService {
+	return &notificationService{repo: repo}
+}
+
+// GetNotifications retourne les dernières notifications de l'utilisateur
+func (s *notificationService) GetNotifications(ctx context.Context, userID uint) ([]models.Notification, error) {
+	return s.repo.GetNotificationsByUser(ctx, userID)
+}
+
+// MarkRead marque une notification comme lue, ErrNotificationNotFound si elle n'appartient pas à l'utilisateur
+func (s *notificationService) MarkRead(ctx context.Context, userID, notificationID uint) error {
+	found, err := s.repo.MarkNotificationRead(ctx, userID, notificationID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"YoannLetacq/todo-api.git/config"
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/repository"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// Délai maximum des appels au fournisseur (découverte, échange du code, clés)
+const oidcHTTPTimeout = 10 * time.Second
+
+var (
+	// ErrUnknownProvider est retournée pour un fournisseur qui n'est pas configuré
+	ErrUnknownProvider = errors.New("fournisseur d'identité inconnu")
+	// ErrProviderUnavailable est retournée quand le fournisseur ne répond pas ou répond mal
+	ErrProviderUnavailable = errors.New("fournisseur d'identité indisponible")
+	// ErrOIDCLoginRejected est retournée quand le fournisseur refuse le code d'autorisation
+	ErrOIDCLoginRejected = errors.New("connexion refusée par le fournisseur d'identité")
+	// ErrInvalidIDToken est retournée pour un ID token absent, mal signé, expiré,
+	// émis pour un autre client ou dont le nonce ne correspond pas
+	ErrInvalidIDToken = errors.New("ID token invalide")
+	// ErrEmailNotVerified est retournée quand le fournisseur ne garantit pas l'email,
+	// qui ne peut alors servir ni à lier ni à créer un compte
+	ErrEmailNotVerified = errors.New("email non vérifié par le fournisseur d'identité")
+)
+
+// OIDCLogin est l'état d'une connexion en cours. Il est conservé par le client
+// entre la redirection vers le fournisseur et le callback, et n'est utilisable qu'une fois.
+type OIDCLogin struct {
+	// State protège le callback contre les requêtes forgées (CSRF)
+	State string `json:"state"`
+	// Nonce lie l'ID token à cette connexion
+	Nonce string `json:"nonce"`
+	// Verifier est le code_verifier PKCE, seul son hash est envoyé au fournisseur
+	Verifier string `json:"verifier"`
+}
+
+type OIDCService interface {
+	AuthCodeURL(ctx context.Context, provider string) (string, *OIDCLogin, error)
+	Callback(ctx context.Context, provider, code string, login *OIDCLogin) (*models.User, error)
+}
+
+type oidcService struct {
+	users      repository.UserRepository
+	identities repository.IdentityRepository
+	providers  map[string]*oidcProvider
+}
+
+// NewOIDCService cree une nouvelle instance de OIDCService pour les fournisseurs configurés
+func NewOIDCService(users repository.UserRepository, identities repository.IdentityRepository, providers map[string]*config.OIDCProviderConfig) OIDCService {
+	client := &http.Client{Timeout: oidcHTTPTimeout}
+	s := &oidcService{users: users, identities: identities, providers: make(map[string]*oidcProvider)}
+	for name, cfg := range providers {
+		s.providers[name] = &oidcProvider{name: name, cfg: *cfg, client: client}
+	}
+	return s
+}
+
+// oidcProvider découvre la configuration du fournisseur à la première connexion
+// et la garde en cache. Un fournisseur indisponible au démarrage n'empêche donc
+// pas le serveur de démarrer, la découverte est retentée à la connexion suivante.
+type oidcProvider struct {
+	name   string
+	cfg    config.OIDCProviderConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// discover lit <issuer>/.well-known/openid-configuration
+func (p *oidcProvider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.verifier != nil {
+		return p.oauth2, p.verifier, nil
+	}
+
+	discovered, err := oidc.NewProvider(oidc.ClientContext(ctx, p.client), p.cfg.Issuer)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		return nil, nil, fmt.Errorf("%w: %s: %v", ErrProviderUnavailable, p.name, err)
+	}
+
+	p.oauth2 = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint:     discovered.Endpoint(),
+		Scopes:       append([]string{oidc.ScopeOpenID}, strings.Fields(p.cfg.Scopes)...),
+	}
+	// Le verifier contrôle la signature avec les clés publiées par le fournisseur (JWKS),
+	// l'émetteur, l'audience (notre client_id) et l'expiration
+	p.verifier = discovered.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+	return p.oauth2, p.verifier, nil
+}
+
+func (s *oidcService) provider(name string) (*oidcProvider, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// AuthCodeURL prépare une connexion et retourne l'URL d'autorisation du fournisseur,
+// avec un state, un nonce et un challenge PKCE (S256) propres à cette connexion
+func (s *oidcService) AuthCodeURL(ctx context.Context, provider string) (string, *OIDCLogin, error) {
+	p, err := s.provider(provider)
+	if err != nil {
+		return "", nil, err
+	}
+	conf, _, err := p.discover(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	state, err := randomOIDCValue()
+	if err != nil {
+		return "", nil, err
+	}
+	nonce, err := randomOIDCValue()
+	if err != nil {
+		return "", nil, err
+	}
+	login := &OIDCLogin{State: state, Nonce: nonce, Verifier: oauth2.GenerateVerifier()}
+
+	url := conf.AuthCodeURL(login.State, oidc.Nonce(login.Nonce), oauth2.S256ChallengeOption(login.Verifier))
+	return url, login, nil
+}
+
+// oidcClaims sont les claims de l'ID token utilisés pour lier ou créer le compte
+type oidcClaims struct {
+	Email string `json:"email"`
+	// EmailVerified est un booléen, mais certains fournisseurs envoient "true"
+	EmailVerified     interface{} `json:"email_verified"`
+	PreferredUsername string      `json:"preferred_username"`
+}
+
+func (c oidcClaims) emailVerified() bool {
+	switch v := c.EmailVerified.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// Callback échange le code d'autorisation, valide l'ID token et retourne l'utilisateur.
+// Un compte externe déjà lié retrouve son utilisateur ; sinon il est lié à l'utilisateur
+// ayant le même email vérifié, ou un nouvel utilisateur sans mot de passe est créé.
+func (s *oidcService) Callback(ctx context.Context, provider, code string, login *OIDCLogin) (*models.User, error) {
+	p, err := s.provider(provider)
+	if err != nil {
+		return nil, err
+	}
+	conf, verifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clientCtx := oidc.ClientContext(ctx, p.client)
+	token, err := conf.Exchange(clientCtx, code, oauth2.VerifierOption(login.Verifier))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) && retrieveErr.Response.StatusCode < http.StatusInternalServerError {
+			return nil, fmt.Errorf("%w: %s", ErrOIDCLoginRejected, retrieveErr.ErrorCode)
+		}
+		return nil, fmt.Errorf("%w: %s: %v", ErrProviderUnavailable, p.name, err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: absent de la réponse", ErrInvalidIDToken)
+	}
+	idToken, err := verifier.Verify(clientCtx, rawIDToken)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(login.Nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce inattendu", ErrInvalidIDToken)
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	return s.linkUser(ctx, p.name, idToken.Subject, claims)
+}
+
+// linkUser retrouve, lie ou crée l'utilisateur d'un compte externe
+func (s *oidcService) linkUser(ctx context.Context, provider, subject string, claims oidcClaims) (*models.User, error) {
+	identity, err := s.identities.GetIdentity(ctx, provider, subject)
+	if err == nil {
+		return s.users.GetUserByID(ctx, identity.UserID)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Sans email vérifié, lier le compte permettrait de prendre celui d'un autre utilisateur
+	email := strings.TrimSpace(claims.Email)
+	if email == "" || !claims.emailVerified() {
+		return nil, ErrEmailNotVerified
+	}
+	identity = &models.UserIdentity{Provider: provider, Subject: subject, Email: email}
+
+	user, err := s.users.GetUserByEmail(ctx, email)
+	if err == nil {
+		identity.UserID = user.ID
+		if err := s.identities.CreateIdentity(ctx, identity); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	username, err := s.availableUsername(ctx, claims.PreferredUsername, email)
+	if err != nil {
+		return nil, err
+	}
+	// Sans mot de passe, la connexion par email et mot de passe est impossible pour ce compte
+	user = &models.User{Username: username, Email: email}
+	if err := s.identities.CreateUserWithIdentity(ctx, user, identity); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Caractères retirés d'un nom d'utilisateur, qui doit rester mentionnable avec @
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// availableUsername dérive un nom d'utilisateur libre de preferred_username ou de l'email
+func (s *oidcService) availableUsername(ctx context.Context, preferred, email string) (string, error) {
+	base := usernameInvalidChars.ReplaceAllString(preferred, "")
+	if base == "" {
+		local, _, _ := strings.Cut(email, "@")
+		base = usernameInvalidChars.ReplaceAllString(local, "")
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	candidates := []string{base}
+	for i := 2; i <= 9; i++ {
+		candidates = append(candidates, fmt.Sprintf("%s%d", base, i))
+	}
+	taken, err := s.users.GetUsersByUsernames(ctx, candidates)
+	if err != nil {
+		return "", err
+	}
+	used := make(map[string]bool, len(taken))
+	for _, u := range taken {
+		used[u.Username] = true
+	}
+	for _, candidate := range candidates {
+		if !used[candidate] {
+			return candidate, nil
+		}
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return base + "-" + hex.EncodeToString(suffix), nil
+}
+
+func randomOIDCValue() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
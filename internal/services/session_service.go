@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/repository"
+	"YoannLetacq/todo-api.git/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// Intervalle minimum entre deux mises à jour de last_seen_at
+	sessionTouchInterval = time.Minute
+	maxUserAgentLength   = 512
+)
+
+var (
+	// ErrSessionNotFound est retournée quand la session à révoquer n'existe pas
+	ErrSessionNotFound = errors.New("session introuvable")
+	// ErrSessionRevoked est retournée pour un token dont la session est révoquée ou expirée
+	ErrSessionRevoked = errors.New("session révoquée ou expirée")
+)
+
+type SessionService interface {
+	StartSession(ctx context.Context, userID uint, userAgent, ip string) (*models.Session, error)
+	ValidateSession(ctx context.Context, userID, sessionID uint) error
+	ListSessions(ctx context.Context, userID uint) ([]models.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID uint) error
+	RevokeOtherSessions(ctx context.Context, userID, currentID uint) (int64, error)
+}
+
+type sessionService struct {
+	sessions repository.SessionRepository
+}
+
+// NewSessionService cree une nouvelle instance de SessionService
+func NewSessionService(sessions repository.SessionRepository) SessionService {
+	return &sessionService{sessions: sessions}
+}
+
+// StartSession enregistre une connexion, elle expire avec le JWT émis pour elle.
+// Les sessions expirées de l'utilisateur sont supprimées au passage.
+func (s *sessionService) StartSession(ctx context.Context, userID uint, userAgent, ip string) (*models.Session, error) {
+	now := time.Now()
+	if err := s.sessions.DeleteExpiredSessions(ctx, userID, now); err != nil {
+		return nil, err
+	}
+	if utf8.RuneCountInString(userAgent) > maxUserAgentLength {
+		userAgent = string([]rune(userAgent)[:maxUserAgentLength])
+	}
+	session := &models.Session{
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(utils.TokenTTL),
+	}
+	if err := s.sessions.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// ValidateSession vérifie que la session d'un token existe encore et enregistre son activité
+func (s *sessionService) ValidateSession(ctx context.Context, userID, sessionID uint) error {
+	session, err := s.sessions.GetSession(ctx, userID, sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrSessionRevoked
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if session.Expired(now) {
+		return ErrSessionRevoked
+	}
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		return s.sessions.TouchSession(ctx, session.ID, now)
+	}
+	return nil
+}
+
+// ListSessions retourne les sessions actives de l'utilisateur
+func (s *sessionService) ListSessions(ctx context.Context, userID uint) ([]models.Session, error) {
+	return s.sessions.GetActiveSessions(ctx, userID, time.Now())
+}
+
+// RevokeSession supprime une session, ses tokens sont aussitôt refusés
+func (s *sessionService) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	deleted, err := s.sessions.DeleteSession(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeOtherSessions supprime toutes les sessions sauf la session courante et retourne leur nombre.
+// Sans session courante (currentID à 0), toutes les sessions sont supprimées.
+func (s *sessionService) RevokeOtherSessions(ctx context.Context, userID, currentID uint) (int64, error) {
+	return s.sessions.DeleteOtherSessions(ctx, userID, currentID)
+}
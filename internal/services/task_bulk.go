@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/repository"
+)
+
+// Opérations acceptées par BulkTasks
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+	BulkStatus = "status"
+)
+
+// ErrBulkAborted est retournée quand un lot atomique a été entièrement annulé
+var ErrBulkAborted = errors.New("lot d'opérations annulé")
+
+// BulkOperation est une opération unitaire d'un lot de tâches
+type BulkOperation struct {
+	Op          string     `json:"op"`
+	ID          uint       `json:"id,omitempty"`
+	Title       string     `json:"title,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Status      string     `json:"status,omitempty"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+}
+
+// BulkResult est le résultat d'une opération du lot, à la même position
+type BulkResult struct {
+	Index int          `json:"index"`
+	Op    string       `json:"op"`
+	OK    bool         `json:"ok"`
+	Error string       `json:"error,omitempty"`
+	Task  *models.Task `json:"task,omitempty"`
+}
+
+// Applique un lot d'opérations sur les tâches d'un utilisateur.
+// Les tâches visées sont chargées en une seule requête puis le lot est appliqué
+// dans une seule transaction. En mode atomique, une seule erreur annule tout le lot
+// et ErrBulkAborted est retournée avec le détail par opération.
+func (s *taskService) BulkTasks(ctx context.Context, userID uint, ops []BulkOperation, atomic bool) ([]BulkResult, error) {
+	results := make([]BulkResult, len(ops))
+
+	var ids []uint
+	for _, op := range ops {
+		if op.Op != BulkCreate && op.ID != 0 {
+			ids = append(ids, op.ID)
+		}
+	}
+
+	existing, err := s.repo.GetTasksByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	tasks := make(map[uint]*models.Task, len(existing))
+	for i := range existing {
+		tasks[existing[i].ID] = &existing[i]
+	}
+
+	var batch []repository.BatchOperation
+	var positions []int
+	failed := false
+
+	for i, op := range ops {
+		results[i] = BulkResult{Index: i, Op: op.Op}
+
+		batchOp, err := prepareBulkOperation(userID, op, tasks)
+		if err != nil {
+			results[i].Error = err.Error()
+			failed = true
+			continue
+		}
+		batch = append(batch, batchOp)
+		positions = append(positions, i)
+	}
+
+	if atomic && failed {
+		cancelBulkResults(results)
+		return results, ErrBulkAborted
+	}
+
+	errs, txErr := s.repo.ApplyBatch(ctx, batch, atomic)
+	for j, i := range positions {
+		if errors.Is(errs[j], repository.ErrBatchTaskFailed) {
+			results[i].Error = "Opération annulée, une opération précédente sur cette tâche a échoué."
+			failed = true
+			continue
+		}
+		if errs[j] != nil {
+			results[i].Error = "Echec de l'opération."
+			failed = true
+			continue
+		}
+		results[i].OK = true
+		results[i].Task = batch[j].Task
+	}
+
+	if txErr != nil {
+		if atomic && failed {
+			cancelBulkResults(results)
+			return results, ErrBulkAborted
+		}
+		return nil, txErr
+	}
+
+	return results, nil
+}
+
+// Valide une opération et la traduit en opération de repository.
+// La map des tâches est mise à jour pour que les opérations suivantes voient ses effets.
+func prepareBulkOperation(userID uint, op BulkOperation, tasks map[uint]*models.Task) (repository.BatchOperation, error) {
+	if op.Op == BulkCreate {
+		if op.Title == "" {
+			return repository.BatchOperation{}, errors.New("Titre manquant.")
+		}
+		task := &models.Task{Title: op.Title, Description: op.Description, Status: op.Status, DueDate: op.DueDate, UserID: userID}
+		if task.Status == "" {
+			task.Status = "todo"
+		}
+		return repository.BatchOperation{
+			Action: repository.BatchCreate,
+			Task:   task,
+			Event:  newTaskEvent(userID, models.TaskEventCreated, nil, task),
+		}, nil
+	}
+
+	if op.Op != BulkUpdate && op.Op != BulkDelete && op.Op != BulkStatus {
+		return repository.BatchOperation{}, errors.New("Opération inconnue.")
+	}
+
+	current, ok := tasks[op.ID]
+	if !ok {
+		return repository.BatchOperation{}, errors.New("Tâche introuvable.")
+	}
+	if current.UserID != userID {
+		return repository.BatchOperation{}, errors.New("Cette tâche ne vous appartiens pas.")
+	}
+
+	task := *current
+	switch op.Op {
+	case BulkDelete:
+		delete(tasks, op.ID)
+		return repository.BatchOperation{
+			Action: repository.BatchDelete,
+			Task:   &task,
+			Event:  newTaskEvent(userID, models.TaskEventDeleted, current, nil),
+		}, nil
+	case BulkStatus:
+		if op.Status == "" {
+			return repository.BatchOperation{}, errors.New("Status manquant.")
+		}
+		task.Status = op.Status
+	default:
+		task.Title = op.Title
+		task.Description = op.Description
+		task.DueDate = op.DueDate
+		if op.Status != "" {
+			task.Status = op.Status
+		}
+	}
+
+	tasks[op.ID] = &task
+	return repository.BatchOperation{
+		Action: repository.BatchUpdate,
+		Task:   &task,
+		Event:  newTaskEvent(userID, models.TaskEventUpdated, current, &task),
+	}, nil
+}
+
+// Marque comme annulées les opérations d'un lot atomique qui n'ont pas échoué elles-mêmes
+func cancelBulkResults(results []BulkResult) {
+	for i := range results {
+		results[i].Task = nil
+		if results[i].Error == "" {
+			results[i].OK = false
+			results[i].Error = "Opération annulée."
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/models"
+)
+
+// ErrHistoryNotFound est retournée quand une tâche n'a aucun historique visible par l'utilisateur
+var ErrHistoryNotFound = errors.New("historique introuvable")
+
+// FieldChange est l'ancienne et la nouvelle valeur d'un champ modifié
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// TaskHistoryEntry est un évènement de l'historique avec son diff décodé
+type TaskHistoryEntry struct {
+	ID        uint                   `json:"id"`
+	TaskID    uint                   `json:"task_id"`
+	ActorID   uint                   `json:"actor_id"`
+	Action    string                 `json:"action"`
+	Changes   map[string]FieldChange `json:"changes"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Retourne une page de l'historique d'une tâche appartenant à l'utilisateur.
+// L'historique reste consultable après la suppression de la tâche.
+func (s *taskService) GetTaskHistory(ctx context.Context, userID, taskID uint, page, pageSize int) ([]TaskHistoryEntry, int64, error) {
+	events, total, err := s.repo.GetTaskEvents(ctx, taskID, userID, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return nil, 0, ErrHistoryNotFound
+	}
+
+	entries := make([]TaskHistoryEntry, len(events))
+	for i, ev := range events {
+		entries[i] = TaskHistoryEntry{
+			ID:        ev.ID,
+			TaskID:    ev.TaskID,
+			ActorID:   ev.ActorID,
+			Action:    ev.Action,
+			Changes:   map[string]FieldChange{},
+			CreatedAt: ev.CreatedAt,
+		}
+		if ev.Changes != "" {
+			if err := json.Unmarshal([]byte(ev.Changes), &entries[i].Changes); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	return entries, total, nil
+}
+
+// newTaskEvent construit l'évènement d'historique d'une action sur une tâche.
+// before vaut nil pour une création, after vaut nil pour une suppression.
+// Retourne nil si une mise à jour ne modifie aucun champ.
+func newTaskEvent(actorID uint, action string, before, after *models.Task) *models.TaskEvent {
+	changes := diffTasks(before, after)
+	if action == models.TaskEventUpdated && len(changes) == 0 {
+		return nil
+	}
+
+	data, _ := json.Marshal(changes)
+	owner := after
+	if owner == nil {
+		owner = before
+	}
+	return &models.TaskEvent{
+		TaskID:  owner.ID,
+		UserID:  owner.UserID,
+		ActorID: actorID,
+		Action:  action,
+		Changes: string(data),
+	}
+}
+
+// diffTasks compare les champs modifiables de deux versions d'une tâche
+func diffTasks(before, after *models.Task) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+
+	field := func(task *models.Task, get func(t *models.Task) interface{}) interface{} {
+		if task == nil {
+			return nil
+		}
+		return get(task)
+	}
+	compare := func(name string, get func(t *models.Task) interface{}) {
+		o, n := field(before, get), field(after, get)
+		if o != n {
+			changes[name] = FieldChange{Old: o, New: n}
+		}
+	}
+
+	compare("title", func(t *models.Task) interface{} { return t.Title })
+	compare("description", func(t *models.Task) interface{} { return t.Description })
+	compare("status", func(t *models.Task) interface{} { return t.Status })
+	compare("due_date", func(t *models.Task) interface{} {
+		if t.DueDate == nil {
+			return nil
+		}
+		return t.DueDate.UTC().Format(time.RFC3339)
+	})
+
+	return changes
+}
@@ -1,51 +1,89 @@
 package services
 
 import (
+	"context"
+
 	"YoannLetacq/todo-api.git/internal/models"
 	"YoannLetacq/todo-api.git/internal/repository"
 )
 
 type TaskService interface {
-	CreateTask(task *models.Task) error
-	GetTasksByUser(userID uint) ([]models.Task, error)
-	GetTaskByID(taskID uint) (*models.Task, error)
-	UpdateTask(task *models.Task) error
-	DeleteTask(task *models.Task) error
+	CreateTask(ctx context.Context, actorID uint, task *models.Task) error
+	GetTasksByUser(ctx context.Context, userID uint) ([]models.Task, error)
+	GetTaskByID(ctx context.Context, taskID uint) (*models.Task, error)
+	UpdateTask(ctx context.Context, actorID uint, task *models.Task) error
+	DeleteTask(ctx context.Context, actorID uint, task *models.Task) error
+	BulkTasks(ctx context.Context, userID uint, ops []BulkOperation, atomic bool) ([]BulkResult, error)
+	ExportTasks(ctx context.Context, userID uint, fn func(task *models.Task) error) error
+	ImportTasks(ctx context.Context, userID uint, tasks []models.Task) error
+	SearchTasks(ctx context.Context, userID uint, query string, limit int) ([]repository.SearchResult, error)
+	GetTaskHistory(ctx context.Context, userID, taskID uint, page, pageSize int) ([]TaskHistoryEntry, int64, error)
 }
 
 // retourne une instance de TaskService
 type taskService struct {
-	repo repository.TaskRepository
+	repo     repository.TaskRepository
+	searcher repository.Searcher
 }
 
 // Retourne une instance de TaskService
-func NewTaskService(repo repository.TaskRepository) TaskService {
+func NewTaskService(repo repository.TaskRepository, searcher repository.Searcher) TaskService {
 	return &taskService{
-		repo: repo,
+		repo:     repo,
+		searcher: searcher,
 	}
 }
 
 // Créer une nouvelle tâche
-func (s *taskService) CreateTask(task *models.Task) error {
-	return s.repo.CreateTask(task)
+func (s *taskService) CreateTask(ctx context.Context, actorID uint, task *models.Task) error {
+	return s.repo.CreateTask(ctx, task, newTaskEvent(actorID, models.TaskEventCreated, nil, task))
 }
 
 // Retourne toutes les tâches d'un utilisateur
-func (s *taskService) GetTasksByUser(userID uint) ([]models.Task, error) {
-	return s.repo.GetTasksByUser(userID)
+func (s *taskService) GetTasksByUser(ctx context.Context, userID uint) ([]models.Task, error) {
+	return s.repo.GetTasksByUser(ctx, userID)
 }
 
 // Retourne une tâche par son ID
-func (s *taskService) GetTaskByID(taskID uint) (*models.Task, error) {
-	return s.repo.GetTaskByID(taskID)
+func (s *taskService) GetTaskByID(ctx context.Context, taskID uint) (*models.Task, error) {
+	return s.repo.GetTaskByID(ctx, taskID)
 }
 
-// Met à jour une tâche
-func (s *taskService) UpdateTask(task *models.Task) error {
-	return s.repo.UpdateTask(task)
+// Met à jour une tâche, le diff est calculé par rapport à la version en base
+func (s *taskService) UpdateTask(ctx context.Context, actorID uint, task *models.Task) error {
+	previous, err := s.repo.GetTaskByID(ctx, task.ID)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdateTask(ctx, task, newTaskEvent(actorID, models.TaskEventUpdated, previous, task))
 }
 
 // Supprime une tâche
-func (s *taskService) DeleteTask(task *models.Task) error {
-	return s.repo.DeleteTask(task)
+func (s *taskService) DeleteTask(ctx context.Context, actorID uint, task *models.Task) error {
+	return s.repo.DeleteTask(ctx, task, newTaskEvent(actorID, models.TaskEventDeleted, task, nil))
+}
+
+// Parcourt toutes les tâches d'un utilisateur pour les exporter
+func (s *taskService) ExportTasks(ctx context.Context, userID uint, fn func(task *models.Task) error) error {
+	return s.repo.StreamTasksByUser(ctx, userID, fn)
+}
+
+// Importe des tâches déjà validées pour un utilisateur, en une seule transaction
+func (s *taskService) ImportTasks(ctx context.Context, userID uint, tasks []models.Task) error {
+	ops := make([]repository.BatchOperation, len(tasks))
+	for i := range tasks {
+		tasks[i].UserID = userID
+		ops[i] = repository.BatchOperation{
+			Action: repository.BatchCreate,
+			Task:   &tasks[i],
+			Event:  newTaskEvent(userID, models.TaskEventCreated, nil, &tasks[i]),
+		}
+	}
+	_, err := s.repo.ApplyBatch(ctx, ops, true)
+	return err
+}
+
+// Recherche les tâches d'un utilisateur en texte intégral, les plus pertinentes d'abord
+func (s *taskService) SearchTasks(ctx context.Context, userID uint, query string, limit int) ([]repository.SearchResult, error) {
+	return s.searcher.Search(ctx, userID, query, limit)
 }
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"context"
+
 	"YoannLetacq/todo-api.git/internal/models"
 	"YoannLetacq/todo-api.git/internal/repository"
 
@@ -8,8 +10,8 @@ import (
 )
 
 type UserService interface {
-	RegisterUser(user *models.User) error
-	LoginUser(email, password string) (*models.User, error)
+	RegisterUser(ctx context.Context, user *models.User) error
+	LoginUser(ctx context.Context, email, password string) (*models.User, error)
 }
 
 type userService struct {
@@ -24,13 +26,13 @@ func NewUserService(repo repository.UserRepository) UserService {
 }
 
 // RegisterUser permet d'enregistrer un utilisateur
-func (s *userService) RegisterUser(user *models.User) error {
-	return s.repo.CreateUser(user)
+func (s *userService) RegisterUser(ctx context.Context, user *models.User) error {
+	return s.repo.CreateUser(ctx, user)
 }
 
 // LoginUser permet de connecter un utilisateur
-func (s *userService) LoginUser(email, password string) (*models.User, error) {
-	user, err := s.repo.GetUserByEmail(email)
+func (s *userService) LoginUser(ctx context.Context, email, password string) (*models.User, error) {
+	user, err := s.repo.GetUserByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"YoannLetacq/todo-api.git/config"
+)
+
+// ErrBlobNotFound est retournée quand aucun blob n'existe pour la clé
+var ErrBlobNotFound = errors.New("blob introuvable")
+
+// ErrInvalidKey est retournée pour une clé vide ou qui sort de l'espace de stockage
+var ErrInvalidKey = errors.New("clé de blob invalide")
+
+// BlobStore stocke des fichiers binaires identifiés par une clé de la forme "a/b/c".
+// Delete ne retourne pas d'erreur si le blob n'existe pas.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewBlobStore retourne le BlobStore configuré : "local" dans cfg.Dir,
+// ou "s3" dans le bucket décrit par cfg.S3.
+func NewBlobStore(cfg config.StorageConfig) (BlobStore, error) {
+	switch cfg.Backend {
+	case "local":
+		return NewLocalStore(cfg.Dir)
+	case "s3":
+		return NewS3Store(S3Config{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKeyID,
+			SecretKey: cfg.S3.SecretAccessKey,
+		})
+	default:
+		return nil, fmt.Errorf("BLOB_STORE inconnu: %q", cfg.Backend)
+	}
+}
+
+// checkKey refuse les clés vides, absolues ou contenant des segments "." et ".."
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return ErrInvalidKey
+	}
+	return nil
+}
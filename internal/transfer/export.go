@@ -0,0 +1,222 @@
+package transfer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/utils"
+)
+
+// Formats d'import et d'export supportés
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+	FormatICS  = "ics"
+)
+
+// ProdID identifie l'API dans les fichiers iCalendar générés
+const ProdID = "-//todo-api//Tasks//FR"
+
+// ErrUnknownFormat est retournée pour un format non supporté
+var ErrUnknownFormat = errors.New("format inconnu, attendu csv, json ou ics")
+
+// Colonnes du CSV exporté, title, description et status sont relues à l'import
+var csvColumns = []string{"id", "title", "description", "status", "due_date", "created_at", "updated_at"}
+
+// record est la forme d'une tâche exportée en JSON
+type record struct {
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Encoder écrit les tâches une par une pour permettre le streaming
+type Encoder interface {
+	Encode(task *models.Task) error
+	Close() error
+}
+
+// IsSupported indique si un format est supporté à l'import et à l'export
+func IsSupported(format string) bool {
+	return format == FormatCSV || format == FormatJSON || format == FormatICS
+}
+
+// ContentType retourne le type MIME d'un format
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatICS:
+		return "text/calendar; charset=utf-8"
+	default:
+		return "application/json; charset=utf-8"
+	}
+}
+
+// NewEncoder retourne l'encodeur du format demandé, écrivant sur w
+func NewEncoder(format string, w io.Writer) (Encoder, error) {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvColumns); err != nil {
+			return nil, err
+		}
+		return &csvEncoder{w: cw}, nil
+	case FormatJSON:
+		if _, err := io.WriteString(w, "["); err != nil {
+			return nil, err
+		}
+		return &jsonEncoder{w: w}, nil
+	case FormatICS:
+		iw, err := utils.NewICalWriter(w, ProdID)
+		if err != nil {
+			return nil, err
+		}
+		return &icsEncoder{w: iw}, nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvEncoder) Encode(task *models.Task) error {
+	dueDate := ""
+	if task.DueDate != nil {
+		dueDate = task.DueDate.UTC().Format(time.RFC3339)
+	}
+	err := e.w.Write([]string{
+		strconv.FormatUint(uint64(task.ID), 10),
+		task.Title,
+		task.Description,
+		task.Status,
+		dueDate,
+		task.CreatedAt.UTC().Format(time.RFC3339),
+		task.UpdatedAt.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonEncoder) Encode(task *models.Task) error {
+	data, err := json.Marshal(record{
+		ID:          task.ID,
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      task.Status,
+		DueDate:     task.DueDate,
+		CreatedAt:   task.CreatedAt,
+		UpdatedAt:   task.UpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.count++
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	_, err := io.WriteString(e.w, "]")
+	return err
+}
+
+type icsEncoder struct {
+	w *utils.ICalWriter
+}
+
+func (e *icsEncoder) Encode(task *models.Task) error {
+	return e.w.WriteComponent(TaskToVTodo(task))
+}
+
+func (e *icsEncoder) Close() error {
+	return e.w.Close()
+}
+
+// TaskToVTodo convertit une tâche en composant VTODO
+func TaskToVTodo(task *models.Task) utils.ICalComponent {
+	todo := utils.ICalComponent{Name: "VTODO"}
+	todo.Add("UID", TaskUID(task))
+	todo.Add("DTSTAMP", utils.ICalTime(task.UpdatedAt))
+	todo.Add("CREATED", utils.ICalTime(task.CreatedAt))
+	todo.Add("LAST-MODIFIED", utils.ICalTime(task.UpdatedAt))
+	todo.Add("SUMMARY", task.Title)
+	todo.Add("DESCRIPTION", task.Description)
+	todo.Add("STATUS", icalStatus(task.Status))
+	if task.DueDate != nil {
+		todo.Add("DUE", utils.ICalTime(*task.DueDate))
+	}
+	return todo
+}
+
+// TaskToVEvent convertit une tâche avec échéance en évènement VEVENT.
+// Une échéance à minuit UTC devient un évènement sur la journée entière.
+func TaskToVEvent(task *models.Task) utils.ICalComponent {
+	event := utils.ICalComponent{Name: "VEVENT"}
+	event.Add("UID", TaskUID(task))
+	event.Add("DTSTAMP", utils.ICalTime(task.UpdatedAt))
+	event.Add("CREATED", utils.ICalTime(task.CreatedAt))
+	event.Add("LAST-MODIFIED", utils.ICalTime(task.UpdatedAt))
+
+	due := task.DueDate.UTC()
+	if due.Hour() == 0 && due.Minute() == 0 && due.Second() == 0 {
+		event.Properties = append(event.Properties,
+			utils.ICalProperty{Name: "DTSTART", Params: map[string]string{"VALUE": "DATE"}, Value: due.Format("20060102")},
+			utils.ICalProperty{Name: "DTEND", Params: map[string]string{"VALUE": "DATE"}, Value: due.AddDate(0, 0, 1).Format("20060102")},
+		)
+	} else {
+		event.Add("DTSTART", utils.ICalTime(due))
+		event.Add("DTEND", utils.ICalTime(due))
+	}
+
+	event.Add("SUMMARY", task.Title)
+	event.Add("DESCRIPTION", task.Description)
+	event.Add("TRANSP", "TRANSPARENT")
+	return event
+}
+
+// TaskUID retourne l'identifiant iCalendar stable d'une tâche
+func TaskUID(task *models.Task) string {
+	return fmt.Sprintf("task-%d@todo-api", task.ID)
+}
+
+func icalStatus(status string) string {
+	switch status {
+	case models.StatusInProgress:
+		return "IN-PROCESS"
+	case models.StatusDone:
+		return "COMPLETED"
+	default:
+		return "NEEDS-ACTION"
+	}
+}
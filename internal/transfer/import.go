@@ -0,0 +1,277 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/utils"
+)
+
+// Longueur maximum d'un titre importé
+const maxTitleLength = 255
+
+// Champs de tâche qu'un mapping CSV peut cibler
+var mappableFields = []string{"title", "description", "status", "due_date"}
+
+// Row est une ligne lue à l'import. Row vaut la ligne du CSV,
+// la position dans le tableau JSON ou le rang du VTODO, à partir de 1.
+type Row struct {
+	Row   int         `json:"row"`
+	Task  models.Task `json:"-"`
+	Error string      `json:"error,omitempty"`
+}
+
+// ParseMapping lit un mapping CSV de la forme "title=Nom,description=Notes"
+// qui associe un champ de tâche au nom d'une colonne du fichier.
+func ParseMapping(s string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, field := range mappableFields {
+		mapping[field] = field
+	}
+	if strings.TrimSpace(s) == "" {
+		return mapping, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		field = strings.ToLower(strings.TrimSpace(field))
+		column = strings.TrimSpace(column)
+		if !ok || column == "" {
+			return nil, fmt.Errorf("mapping invalide: %q", pair)
+		}
+		if _, known := mapping[field]; !known {
+			return nil, fmt.Errorf("champ inconnu dans le mapping: %q", field)
+		}
+		mapping[field] = column
+	}
+	return mapping, nil
+}
+
+// Decode lit un fichier d'import et retourne chaque ligne validée.
+// Une erreur n'est retournée que si le fichier est illisible dans son ensemble,
+// les erreurs de validation sont portées par chaque Row.
+func Decode(format string, r io.Reader, mapping map[string]string) ([]Row, error) {
+	var rows []Row
+	var err error
+
+	switch format {
+	case FormatCSV:
+		rows, err = decodeCSV(r, mapping)
+	case FormatJSON:
+		rows, err = decodeJSON(r)
+	case FormatICS:
+		rows, err = decodeICS(r)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rows {
+		if rows[i].Error == "" {
+			rows[i].Error = validate(&rows[i].Task)
+		}
+	}
+	return rows, nil
+}
+
+func decodeCSV(r io.Reader, mapping map[string]string) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("en-tête CSV illisible: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for field, name := range mapping {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")), name) {
+				columns[field] = i
+				break
+			}
+		}
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("colonne %q introuvable dans l'en-tête CSV", mapping["title"])
+	}
+
+	get := func(record []string, field string) string {
+		i, ok := columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rows = append(rows, Row{Row: parseErr.StartLine, Error: "Ligne CSV mal formée."})
+				continue
+			}
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		row := Row{
+			Row: line,
+			Task: models.Task{
+				Title:       get(record, "title"),
+				Description: get(record, "description"),
+				Status:      get(record, "status"),
+			},
+		}
+		row.Task.DueDate, err = parseDueDate(get(record, "due_date"))
+		if err != nil {
+			row.Error = err.Error()
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func decodeJSON(r io.Reader) ([]Row, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Accepte un tableau de tâches ou la réponse de GET /tasks {"tasks": [...]}
+	var items []json.RawMessage
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var wrapper struct {
+			Tasks []json.RawMessage `json:"tasks"`
+		}
+		if err := json.Unmarshal(trimmed, &wrapper); err != nil {
+			return nil, fmt.Errorf("JSON invalide: %w", err)
+		}
+		items = wrapper.Tasks
+	} else if err := json.Unmarshal(trimmed, &items); err != nil {
+		return nil, fmt.Errorf("JSON invalide: %w", err)
+	}
+
+	rows := make([]Row, 0, len(items))
+	for i, item := range items {
+		var rec struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			Status      string `json:"status"`
+			DueDate     string `json:"due_date"`
+		}
+		if err := json.Unmarshal(item, &rec); err != nil {
+			rows = append(rows, Row{Row: i + 1, Error: "Objet JSON invalide."})
+			continue
+		}
+		row := Row{
+			Row: i + 1,
+			Task: models.Task{
+				Title:       strings.TrimSpace(rec.Title),
+				Description: rec.Description,
+				Status:      strings.TrimSpace(rec.Status),
+			},
+		}
+		row.Task.DueDate, err = parseDueDate(strings.TrimSpace(rec.DueDate))
+		if err != nil {
+			row.Error = err.Error()
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func decodeICS(r io.Reader) ([]Row, error) {
+	components, err := utils.ParseICal(r)
+	if err != nil {
+		return nil, fmt.Errorf("iCalendar invalide: %w", err)
+	}
+
+	var rows []Row
+	for _, c := range components {
+		if c.Name != "VTODO" {
+			continue
+		}
+		row := Row{
+			Row: len(rows) + 1,
+			Task: models.Task{
+				Title:       strings.TrimSpace(c.Get("SUMMARY")),
+				Description: c.Get("DESCRIPTION"),
+			},
+		}
+		status, err := statusFromICal(c.Get("STATUS"))
+		if err != nil {
+			row.Error = err.Error()
+		}
+		row.Task.Status = status
+		if due := c.Get("DUE"); due != "" {
+			t, err := utils.ParseICalTime(due)
+			if err != nil {
+				row.Error = "Date d'échéance invalide."
+			} else {
+				row.Task.DueDate = &t
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func statusFromICal(status string) (string, error) {
+	switch strings.ToUpper(strings.TrimSpace(status)) {
+	case "", "NEEDS-ACTION":
+		return models.StatusTodo, nil
+	case "IN-PROCESS":
+		return models.StatusInProgress, nil
+	case "COMPLETED":
+		return models.StatusDone, nil
+	default:
+		return "", fmt.Errorf("Status iCalendar non supporté: %s.", status)
+	}
+}
+
+// parseDueDate lit une date d'échéance RFC 3339 ou au format AAAA-MM-JJ, vide si absente
+func parseDueDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("Date d'échéance invalide: %s.", value)
+}
+
+// validate complète et vérifie une tâche importée, retourne le message d'erreur éventuel
+func validate(task *models.Task) string {
+	if task.Title == "" {
+		return "Titre manquant."
+	}
+	if len(task.Title) > maxTitleLength {
+		return fmt.Sprintf("Titre trop long (%d caractères maximum).", maxTitleLength)
+	}
+	if task.Status == "" {
+		task.Status = models.StatusTodo
+	}
+	switch task.Status {
+	case models.StatusTodo, models.StatusInProgress, models.StatusDone:
+		return ""
+	default:
+		return fmt.Sprintf("Status invalide: %s.", task.Status)
+	}
+}
@@ -0,0 +1,238 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Longueur maximum d'une ligne iCalendar avant repli (RFC 5545 §3.1)
+const icalLineLimit = 75
+
+// ICalProperty est une propriété d'un composant iCalendar, ex: SUMMARY ou DTSTART;VALUE=DATE
+type ICalProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// ICalComponent est un composant iCalendar (VTODO, VEVENT...) et ses propriétés dans l'ordre
+type ICalComponent struct {
+	Name       string
+	Properties []ICalProperty
+}
+
+// Add ajoute une propriété au composant, les valeurs vides sont ignorées
+func (c *ICalComponent) Add(name, value string) {
+	if value == "" {
+		return
+	}
+	c.Properties = append(c.Properties, ICalProperty{Name: name, Value: value})
+}
+
+// Get retourne la valeur de la première propriété portant ce nom
+func (c ICalComponent) Get(name string) string {
+	for _, p := range c.Properties {
+		if strings.EqualFold(p.Name, name) {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// ICalTime formate une date au format UTC iCalendar
+func ICalTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// ParseICalTime lit une date iCalendar UTC, locale ou au format date seule (VALUE=DATE).
+// Les dates locales sans fuseau sont interprétées en UTC.
+func ParseICalTime(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("date iCalendar invalide: %q", value)
+}
+
+// ICalWriter écrit un calendrier iCalendar composant par composant
+type ICalWriter struct {
+	w *bufio.Writer
+}
+
+// NewICalWriter ouvre un VCALENDAR sur w
+func NewICalWriter(w io.Writer, prodID string) (*ICalWriter, error) {
+	iw := &ICalWriter{w: bufio.NewWriter(w)}
+	iw.writeLine("BEGIN:VCALENDAR")
+	iw.writeLine("VERSION:2.0")
+	iw.writeLine("PRODID:" + prodID)
+	iw.writeLine("CALSCALE:GREGORIAN")
+	return iw, iw.w.Flush()
+}
+
+// WriteComponent écrit un composant complet
+func (iw *ICalWriter) WriteComponent(c ICalComponent) error {
+	iw.writeLine("BEGIN:" + c.Name)
+	for _, p := range c.Properties {
+		name := p.Name
+		keys := make([]string, 0, len(p.Params))
+		for k := range p.Params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			name += ";" + k + "=" + p.Params[k]
+		}
+		iw.writeLine(name + ":" + escapeICalText(p.Value))
+	}
+	iw.writeLine("END:" + c.Name)
+	return iw.w.Flush()
+}
+
+// Close termine le VCALENDAR
+func (iw *ICalWriter) Close() error {
+	iw.writeLine("END:VCALENDAR")
+	return iw.w.Flush()
+}
+
+// writeLine écrit une ligne terminée par CRLF en la repliant tous les 75 octets
+func (iw *ICalWriter) writeLine(line string) {
+	limit := icalLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		iw.w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// Les lignes de continuation commencent par une espace
+		limit = icalLineLimit - 1
+	}
+	iw.w.WriteString(line + "\r\n")
+}
+
+// ParseICal lit un calendrier et retourne ses composants de premier niveau (VTODO, VEVENT...).
+// Les sous-composants comme VALARM sont ignorés.
+func ParseICal(r io.Reader) ([]ICalComponent, error) {
+	lines, err := unfoldICalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var components []ICalComponent
+	var current *ICalComponent
+	depth := 0
+	inCalendar := false
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		prop, err := parseICalProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("ligne %d: %w", i+1, err)
+		}
+
+		switch strings.ToUpper(prop.Name) {
+		case "BEGIN":
+			value := strings.ToUpper(prop.Value)
+			if value == "VCALENDAR" && !inCalendar {
+				inCalendar = true
+				continue
+			}
+			if !inCalendar {
+				return nil, errors.New("BEGIN:VCALENDAR manquant")
+			}
+			depth++
+			if depth == 1 {
+				current = &ICalComponent{Name: value}
+			}
+		case "END":
+			if depth == 0 {
+				if strings.ToUpper(prop.Value) == "VCALENDAR" {
+					return components, nil
+				}
+				return nil, fmt.Errorf("ligne %d: END:%s inattendu", i+1, prop.Value)
+			}
+			if depth == 1 {
+				components = append(components, *current)
+				current = nil
+			}
+			depth--
+		default:
+			if depth == 1 {
+				current.Properties = append(current.Properties, prop)
+			}
+		}
+	}
+
+	if !inCalendar {
+		return nil, errors.New("BEGIN:VCALENDAR manquant")
+	}
+	return nil, errors.New("END:VCALENDAR manquant")
+}
+
+// unfoldICalLines lit les lignes et recolle celles repliées par une espace ou une tabulation
+func unfoldICalLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseICalProperty découpe NAME;PARAM=VALUE:VALEUR
+func parseICalProperty(line string) (ICalProperty, error) {
+	colon := -1
+	inQuotes := false
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		}
+		if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon <= 0 {
+		return ICalProperty{}, errors.New("propriété iCalendar mal formée")
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop := ICalProperty{Name: strings.ToUpper(parts[0]), Value: unescapeICalText(line[colon+1:])}
+	for _, param := range parts[1:] {
+		if k, v, ok := strings.Cut(param, "="); ok {
+			if prop.Params == nil {
+				prop.Params = make(map[string]string)
+			}
+			prop.Params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return prop, nil
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeICalText(s string) string {
+	return icalEscaper.Replace(s)
+}
+
+var icalUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+func unescapeICalText(s string) string {
+	return icalUnescaper.Replace(s)
+}
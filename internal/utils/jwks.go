@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+)
+
+// JWK est une clé publique au format JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 (OKP, RFC 8037)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet est le document publié sur /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// ParseJWTKeyPEM lit une clé RSA ou Ed25519 au format PEM, l'algorithme est déduit du type de clé.
+// Une clé privée (PKCS#1 ou PKCS#8) permet de signer, une clé publique (PKIX ou PKCS#1) seulement de vérifier.
+func ParseJWTKeyPEM(id string, data []byte) (JWTKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return JWTKey{}, fmt.Errorf("clé JWT %q: aucun bloc PEM trouvé", id)
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return JWTKey{}, fmt.Errorf("clé JWT %q: bloc PEM %q non supporté", id, block.Type)
+	}
+	if err != nil {
+		return JWTKey{}, fmt.Errorf("clé JWT %q: %w", id, err)
+	}
+
+	key := JWTKey{ID: id}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm, key.PrivateKey, key.PublicKey = AlgRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Algorithm, key.PublicKey = AlgRS256, k
+	case ed25519.PrivateKey:
+		key.Algorithm, key.PrivateKey, key.PublicKey = AlgEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Algorithm, key.PublicKey = AlgEdDSA, k
+	default:
+		return JWTKey{}, fmt.Errorf("clé JWT %q: seules les clés RSA et Ed25519 sont supportées", id)
+	}
+	return key, key.validate()
+}
+
+// JWKS retourne les clés publiques de vérification, clé active en tête.
+// Les secrets HS256 ne sont jamais publiés.
+func (k *JWTKeys) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, id := range k.order {
+		if jwk, ok := k.byID[id].jwk(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// jwk convertit la clé publique, ok vaut false pour une clé HS256
+func (k JWTKey) jwk() (JWK, bool) {
+	encode := base64.RawURLEncoding.EncodeToString
+	switch pub := k.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{Kty: "RSA", Kid: k.ID, Use: "sig", Alg: AlgRS256,
+			N: encode(pub.N.Bytes()), E: encode(big.NewInt(int64(pub.E)).Bytes())}, true
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Kid: k.ID, Use: "sig", Alg: AlgEdDSA, Crv: "Ed25519", X: encode(pub)}, true
+	}
+	return JWK{}, false
+}
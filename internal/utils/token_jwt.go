@@ -1,56 +1,215 @@
 package utils
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"errors"
-	"log"
+	"fmt"
+	"strings"
 	"time"
 
-	"YoannLetacq/todo-api.git/config"
-
 	"github.com/golang-jwt/jwt"
 )
 
-func GenerateJWT(userID, email string) (string, error) {
-	secretKey := config.GetEnv("JWT_SECRET", "my_secret_key")
-	log.Println("🔑 Clé utilisée pour SIGNER :", secretKey)
+// Durée de validité des tokens de connexion
+const TokenTTL = 24 * time.Hour
+
+// Algorithmes de signature supportés
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Taille minimale d'une clé RSA acceptée
+const minRSABits = 2048
+
+// JWTKey est une clé de signature identifiée par son kid.
+// HS256 utilise Secret, RS256 et EdDSA utilisent la paire PrivateKey/PublicKey ;
+// une clé asymétrique sans PrivateKey ne sert qu'à vérifier.
+type JWTKey struct {
+	ID string
+	// Algorithm vaut HS256 si vide
+	Algorithm  string
+	Secret     []byte
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+func (k JWTKey) algorithm() string {
+	if k.Algorithm == "" {
+		return AlgHS256
+	}
+	return k.Algorithm
+}
+
+func (k JWTKey) method() jwt.SigningMethod {
+	switch k.algorithm() {
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+// canSign indique si la clé peut signer, et pas seulement vérifier
+func (k JWTKey) canSign() bool {
+	if k.algorithm() == AlgHS256 {
+		return len(k.Secret) > 0
+	}
+	return k.PrivateKey != nil
+}
+
+// signingKey retourne la clé attendue par jwt pour signer
+func (k JWTKey) signingKey() interface{} {
+	if k.algorithm() == AlgHS256 {
+		return k.Secret
+	}
+	return k.PrivateKey
+}
 
-	if secretKey == "" {
-		return "", errors.New("clé JWT manquante")
+// verificationKey retourne la clé attendue par jwt pour vérifier
+func (k JWTKey) verificationKey() interface{} {
+	if k.algorithm() == AlgHS256 {
+		return k.Secret
 	}
+	return k.PublicKey
+}
+
+// validate vérifie que le type des clés correspond à l'algorithme
+func (k JWTKey) validate() error {
+	if k.ID == "" {
+		return errors.New("clé JWT sans identifiant")
+	}
+	switch k.algorithm() {
+	case AlgHS256:
+		if len(k.Secret) == 0 {
+			return fmt.Errorf("clé JWT %q: secret manquant", k.ID)
+		}
+	case AlgRS256:
+		pub, ok := k.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("clé JWT %q: clé publique RSA attendue", k.ID)
+		}
+		if pub.N.BitLen() < minRSABits {
+			return fmt.Errorf("clé JWT %q: clé RSA de %d bits, %d minimum", k.ID, pub.N.BitLen(), minRSABits)
+		}
+		if _, ok := k.PrivateKey.(*rsa.PrivateKey); k.PrivateKey != nil && !ok {
+			return fmt.Errorf("clé JWT %q: clé privée RSA attendue", k.ID)
+		}
+	case AlgEdDSA:
+		if _, ok := k.PublicKey.(ed25519.PublicKey); !ok {
+			return fmt.Errorf("clé JWT %q: clé publique Ed25519 attendue", k.ID)
+		}
+		if _, ok := k.PrivateKey.(ed25519.PrivateKey); k.PrivateKey != nil && !ok {
+			return fmt.Errorf("clé JWT %q: clé privée Ed25519 attendue", k.ID)
+		}
+	default:
+		return fmt.Errorf("clé JWT %q: algorithme %q non supporté", k.ID, k.Algorithm)
+	}
+	return nil
+}
 
+// JWTKeys signe les tokens avec la clé active et les vérifie avec toutes les clés connues.
+// Le kid de la clé est écrit dans l'en-tête du token, ce qui permet une rotation :
+// la nouvelle clé devient active et l'ancienne reste acceptée en vérification
+// jusqu'à l'expiration des tokens qu'elle a signés.
+type JWTKeys struct {
+	active JWTKey
+	byID   map[string]JWTKey
+	// ordre de déclaration des clés, pour un JWKS stable
+	order []string
+}
+
+// NewJWTKeys crée le jeu de clés à partir de la clé active et des anciennes clés encore acceptées
+func NewJWTKeys(active JWTKey, previous ...JWTKey) (*JWTKeys, error) {
+	if err := active.validate(); err != nil {
+		return nil, err
+	}
+	if !active.canSign() {
+		return nil, fmt.Errorf("clé JWT %q: la clé active doit pouvoir signer", active.ID)
+	}
+
+	keys := &JWTKeys{active: active, byID: map[string]JWTKey{active.ID: active}, order: []string{active.ID}}
+	for _, key := range previous {
+		if err := key.validate(); err != nil {
+			return nil, err
+		}
+		if _, exists := keys.byID[key.ID]; exists {
+			return nil, errors.New("identifiant de clé JWT en double: " + key.ID)
+		}
+		keys.byID[key.ID] = key
+		keys.order = append(keys.order, key.ID)
+	}
+	return keys, nil
+}
+
+// Generate signe un token de connexion avec la clé active, sans claim scope ni session.
+// Un tel token ne peut pas être révoqué, la connexion utilise GenerateForSession.
+func (k *JWTKeys) Generate(userID, email string) (string, error) {
+	return k.GenerateWithScopes(userID, email, nil)
+}
+
+// GenerateWithScopes signe un token de connexion portant les scopes donnés
+// dans le claim scope, séparés par des espaces (RFC 8693)
+func (k *JWTKeys) GenerateWithScopes(userID, email string, scopes []string) (string, error) {
+	return k.GenerateForSession(userID, email, scopes, "")
+}
+
+// GenerateForSession signe un token de connexion lié à une session par le claim sid.
+// Le token cesse d'être accepté dès que la session est révoquée.
+func (k *JWTKeys) GenerateForSession(userID, email string, scopes []string, sessionID string) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"user_id": userID,
 		"email":   email,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
-		"iat":     time.Now().Unix(),
-		"nbf":     time.Now().Unix(),
+		"exp":     now.Add(TokenTTL).Unix(),
+		"iat":     now.Unix(),
+		"nbf":     now.Unix(),
+	}
+	if len(scopes) > 0 {
+		claims["scope"] = strings.Join(scopes, " ")
+	}
+	if sessionID != "" {
+		claims["sid"] = sessionID
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(secretKey))
-
+	token := jwt.NewWithClaims(k.active.method(), claims)
+	token.Header["kid"] = k.active.ID
+	tokenString, err := token.SignedString(k.active.signingKey())
 	if err != nil {
 		return "", errors.New("échec de la signature du token JWT")
 	}
-
-	log.Println("✅ Token généré :", tokenString)
 	return tokenString, nil
 }
 
-func ParseToken(tokenString string) (*jwt.Token, map[string]string, error) {
-	secretKey := config.GetEnv("JWT_SECRET", "my_secret_key")
-	log.Println("🔑 Clé utilisée pour VERIFICATION :", secretKey)
-
-	if secretKey == "" {
-		return nil, nil, errors.New("clé JWT manquante")
-	}
-
+// Parse vérifie un token avec la clé désignée par son kid et retourne ses claims.
+// Un token sans kid, émis avant la rotation des clés, est vérifié avec la clé active.
+// Les claims scope et sid ne sont présents dans le résultat que s'ils figurent dans le token.
+func (k *JWTKeys) Parse(tokenString string) (*jwt.Token, map[string]string, error) {
 	token, err := jwt.ParseWithClaims(tokenString, jwt.MapClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte(secretKey), nil
+		key := k.active
+		if kid, present := t.Header["kid"]; present {
+			id, ok := kid.(string)
+			if !ok {
+				return nil, errors.New("kid invalide")
+			}
+			if key, ok = k.byID[id]; !ok {
+				return nil, errors.New("clé inconnue")
+			}
+		}
+		// L'algorithme est imposé par la clé et non par le token : cela exclut "none"
+		// et la vérification HS256 d'un token avec une clé publique comme secret
+		if t.Method.Alg() != key.algorithm() {
+			return nil, errors.New("algorithme de signature inattendu")
+		}
+		return key.verificationKey(), nil
 	})
 
 	if err != nil || !token.Valid {
-		log.Println("❌ Échec de la validation du token JWT :", err)
 		return nil, nil, errors.New("échec de la validation du token JWT")
 	}
 
@@ -69,6 +228,16 @@ func ParseToken(tokenString string) (*jwt.Token, map[string]string, error) {
 		return nil, nil, errors.New("email invalide ou vide dans le token")
 	}
 
-	log.Println("✅ Token valide avec claims :", claims)
-	return token, map[string]string{"user_id": userID, "email": email}, nil
+	result := map[string]string{"user_id": userID, "email": email}
+	if scope, present := claims["scope"]; present {
+		if result["scope"], ok = scope.(string); !ok {
+			return nil, nil, errors.New("scope invalide dans le token")
+		}
+	}
+	if sid, present := claims["sid"]; present {
+		if result["sid"], ok = sid.(string); !ok || result["sid"] == "" {
+			return nil, nil, errors.New("sid invalide dans le token")
+		}
+	}
+	return token, result, nil
 }
@@ -1,25 +1,111 @@
 package routes
 
 import (
+	"log"
+	"time"
+
 	"YoannLetacq/todo-api.git/internal/handlers"
+	"YoannLetacq/todo-api.git/internal/middleware"
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/ratelimit"
+	"YoannLetacq/todo-api.git/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Handlers regroupe les handlers injectés dans le routeur
+type Handlers struct {
+	Users         *handlers.UserHandler
+	OIDC          *handlers.OIDCHandler
+	MFA           *handlers.MFAHandler
+	Sessions      *handlers.SessionHandler
+	APITokens     *handlers.APITokenHandler
+	Tasks         *handlers.TaskHandler
+	Comments      *handlers.CommentHandler
+	Attachments   *handlers.AttachmentHandler
+	Notifications *handlers.NotificationHandler
+	Calendar      *handlers.CalendarHandler
+	Realtime      *handlers.RealtimeHandler
+	Idempotency   *handlers.IdempotencyHandler
+}
+
+// Config regroupe les réglages du routeur
+type Config struct {
+	// RequestTimeout est la durée maximale de traitement d'une requête, 0 pour aucune limite
+	RequestTimeout time.Duration
+	// JWTKeys vérifie les tokens des requêtes authentifiées
+	JWTKeys *utils.JWTKeys
+	// APITokens vérifie les tokens d'accès personnels, nil pour n'accepter que les JWT
+	APITokens middleware.APITokenResolver
+	// Sessions vérifie que la session d'un JWT n'est pas révoquée
+	Sessions middleware.SessionResolver
+	// RateLimitStore conserve les quotas de requêtes, nil pour ne pas limiter
+	RateLimitStore ratelimit.Store
+	// RateLimits sont les quotas des classes de routes
+	RateLimits ratelimit.Policies
+	// DisableLegacyRoutes retire les routes sans préfixe /api/v1
+	DisableLegacyRoutes bool
+	// LegacySunset est la date de retrait des routes sans préfixe, zéro si elle n'est pas fixée
+	LegacySunset time.Time
+	// TrustedProxies sont les reverse proxies dont les en-têtes X-Forwarded-For et X-Real-IP
+	// donnent l'IP du client, aucun par défaut
+	TrustedProxies []string
+}
+
+// Préfixe de la version 1 de l'API
+const V1Prefix = "/api/v1"
+
+// Date de dépréciation des routes sans préfixe de version, annoncée dans l'en-tête Deprecation
+var legacyDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// chain regroupe les middlewares partagés par toutes les versions de l'API. Chaque version
+// déclare ses routes à partir de ces middlewares : une version 2 s'ajoute avec
+// registerV2(router.Group("/api/v2"), ...) sans toucher aux routes de la v1.
+// Les handlers construisent eux-mêmes les DTO de la v1 (internal/handlers/dto.go) :
+// une v2 qui change le format des requêtes ou des réponses aura ses propres handlers.
+type chain struct {
+	timeout gin.HandlerFunc
+	auth    gin.HandlerFunc
+	// Scopes exigés par route, une requête dont le token ne les a pas reçoit 403 insufficient_scope
+	read   gin.HandlerFunc
+	write  gin.HandlerFunc
+	remove gin.HandlerFunc
+	// Quotas de requêtes : l'inscription et la connexion ont le leur, par IP,
+	// les autres routes sont limitées en lecture (GET) ou en écriture
+	authLimit gin.HandlerFunc
+	limit     gin.HandlerFunc
+}
+
 // SetupRouter ... Configure les routes
-func SetupRouter() *gin.Engine {
-	router := gin.Default()
-
-	router.POST("/register", handlers.RegisterUser)
-	router.POST("/login", handlers.LoginHandler)
-
-	taskGroup := router.Group("/tasks")
-	{
-		taskGroup.POST("", handlers.CreateTask)
-		taskGroup.GET("", handlers.GetTasks)
-		taskGroup.GET("/:id", handlers.GetTask)
-		taskGroup.PUT("/:id", handlers.UpdateTask)
-		taskGroup.DELETE("/:id", handlers.DeleteTask)
+// L'API est servie sous /api/v1. Les anciennes routes sans préfixe restent des alias
+// dépréciés de la v1, sauf si cfg.DisableLegacyRoutes est vrai.
+func SetupRouter(h Handlers, cfg Config) *gin.Engine {
+	// Équivalent de gin.Default, avec des logs qui masquent les tokens présents dans l'URL
+	router := gin.New()
+	router.Use(middleware.Logger(), gin.Recovery())
+	// Gin croit par défaut les en-têtes X-Forwarded-For de n'importe quel client : un client
+	// pourrait changer d'IP à chaque requête et échapper aux quotas de connexion
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Println("Proxies de confiance invalides, aucun n'est retenu:", err)
+		_ = router.SetTrustedProxies(nil)
+	}
+
+	m := chain{
+		timeout:   middleware.RequestTimeout(cfg.RequestTimeout),
+		auth:      middleware.Authenticate(cfg.JWTKeys, cfg.APITokens, cfg.Sessions),
+		read:      middleware.RequireScope(models.ScopeTasksRead),
+		write:     middleware.RequireScope(models.ScopeTasksWrite),
+		remove:    middleware.RequireScope(models.ScopeTasksDelete),
+		authLimit: middleware.RateLimitByIP(cfg.RateLimitStore, cfg.RateLimits.Auth),
+		limit:     middleware.RateLimitByMethod(cfg.RateLimitStore, cfg.RateLimits.Read, cfg.RateLimits.Write),
+	}
+
+	// Les clés publiques restent à leur emplacement standard (RFC 8615), hors version
+	router.GET("/.well-known/jwks.json", m.timeout, m.limit, h.Users.JWKS)
+
+	registerV1(router.Group(V1Prefix), h, m)
+	if !cfg.DisableLegacyRoutes {
+		registerV1(router.Group("", middleware.Deprecated(legacyDeprecatedAt, cfg.LegacySunset, V1Prefix)), h, m)
 	}
 
 	return router
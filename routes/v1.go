@@ -0,0 +1,88 @@
+package routes
+
+import (
+	"YoannLetacq/todo-api.git/internal/handlers"
+	"YoannLetacq/todo-api.git/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerV1 déclare les routes de la version 1 de l'API sur r
+func registerV1(r *gin.RouterGroup, h Handlers, m chain) {
+	// La websocket est une connexion longue, elle n'est pas soumise au délai des requêtes
+	r.GET("/ws", middleware.QueryToken(), m.auth, m.limit, m.read, h.Realtime.ServeWS)
+
+	public := r.Group("", m.timeout, m.auth)
+	public.POST("/register", m.authLimit, h.Users.RegisterUser)
+	public.POST("/login", m.authLimit, h.Users.LoginHandler)
+	public.POST("/login/mfa", m.authLimit, h.MFA.VerifyLogin)
+	public.GET("/auth/:provider/login", m.authLimit, h.OIDC.Login)
+	public.GET("/auth/:provider/callback", m.authLimit, h.OIDC.Callback)
+
+	api := public.Group("", m.limit)
+	// Les requêtes qui modifient les tâches et leurs ressources sont rejouables avec
+	// Idempotency-Key. Les routes du compte et du calendrier ne le sont pas : leurs réponses
+	// contiennent des secrets (token d'accès, secret TOTP, URL du flux) qui seraient
+	// conservés en clair avec la réponse.
+	resources := api.Group("", h.Idempotency.Idempotent(r.BasePath()))
+	// Spécification OpenAPI et documentation interactive, publiques
+	api.GET("/openapi.json", handlers.OpenAPISpec)
+	api.GET("/docs", handlers.DocsUI)
+
+	sessionGroup := api.Group("/me/sessions")
+	{
+		sessionGroup.GET("", h.Sessions.GetSessions)
+		sessionGroup.DELETE("", h.Sessions.DeleteOtherSessions)
+		sessionGroup.DELETE("/:id", h.Sessions.DeleteSession)
+	}
+
+	mfaGroup := api.Group("/me/mfa")
+	{
+		mfaGroup.GET("", h.MFA.GetMFAStatus)
+		mfaGroup.POST("/totp", h.MFA.EnrollTOTP)
+		mfaGroup.POST("/totp/confirm", h.MFA.ConfirmTOTP)
+		mfaGroup.POST("/disable", h.MFA.DisableTOTP)
+	}
+
+	tokenGroup := api.Group("/me/tokens")
+	{
+		tokenGroup.POST("", h.APITokens.CreateAPIToken)
+		tokenGroup.GET("", h.APITokens.GetAPITokens)
+		tokenGroup.DELETE("/:id", h.APITokens.DeleteAPIToken)
+	}
+
+	taskGroup := resources.Group("/tasks")
+	{
+		taskGroup.POST("", m.write, h.Tasks.CreateTask)
+		taskGroup.GET("", m.read, h.Tasks.GetTasks)
+		taskGroup.POST("/bulk", m.write, h.Tasks.BulkTasks)
+		taskGroup.GET("/export", m.read, h.Tasks.ExportTasks)
+		taskGroup.GET("/search", m.read, h.Tasks.SearchTasks)
+		taskGroup.POST("/import", m.write, h.Tasks.ImportTasks)
+		taskGroup.GET("/:id", m.read, h.Tasks.GetTask)
+		taskGroup.PUT("/:id", m.write, h.Tasks.UpdateTask)
+		taskGroup.DELETE("/:id", m.remove, h.Tasks.DeleteTask)
+		taskGroup.GET("/:id/history", m.read, h.Tasks.GetTaskHistory)
+		taskGroup.GET("/:id/comments", m.read, h.Comments.GetComments)
+		taskGroup.POST("/:id/comments", m.write, h.Comments.CreateComment)
+		taskGroup.PUT("/:id/comments/:comment_id", m.write, h.Comments.UpdateComment)
+		taskGroup.DELETE("/:id/comments/:comment_id", m.remove, h.Comments.DeleteComment)
+		taskGroup.GET("/:id/attachments", m.read, h.Attachments.GetAttachments)
+		taskGroup.POST("/:id/attachments", m.write, h.Attachments.UploadAttachment)
+		taskGroup.GET("/:id/attachments/:attachment_id", m.read, h.Attachments.DownloadAttachment)
+		taskGroup.DELETE("/:id/attachments/:attachment_id", m.remove, h.Attachments.DeleteAttachment)
+	}
+
+	notificationGroup := resources.Group("/notifications")
+	{
+		notificationGroup.GET("", m.read, h.Notifications.GetNotifications)
+		notificationGroup.POST("/:id/read", m.write, h.Notifications.MarkNotificationRead)
+	}
+
+	calendarGroup := api.Group("/calendar")
+	{
+		calendarGroup.POST("/token", m.write, h.Calendar.CreateCalendarFeed)
+		calendarGroup.DELETE("/token", m.write, h.Calendar.DeleteCalendarFeed)
+		calendarGroup.GET("/feed/:token", h.Calendar.GetCalendarFeed)
+	}
+}
@@ -0,0 +1,164 @@
+// tests/api_token_test.go
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// apiTokenRequest envoie une requête JSON authentifiée par le token donné
+func apiTokenRequest(router *gin.Engine, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+type createdAPIToken struct {
+	Token    string `json:"token"`
+	APIToken struct {
+		ID         uint       `json:"id"`
+		Name       string     `json:"name"`
+		Scopes     []string   `json:"scopes"`
+		ExpiresAt  time.Time  `json:"expires_at"`
+		LastUsedAt *time.Time `json:"last_used_at"`
+	} `json:"api_token"`
+}
+
+// TestAPITokenLifecycle vérifie la création, l'utilisation, le stockage haché et la révocation d'un token d'accès.
+func TestAPITokenLifecycle(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	_, jwt := createTestUserAndToken(t, a.DB)
+
+	w := apiTokenRequest(a.Router, "POST", "/me/tokens", jwt, gin.H{"name": "ci", "scopes": []string{"tasks:write", "tasks:read"}, "expires_in_days": 7})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created createdAPIToken
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, strings.HasPrefix(created.Token, services.APITokenPrefix))
+	assert.Equal(t, "ci", created.APIToken.Name)
+	assert.Equal(t, []string{"tasks:read", "tasks:write"}, created.APIToken.Scopes)
+	assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), created.APIToken.ExpiresAt, time.Minute)
+	assert.Nil(t, created.APIToken.LastUsedAt)
+
+	// Seul le hash est stocké
+	var stored models.APIToken
+	if err := a.DB.First(&stored, created.APIToken.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, stored.TokenHash)
+	assert.NotContains(t, stored.TokenHash, created.Token)
+	assert.NotContains(t, created.Token, stored.TokenHash)
+
+	// Le token authentifie l'API comme un JWT
+	w = apiTokenRequest(a.Router, "POST", "/tasks", created.Token, gin.H{"title": "Depuis la CI"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = apiTokenRequest(a.Router, "GET", "/tasks", created.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// La liste n'expose jamais la valeur du token et indique sa dernière utilisation
+	w = apiTokenRequest(a.Router, "GET", "/me/tokens", jwt, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), created.Token)
+	assert.NotContains(t, w.Body.String(), stored.TokenHash)
+	var list struct {
+		APITokens []struct {
+			ID         uint       `json:"id"`
+			LastUsedAt *time.Time `json:"last_used_at"`
+		} `json:"api_tokens"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, list.APITokens, 1) && assert.NotNil(t, list.APITokens[0].LastUsedAt) {
+		assert.WithinDuration(t, time.Now(), *list.APITokens[0].LastUsedAt, time.Minute)
+	}
+
+	// Un token d'accès ne gère pas les tokens d'accès
+	w = apiTokenRequest(a.Router, "GET", "/me/tokens", created.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = apiTokenRequest(a.Router, "DELETE", "/me/tokens/"+strconv.Itoa(int(created.APIToken.ID)), created.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Un autre utilisateur ne peut pas révoquer le token
+	other := models.User{Username: "autre", Email: "autre@example.com"}
+	if err := a.DB.Create(&other).Error; err != nil {
+		t.Fatal(err)
+	}
+	otherJWT, err := testJWTKeys.Generate(strconv.Itoa(int(other.ID)), other.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = apiTokenRequest(a.Router, "DELETE", "/me/tokens/"+strconv.Itoa(int(created.APIToken.ID)), otherJWT, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Une fois révoqué, le token est refusé
+	w = apiTokenRequest(a.Router, "DELETE", "/me/tokens/"+strconv.Itoa(int(created.APIToken.ID)), jwt, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = apiTokenRequest(a.Router, "GET", "/tasks", created.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// TestAPITokenScopesAndExpiry vérifie les scopes, l'expiration et la validation des demandes de token.
+func TestAPITokenScopesAndExpiry(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	_, jwt := createTestUserAndToken(t, a.DB)
+
+	w := apiTokenRequest(a.Router, "POST", "/me/tokens", jwt, gin.H{"name": "lecture", "scopes": []string{"tasks:read"}})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created createdAPIToken
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	assert.WithinDuration(t, time.Now().Add(services.DefaultAPITokenTTL), created.APIToken.ExpiresAt, time.Minute)
+
+	w = apiTokenRequest(a.Router, "GET", "/tasks", created.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = apiTokenRequest(a.Router, "POST", "/tasks", created.Token, gin.H{"title": "Interdit"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "insufficient_scope")
+
+	// Un token expiré est refusé
+	if err := a.DB.Model(&models.APIToken{}).Where("id = ?", created.APIToken.ID).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	w = apiTokenRequest(a.Router, "GET", "/tasks", created.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Un token inconnu est refusé
+	w = apiTokenRequest(a.Router, "GET", "/tasks", services.APITokenPrefix+"inconnu", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	for _, body := range []gin.H{
+		{"name": "", "scopes": []string{"tasks:read"}},
+		{"name": "sans scope", "scopes": []string{}},
+		{"name": "scope inconnu", "scopes": []string{"admin:all"}},
+		{"name": "trop long", "scopes": []string{"tasks:read"}, "expires_in_days": 400},
+		{"name": "négatif", "scopes": []string{"tasks:read"}, "expires_in_days": -1},
+	} {
+		w = apiTokenRequest(a.Router, "POST", "/me/tokens", jwt, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body["name"])
+	}
+}
@@ -0,0 +1,181 @@
+// tests/attachments_test.go
+package tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"YoannLetacq/todo-api.git/internal/app"
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTaskAttachments vérifie l'envoi, les limites, le téléchargement et le nettoyage des pièces jointes.
+func TestTaskAttachments(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	blobs, err := storage.NewLocalStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newTestAppWith(t, app.Options{Blobs: blobs, MaxAttachmentSize: 64})
+	router := a.Router
+
+	_, token := createTestUserAndToken(t, a.DB)
+
+	send := func(method, path string, body io.Reader, contentType string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, body)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	upload := func(path, filename string, content []byte) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		form := multipart.NewWriter(&buf)
+		part, _ := form.CreateFormFile("file", filename)
+		part.Write(content)
+		form.Close()
+		return send("POST", path, &buf, form.FormDataContentType())
+	}
+
+	w := send("POST", "/tasks", strings.NewReader(`{"title":"Avec reçu"}`), "application/json")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var createResp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &createResp)
+	taskID := strconv.Itoa(int(createResp["task"].(map[string]interface{})["id"].(float64)))
+	base := "/tasks/" + taskID + "/attachments"
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), []byte("données")...)
+	w = upload(base, "C:\\scans\\reçu 1.png", png)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var uploadResp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &uploadResp)
+	attachment := uploadResp["attachment"].(map[string]interface{})
+	sum := sha256.Sum256(png)
+	assert.Equal(t, hex.EncodeToString(sum[:]), attachment["sha256"])
+	assert.Equal(t, "image/png", attachment["content_type"])
+	assert.Equal(t, "reçu 1.png", attachment["filename"])
+	assert.NotContains(t, attachment, "StorageKey")
+	attachmentID := strconv.Itoa(int(attachment["id"].(float64)))
+
+	// Le type est détecté depuis le contenu, pas depuis l'extension
+	w = upload(base, "photo.png", []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	w = upload(base, "long.txt", bytes.Repeat([]byte("a"), 65))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	w = upload(base, "vide.txt", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = send("GET", base+"/"+attachmentID, nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, png, w.Body.Bytes())
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename*=utf-8''re%C3%A7u%201.png", w.Header().Get("Content-Disposition"))
+
+	w = send("GET", base, nil, "")
+	var listResp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &listResp)
+	assert.Len(t, listResp["attachments"], 1)
+
+	// Supprimer la tâche supprime ses pièces jointes et leurs blobs
+	w = send("DELETE", "/tasks/"+taskID, nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var count int64
+	a.DB.Model(&models.Attachment{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	files, _ := filepath.Glob(filepath.Join(dir, "tasks", taskID, "*"))
+	assert.Empty(t, files)
+}
+
+// fakeS3 est un stand-in S3 en mémoire qui vérifie la présence d'une signature SigV4
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKTEST/") ||
+		!strings.Contains(auth, "/eu-west-3/s3/aws4_request") ||
+		r.Header.Get("X-Amz-Date") == "" || r.Header.Get("X-Amz-Content-Sha256") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = data
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// TestS3BlobStore vérifie le BlobStore S3 contre un stand-in local.
+func TestS3BlobStore(t *testing.T) {
+	t.Parallel()
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store, err := storage.NewS3Store(storage.S3Config{
+		Endpoint:  server.URL,
+		Region:    "eu-west-3",
+		Bucket:    "pieces",
+		AccessKey: "AKTEST",
+		SecretKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	content := []byte("contenu du reçu")
+	err = store.Put(ctx, "tasks/1/abc", bytes.NewReader(content), int64(len(content)), "text/plain")
+	assert.NoError(t, err)
+	assert.Contains(t, fake.objects, "/pieces/tasks/1/abc")
+
+	reader, err := store.Get(ctx, "tasks/1/abc")
+	if assert.NoError(t, err) {
+		data, _ := io.ReadAll(reader)
+		reader.Close()
+		assert.Equal(t, content, data)
+	}
+
+	assert.NoError(t, store.Delete(ctx, "tasks/1/abc"))
+	_, err = store.Get(ctx, "tasks/1/abc")
+	assert.ErrorIs(t, err, storage.ErrBlobNotFound)
+	assert.ErrorIs(t, store.Put(ctx, "../evasion", bytes.NewReader(nil), 0, ""), storage.ErrInvalidKey)
+
+	// Une signature refusée remonte en erreur
+	bad, _ := storage.NewS3Store(storage.S3Config{Endpoint: server.URL, Bucket: "pieces", AccessKey: "AUTRE", SecretKey: "x"})
+	assert.Error(t, bad.Put(ctx, "tasks/1/abc", bytes.NewReader(content), int64(len(content)), ""))
+}
@@ -0,0 +1,147 @@
+// tests/bulk_test.go
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"YoannLetacq/todo-api.git/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// postBulk envoie un lot d'opérations sur POST /tasks/bulk.
+func postBulk(router *gin.Engine, token string, body map[string]interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+	jsonData, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", "/tasks/bulk", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+// TestBulkTasksAtomic vérifie qu'une erreur annule tout le lot en mode atomique.
+func TestBulkTasksAtomic(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	router := a.Router
+
+	user, token := createTestUserAndToken(t, a.DB)
+	task := models.Task{Title: "Bulk", Status: "todo", UserID: user.ID}
+	a.DB.Create(&task)
+
+	w, resp := postBulk(router, token, map[string]interface{}{
+		"operations": []map[string]interface{}{
+			{"op": "status", "id": task.ID, "status": "done"},
+			{"op": "create", "title": "Bulk Created"},
+			{"op": "delete", "id": task.ID + 1000},
+		},
+	})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	results := resp["results"].([]interface{})
+	assert.Len(t, results, 3)
+	assert.Equal(t, "Opération annulée.", results[0].(map[string]interface{})["error"])
+	assert.Equal(t, "Tâche introuvable.", results[2].(map[string]interface{})["error"])
+
+	var unchanged models.Task
+	a.DB.First(&unchanged, task.ID)
+	assert.Equal(t, "todo", unchanged.Status)
+
+	var count int64
+	a.DB.Model(&models.Task{}).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+// TestBulkTasksBestEffort vérifie que les opérations valides sont appliquées malgré les erreurs.
+func TestBulkTasksBestEffort(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	router := a.Router
+
+	user, token := createTestUserAndToken(t, a.DB)
+	first := models.Task{Title: "First", Status: "todo", UserID: user.ID}
+	second := models.Task{Title: "Second", Status: "todo", UserID: user.ID}
+	a.DB.Create(&first)
+	a.DB.Create(&second)
+
+	w, resp := postBulk(router, token, map[string]interface{}{
+		"mode": "best_effort",
+		"operations": []map[string]interface{}{
+			{"op": "status", "id": first.ID, "status": "done"},
+			{"op": "delete", "id": second.ID},
+			{"op": "status", "id": second.ID, "status": "done"},
+			{"op": "unknown"},
+		},
+	})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	results := resp["results"].([]interface{})
+	assert.Equal(t, true, results[0].(map[string]interface{})["ok"])
+	assert.Equal(t, true, results[1].(map[string]interface{})["ok"])
+	assert.Equal(t, false, results[2].(map[string]interface{})["ok"])
+	assert.Equal(t, false, results[3].(map[string]interface{})["ok"])
+
+	var updated models.Task
+	a.DB.First(&updated, first.ID)
+	assert.Equal(t, "done", updated.Status)
+	assert.Error(t, a.DB.First(&models.Task{}, second.ID).Error)
+}
+
+// TestBulkTasksBestEffortRollback vérifie qu'une opération annulée en base ne se retrouve
+// ni dans les opérations suivantes sur la même tâche ni dans les résultats.
+func TestBulkTasksBestEffortRollback(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	router := a.Router
+
+	user, token := createTestUserAndToken(t, a.DB)
+	first := models.Task{Title: "First", Status: "todo", UserID: user.ID}
+	second := models.Task{Title: "Second", Status: "todo", UserID: user.ID}
+	a.DB.Create(&first)
+	a.DB.Create(&second)
+
+	// La base refuse le titre "boom" : l'opération échoue dans son savepoint
+	err := a.DB.Exec(`CREATE TRIGGER bulk_reject BEFORE UPDATE ON tasks WHEN NEW.title = 'boom' AND NEW.status = 'todo'
+		BEGIN SELECT RAISE(ABORT, 'titre refusé'); END`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, resp := postBulk(router, token, map[string]interface{}{
+		"mode": "best_effort",
+		"operations": []map[string]interface{}{
+			{"op": "update", "id": first.ID, "title": "boom"},
+			{"op": "status", "id": first.ID, "status": "done"},
+			{"op": "status", "id": second.ID, "status": "done"},
+		},
+	})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	results := resp["results"].([]interface{})
+	for i, ok := range []bool{false, false, true} {
+		result := results[i].(map[string]interface{})
+		assert.Equal(t, ok, result["ok"], "opération %d", i)
+		if !ok {
+			assert.Nil(t, result["task"], "opération %d", i)
+		}
+	}
+	assert.Contains(t, results[1].(map[string]interface{})["error"], "opération précédente")
+	task := results[2].(map[string]interface{})["task"].(map[string]interface{})
+	assert.Equal(t, "Second", task["title"])
+	assert.Equal(t, "done", task["status"])
+
+	// Le statut de la première tâche n'a pas été appliqué avec le titre annulé
+	var unchanged models.Task
+	a.DB.First(&unchanged, first.ID)
+	assert.Equal(t, "First", unchanged.Title)
+	assert.Equal(t, "todo", unchanged.Status)
+}
@@ -0,0 +1,83 @@
+// tests/calendar_test.go
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCalendarFeed vérifie le rendu du flux, l'ETag et la révocation du token.
+func TestCalendarFeed(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	router := a.Router
+
+	user, token := createTestUserAndToken(t, a.DB)
+	due := time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)
+	a.DB.Create(&models.Task{Title: "Sans échéance", Status: "todo", UserID: user.ID})
+	task := models.Task{Title: "Avec échéance", Status: "todo", DueDate: &due, UserID: user.ID}
+	a.DB.Create(&task)
+
+	req, _ := http.NewRequest("POST", "/calendar/token", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var resp map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal("Erreur de parsing:", err)
+	}
+	feedPath := resp["url"][strings.Index(resp["url"], "/calendar/feed/"):]
+
+	// Premier appel : rendu complet
+	req, _ = http.NewRequest("GET", feedPath, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "BEGIN:VTODO\r\n")
+	assert.Contains(t, body, "SUMMARY:Sans échéance\r\n")
+	assert.Contains(t, body, "BEGIN:VEVENT\r\n")
+	assert.Contains(t, body, "DTSTART;VALUE=DATE:20260314\r\n")
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// Même ETag : 304 sans corps
+	req, _ = http.NewRequest("GET", feedPath, nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	// Une modification change l'ETag
+	task.Title = "Échéance modifiée"
+	a.DB.Save(&task)
+	req, _ = http.NewRequest("GET", feedPath, nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+
+	// Révocation : le flux n'est plus accessible
+	req, _ = http.NewRequest("DELETE", "/calendar/token", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", feedPath, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
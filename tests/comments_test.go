@@ -0,0 +1,203 @@
+// tests/comments_test.go
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"YoannLetacq/todo-api.git/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTaskComments vérifie le CRUD des commentaires, les droits et les mentions.
+func TestTaskComments(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	router := a.Router
+
+	_, token := createTestUserAndToken(t, a.DB)
+
+	other := models.User{Username: "otherUser", Email: "otherUser@example.com", Password: "x"}
+	if err := a.DB.Create(&other).Error; err != nil {
+		t.Fatal("Erreur lors de la création de l'utilisateur:", err)
+	}
+	otherToken, _ := testJWTKeys.Generate(strconv.Itoa(int(other.ID)), other.Email)
+
+	send := func(token, method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(token, "POST", "/tasks", map[string]string{"title": "Commentée"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var createResp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &createResp)
+	taskID := strconv.Itoa(int(createResp["task"].(map[string]interface{})["id"].(float64)))
+
+	// Mention de soi-même et adresse email : aucune notification
+	w = send(token, "POST", "/tasks/"+taskID+"/comments", map[string]string{"body": "Revue @testUser, contact a@otherUser.fr"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var commentResp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &commentResp)
+	comment := commentResp["comment"].(map[string]interface{})
+	commentID := strconv.Itoa(int(comment["id"].(float64)))
+	assert.Nil(t, comment["edited_at"])
+
+	var count int64
+	a.DB.Model(&models.Notification{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	w = send(token, "POST", "/tasks/"+taskID+"/comments", map[string]string{"body": "   "})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Un utilisateur sans accès à la tâche ne peut ni lire ni commenter
+	w = send(otherToken, "GET", "/tasks/"+taskID+"/comments", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = send(otherToken, "PUT", "/tasks/"+taskID+"/comments/"+commentID, map[string]string{"body": "piraté"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = send(token, "PUT", "/tasks/"+taskID+"/comments/"+commentID, map[string]string{"body": "Revue terminée"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &commentResp)
+	comment = commentResp["comment"].(map[string]interface{})
+	assert.Equal(t, "Revue terminée", comment["body"])
+	assert.NotNil(t, comment["edited_at"])
+
+	w = send(token, "GET", "/tasks/"+taskID+"/comments", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var listResp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &listResp)
+	assert.Len(t, listResp["comments"], 1)
+
+	// Un commentaire d'une autre tâche est introuvable
+	w = send(token, "DELETE", "/tasks/999999/comments/"+commentID, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = send(token, "DELETE", "/tasks/"+taskID+"/comments/"+commentID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = send(token, "GET", "/tasks/"+taskID+"/comments", nil)
+	json.Unmarshal(w.Body.Bytes(), &listResp)
+	assert.Len(t, listResp["comments"], 0)
+
+	w = send(token, "GET", "/notifications", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = send(token, "POST", "/notifications/999999/read", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestCommentMentions vérifie qu'une mention du propriétaire donne accès à la tâche et notifie
+// l'utilisateur mentionné, et que la suppression de la tâche supprime sa discussion.
+func TestCommentMentions(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	router := a.Router
+
+	owner, token := createTestUserAndToken(t, a.DB)
+
+	other := models.User{Username: "otherUser", Email: "otherUser@example.com", Password: "x"}
+	third := models.User{Username: "thirdUser", Email: "thirdUser@example.com", Password: "x"}
+	for _, u := range []*models.User{&other, &third} {
+		if err := a.DB.Create(u).Error; err != nil {
+			t.Fatal("Erreur lors de la création de l'utilisateur:", err)
+		}
+	}
+	otherToken, _ := testJWTKeys.Generate(strconv.Itoa(int(other.ID)), other.Email)
+	thirdToken, _ := testJWTKeys.Generate(strconv.Itoa(int(third.ID)), third.Email)
+
+	send := func(token, method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(token, "POST", "/tasks", map[string]string{"title": "Partagée"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var createResp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &createResp)
+	taskID := strconv.Itoa(int(createResp["task"].(map[string]interface{})["id"].(float64)))
+
+	w = send(otherToken, "GET", "/tasks/"+taskID+"/comments", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Le propriétaire mentionne otherUser : il est notifié et peut lire et commenter la tâche
+	w = send(token, "POST", "/tasks/"+taskID+"/comments", map[string]string{"body": "@otherUser peux-tu relire ?"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = send(otherToken, "GET", "/notifications", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var notifResp struct {
+		Notifications []map[string]interface{} `json:"notifications"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &notifResp)
+	if assert.Len(t, notifResp.Notifications, 1) {
+		n := notifResp.Notifications[0]
+		assert.Equal(t, models.NotificationMention, n["type"])
+		assert.Equal(t, float64(owner.ID), n["actor_id"])
+		assert.Nil(t, n["read_at"])
+
+		notificationID := strconv.Itoa(int(n["id"].(float64)))
+		w = send(token, "POST", "/notifications/"+notificationID+"/read", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = send(otherToken, "POST", "/notifications/"+notificationID+"/read", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = send(otherToken, "GET", "/notifications", nil)
+		json.Unmarshal(w.Body.Bytes(), &notifResp)
+		assert.NotNil(t, notifResp.Notifications[0]["read_at"])
+	}
+
+	w = send(otherToken, "GET", "/tasks/"+taskID+"/comments", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Un participant voit les pièces jointes mais ne supprime pas celles du propriétaire
+	tid, _ := strconv.Atoi(taskID)
+	attachment := models.Attachment{TaskID: uint(tid), UploaderID: owner.ID, Filename: "a.txt", ContentType: "text/plain", SHA256: "x", StorageKey: "mentions/a.txt"}
+	if err := a.DB.Create(&attachment).Error; err != nil {
+		t.Fatal(err)
+	}
+	w = send(otherToken, "GET", "/tasks/"+taskID+"/attachments", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = send(otherToken, "DELETE", "/tasks/"+taskID+"/attachments/"+strconv.Itoa(int(attachment.ID)), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// La mention d'un participant ne donne pas accès à la tâche
+	w = send(otherToken, "POST", "/tasks/"+taskID+"/comments", map[string]string{"body": "Relu, @testUser. cc @thirdUser"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = send(thirdToken, "GET", "/tasks/"+taskID+"/comments", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var count int64
+	a.DB.Model(&models.Notification{}).Where("user_id = ?", owner.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+	a.DB.Model(&models.Notification{}).Where("user_id = ?", third.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// La suppression de la tâche supprime ses commentaires, notifications et participants
+	w = send(token, "DELETE", "/tasks/"+taskID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	a.DB.Model(&models.Comment{}).Where("task_id = ?", taskID).Count(&count)
+	assert.Equal(t, int64(0), count)
+	a.DB.Model(&models.Notification{}).Where("task_id = ?", taskID).Count(&count)
+	assert.Equal(t, int64(0), count)
+	a.DB.Model(&models.TaskParticipant{}).Where("task_id = ?", taskID).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
@@ -0,0 +1,114 @@
+// tests/dto_test.go
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"YoannLetacq/todo-api.git/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRequestDTOs vérifie que les corps de requête refusent les champs inconnus,
+// dont les champs internes d'un enregistrement (id, user_id, dates).
+func TestRequestDTOs(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	user, token := createTestUserAndToken(t, a.DB)
+
+	other := models.User{Username: "autre", Email: "autre@example.com"}
+	if err := a.DB.Create(&other).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Un client ne peut pas choisir l'ID ni le propriétaire d'une tâche
+	w := apiTokenRequest(a.Router, "POST", "/tasks", token, map[string]interface{}{"title": "Volée", "user_id": other.ID})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = apiTokenRequest(a.Router, "POST", "/tasks", token, map[string]interface{}{"title": "Forcée", "ID": 4242})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	// Une faute de frappe n'est pas ignorée silencieusement
+	w = apiTokenRequest(a.Router, "POST", "/tasks", token, map[string]interface{}{"title": "Typo", "descritpion": "..."})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var n int64
+	if err := a.DB.Model(&models.Task{}).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(0), n)
+
+	task := models.Task{Title: "Existante", Status: models.StatusTodo, UserID: user.ID}
+	if err := a.DB.Create(&task).Error; err != nil {
+		t.Fatal(err)
+	}
+	path := "/tasks/" + strconv.Itoa(int(task.ID))
+	w = apiTokenRequest(a.Router, "PUT", path, token, map[string]interface{}{"title": "Modifiée", "created_at": "2020-01-01T00:00:00Z"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = apiTokenRequest(a.Router, "PUT", path, token, map[string]interface{}{"title": "Modifiée"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = apiTokenRequest(a.Router, "POST", "/tasks/bulk", token, map[string]interface{}{
+		"operations": []map[string]interface{}{{"op": "create", "title": "Lot", "user_id": other.ID}},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = apiTokenRequest(a.Router, "POST", path+"/comments", token, map[string]interface{}{"body": "Salut", "author_id": other.ID})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Inscription : les champs sont obligatoires et les champs inconnus refusés
+	register := func(body map[string]interface{}) int {
+		data, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/register", bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusBadRequest, register(map[string]interface{}{"username": "x", "email": "x@example.com", "password": "secret", "ID": 1}))
+	assert.Equal(t, http.StatusBadRequest, register(map[string]interface{}{"username": "x", "email": "x@example.com"}))
+
+	// Des données après le corps JSON sont refusées
+	for _, body := range []string{`{"title": "Double"}{"title": "Double"}`, `{"title": "Suite"} garbage`} {
+		req, _ := http.NewRequest("POST", "/tasks", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	// Les espaces après le corps restent acceptés
+	req, _ := http.NewRequest("POST", "/tasks", bytes.NewBufferString("{\"title\": \"Espaces\"}\n  "))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	assert.Equal(t, http.StatusCreated, register(map[string]interface{}{"username": "x", "email": "x@example.com", "password": "secret"}))
+}
+
+// TestResponseDTOs vérifie que les réponses n'exposent que les champs documentés, en snake_case.
+func TestResponseDTOs(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	user, token := createTestUserAndToken(t, a.DB)
+
+	w := apiTokenRequest(a.Router, "POST", "/tasks", token, map[string]string{"title": "Publique"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var resp struct {
+		Task map[string]interface{} `json:"task"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]string, 0, len(resp.Task))
+	for k := range resp.Task {
+		keys = append(keys, k)
+	}
+	assert.ElementsMatch(t, []string{"id", "title", "description", "status", "due_date", "user_id", "created_at", "updated_at"}, keys)
+	assert.Equal(t, float64(user.ID), resp.Task["user_id"])
+	assert.Equal(t, models.StatusTodo, resp.Task["status"])
+}
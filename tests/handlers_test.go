@@ -6,46 +6,51 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"strconv"
 	"testing"
 
 	"YoannLetacq/todo-api.git/config"
 	"YoannLetacq/todo-api.git/internal/handlers"
+	"YoannLetacq/todo-api.git/internal/middleware"
 	"YoannLetacq/todo-api.git/internal/models"
 	"YoannLetacq/todo-api.git/internal/repository"
 	"YoannLetacq/todo-api.git/internal/services"
-	"YoannLetacq/todo-api.git/internal/utils"
+	"YoannLetacq/todo-api.git/routes"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
-// setHandlerTestDB initialise la base de données pour les tests de handlers.
-func setHandlerTestDB() {
-	config.InitDB(true)
-	config.DB.Exec("DELETE FROM users")
-	config.DB.Exec("DELETE FROM tasks")
-	config.DB.AutoMigrate(&models.User{}, &models.Task{})
-}
+// newHandlerTest ouvre une base isolée et construit les handlers utilisateurs et tâches.
+func newHandlerTest(t *testing.T) (*gorm.DB, routes.Handlers) {
+	db, err := config.OpenTestDB()
+	if err != nil {
+		t.Fatal("Erreur lors de l'ouverture de la base de test:", err)
+	}
 
-// initHandlerTestServices initialise et injecte les services dans les handlers.
-func initHandlerTestServices() {
 	// Service User
-	userRepo := repository.NewUserRepository()
+	userRepo := repository.NewUserRepository(db)
 	userSvc := services.NewUserService(userRepo)
-	handlers.InitUserHandlers(userSvc) // Assurez-vous d'utiliser le bon nom (InitUserHandlers)
 
 	// Service Task
-	taskRepo := repository.NewTaskRepository()
-	taskSvc := services.NewTaskService(taskRepo)
-	handlers.InitTaskHandlers(taskSvc)
+	taskRepo := repository.NewTaskRepository(db)
+	searcher, err := repository.NewSearcher(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskSvc := services.NewTaskService(taskRepo, searcher)
+
+	return db, routes.Handlers{
+		Users: handlers.NewUserHandler(userSvc, services.NewMFAService(repository.NewMFARepository(db), userRepo),
+			services.NewSessionService(repository.NewSessionRepository(db)), testJWTKeys),
+		Tasks: handlers.NewTaskHandler(taskSvc, nil, nil),
+	}
 }
 
 // createTestUser crée un utilisateur en BDD.
-func createTestUser(t *testing.T) models.User {
-	os.Setenv("JWT_SECRET", "my_secret_key")
+func createTestUser(t *testing.T, db *gorm.DB) models.User {
 	password := "password"
 	hashedPass, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -56,7 +61,7 @@ func createTestUser(t *testing.T) models.User {
 		Email:    "testUser@example.com",
 		Password: string(hashedPass),
 	}
-	if err := config.DB.Create(&user).Error; err != nil {
+	if err := db.Create(&user).Error; err != nil {
 		t.Fatal("Erreur lors de la création de l'utilisateur:", err)
 	}
 	return user
@@ -64,7 +69,7 @@ func createTestUser(t *testing.T) models.User {
 
 // generateTokenForUser génère un token JWT pour un utilisateur.
 func generateTokenForUser(user models.User, t *testing.T) string {
-	token, err := utils.GenerateJWT(strconv.Itoa(int(user.ID)), user.Email)
+	token, err := testJWTKeys.Generate(strconv.Itoa(int(user.ID)), user.Email)
 	if err != nil {
 		t.Fatal("Erreur lors de la génération du token JWT:", err)
 	}
@@ -73,8 +78,8 @@ func generateTokenForUser(user models.User, t *testing.T) string {
 
 // TestRegisterUserHandler teste directement le handler RegisterUser.
 func TestRegisterUserHandler(t *testing.T) {
-	setHandlerTestDB()
-	initHandlerTestServices()
+	t.Parallel()
+	_, h := newHandlerTest(t)
 
 	registerData := map[string]string{
 		"username": "handlerUser",
@@ -89,7 +94,7 @@ func TestRegisterUserHandler(t *testing.T) {
 	c, _ := gin.CreateTestContext(w)
 	c.Request = req
 
-	handlers.RegisterUser(c)
+	h.Users.RegisterUser(c)
 
 	assert.Equal(t, http.StatusCreated, w.Code)
 	var resp map[string]string
@@ -101,11 +106,11 @@ func TestRegisterUserHandler(t *testing.T) {
 
 // TestLoginUserHandler teste directement le handler LoginHandler.
 func TestLoginUserHandler(t *testing.T) {
-	setHandlerTestDB()
-	initHandlerTestServices()
+	t.Parallel()
+	db, h := newHandlerTest(t)
 
 	// Créer un utilisateur
-	user := createTestUser(t)
+	user := createTestUser(t, db)
 
 	// Préparer la requête de login
 	loginData := map[string]string{
@@ -119,7 +124,7 @@ func TestLoginUserHandler(t *testing.T) {
 	c, _ := gin.CreateTestContext(w)
 	c.Request = req
 
-	handlers.LoginHandler(c)
+	h.Users.LoginHandler(c)
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	var resp map[string]string
@@ -128,7 +133,7 @@ func TestLoginUserHandler(t *testing.T) {
 	}
 	token, exists := resp["token"]
 	assert.True(t, exists, "Le token JWT est absent")
-	parsedToken, claims, err := utils.ParseToken(token)
+	parsedToken, claims, err := testJWTKeys.Parse(token)
 	assert.NoError(t, err)
 	assert.True(t, parsedToken.Valid)
 	assert.Equal(t, strconv.Itoa(int(user.ID)), claims["user_id"])
@@ -136,12 +141,12 @@ func TestLoginUserHandler(t *testing.T) {
 
 // TestGetTaskHandler teste directement le handler GetTask.
 func TestGetTaskHandler(t *testing.T) {
-	setHandlerTestDB()
-	initHandlerTestServices()
+	t.Parallel()
+	db, h := newHandlerTest(t)
 
-	user, token := createTestUserAndToken(t)
+	user, token := createTestUserAndToken(t, db)
 	task := models.Task{Title: "Task Get", Description: "Desc Get", Status: "done", UserID: user.ID}
-	config.DB.Create(&task)
+	db.Create(&task)
 
 	req, _ := http.NewRequest("GET", "/tasks/"+strconv.Itoa(int(task.ID)), nil)
 	req.Header.Set("Authorization", "Bearer "+token)
@@ -150,8 +155,9 @@ func TestGetTaskHandler(t *testing.T) {
 	c.Request = req
 	// Injecter le paramètre de route manuellement
 	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}}
+	middleware.Authenticate(testJWTKeys, nil, nil)(c)
 
-	handlers.GetTask(c)
+	h.Tasks.GetTask(c)
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	var resp map[string]interface{}
@@ -167,12 +173,12 @@ func TestGetTaskHandler(t *testing.T) {
 
 // TestUpdateTaskHandler teste directement le handler UpdateTask.
 func TestUpdateTaskHandler(t *testing.T) {
-	setHandlerTestDB()
-	initHandlerTestServices()
+	t.Parallel()
+	db, h := newHandlerTest(t)
 
-	user, token := createTestUserAndToken(t)
+	user, token := createTestUserAndToken(t, db)
 	task := models.Task{Title: "Task Update", Description: "Old Desc", Status: "todo", UserID: user.ID}
-	config.DB.Create(&task)
+	db.Create(&task)
 
 	updatedData := map[string]string{
 		"title":       "Task Updated",
@@ -188,8 +194,9 @@ func TestUpdateTaskHandler(t *testing.T) {
 	c.Request = req
 	// Injecter le paramètre de route
 	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}}
+	middleware.Authenticate(testJWTKeys, nil, nil)(c)
 
-	handlers.UpdateTask(c)
+	h.Tasks.UpdateTask(c)
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	var resp map[string]interface{}
@@ -206,12 +213,12 @@ func TestUpdateTaskHandler(t *testing.T) {
 
 // TestDeleteTaskHandler teste directement le handler DeleteTask.
 func TestDeleteTaskHandler(t *testing.T) {
-	setHandlerTestDB()
-	initHandlerTestServices()
+	t.Parallel()
+	db, h := newHandlerTest(t)
 
-	user, token := createTestUserAndToken(t)
+	user, token := createTestUserAndToken(t, db)
 	task := models.Task{Title: "Task Delete", Description: "Desc Delete", Status: "done", UserID: user.ID}
-	config.DB.Create(&task)
+	db.Create(&task)
 
 	req, _ := http.NewRequest("DELETE", "/tasks/"+strconv.Itoa(int(task.ID)), nil)
 	req.Header.Set("Authorization", "Bearer "+token)
@@ -220,8 +227,9 @@ func TestDeleteTaskHandler(t *testing.T) {
 	c.Request = req
 	// Injecter le paramètre de route
 	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}}
+	middleware.Authenticate(testJWTKeys, nil, nil)(c)
 
-	handlers.DeleteTask(c)
+	h.Tasks.DeleteTask(c)
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	var resp map[string]interface{}
@@ -232,6 +240,6 @@ func TestDeleteTaskHandler(t *testing.T) {
 
 	// Vérifier que la tâche n'existe plus en base
 	var deletedTask models.Task
-	err := config.DB.First(&deletedTask, task.ID).Error
+	err := db.First(&deletedTask, task.ID).Error
 	assert.Error(t, err)
 }
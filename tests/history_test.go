@@ -0,0 +1,77 @@
+// tests/history_test.go
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTaskHistory vérifie l'enregistrement des créations, modifications et suppressions.
+func TestTaskHistory(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	router := a.Router
+
+	user, token := createTestUserAndToken(t, a.DB)
+
+	send := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", "/tasks", map[string]string{"title": "Historique"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var createResp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &createResp)
+	taskID := strconv.Itoa(int(createResp["task"].(map[string]interface{})["id"].(float64)))
+
+	w = send("PUT", "/tasks/"+taskID, map[string]string{"title": "Historique", "status": "done"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = send("DELETE", "/tasks/"+taskID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// L'historique reste consultable après la suppression
+	w = send("GET", "/tasks/"+taskID+"/history?page_size=2", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal("Erreur de parsing:", err)
+	}
+	assert.Equal(t, float64(3), resp["total"])
+
+	history := resp["history"].([]interface{})
+	assert.Len(t, history, 2)
+	deleted := history[0].(map[string]interface{})
+	assert.Equal(t, "deleted", deleted["action"])
+	assert.Equal(t, float64(user.ID), deleted["actor_id"])
+
+	updated := history[1].(map[string]interface{})
+	assert.Equal(t, "updated", updated["action"])
+	changes := updated["changes"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"old": "todo", "new": "done"}, changes["status"])
+	assert.NotContains(t, changes, "title")
+
+	w = send("GET", "/tasks/"+taskID+"/history?page=2&page_size=2", nil)
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	history = resp["history"].([]interface{})
+	assert.Len(t, history, 1)
+	assert.Equal(t, "created", history[0].(map[string]interface{})["action"])
+
+	// Historique d'une tâche inconnue
+	w = send("GET", "/tasks/999999/history", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,221 @@
+// tests/idempotency_test.go
+package tests
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/app"
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/routes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// idempotentRequest envoie une requête authentifiée avec l'en-tête Idempotency-Key
+func idempotentRequest(router *gin.Engine, method, path, token, key string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Idempotency-Key", key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// TestIdempotencyKey vérifie le rejeu d'une requête avec la même clé et le refus d'un autre corps.
+func TestIdempotencyKey(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	user, token := createTestUserAndToken(t, a.DB)
+	taskCount := func() int64 {
+		var n int64
+		if err := a.DB.Model(&models.Task{}).Where("user_id = ?", user.ID).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	body := map[string]string{"title": "Acheter du pain"}
+	first := idempotentRequest(a.Router, "POST", "/tasks", token, "cle-1", body)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+	// La même requête est rejouée sans créer de seconde tâche
+	second := idempotentRequest(a.Router, "POST", "/tasks", token, "cle-1", body)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, first.Header().Get("Content-Type"), second.Header().Get("Content-Type"))
+	assert.Equal(t, int64(1), taskCount())
+
+	// Un autre corps avec la même clé est refusé
+	w := idempotentRequest(a.Router, "POST", "/tasks", token, "cle-1", map[string]string{"title": "Autre"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = idempotentRequest(a.Router, "POST", "/tasks/bulk", token, "cle-1", body)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// Sans clé, chaque requête est exécutée
+	w = apiTokenRequest(a.Router, "POST", "/tasks", token, body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, int64(2), taskCount())
+
+	// Les clés sont propres à chaque utilisateur
+	other := models.User{Username: "autre", Email: "autre@example.com"}
+	if err := a.DB.Create(&other).Error; err != nil {
+		t.Fatal(err)
+	}
+	otherJWT, err := testJWTKeys.Generate(strconv.Itoa(int(other.ID)), other.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = idempotentRequest(a.Router, "POST", "/tasks", otherJWT, "cle-1", body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+
+	// Une clé expirée après 24 h peut être réutilisée
+	if err := a.DB.Model(&models.IdempotencyKey{}).Where("user_id = ?", user.ID).Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	w = idempotentRequest(a.Router, "POST", "/tasks", token, "cle-1", map[string]string{"title": "Autre"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, int64(3), taskCount())
+
+	// Une requête interrompue avant sa réponse est reprise une fois son verrou expiré
+	var encoded bytes.Buffer
+	_ = json.NewEncoder(&encoded).Encode(body)
+	fingerprint := sha256.Sum256(append([]byte("POST /tasks\n\n"), encoded.Bytes()...))
+	stale := models.IdempotencyKey{
+		UserID:      user.ID,
+		Key:         "cle-2",
+		Fingerprint: hex.EncodeToString(fingerprint[:]),
+		LockedUntil: time.Now().Add(-time.Second),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	if err := a.DB.Create(&stale).Error; err != nil {
+		t.Fatal(err)
+	}
+	w = idempotentRequest(a.Router, "POST", "/tasks", token, "cle-2", body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, int64(4), taskCount())
+
+	// Une clé trop longue est refusée
+	w = idempotentRequest(a.Router, "POST", "/tasks", token, string(bytes.Repeat([]byte("k"), 256)), body)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// La même requête retentée sous /api/v1 est rejouée : l'empreinte ne dépend pas du préfixe
+	w = idempotentRequest(a.Router, "POST", routes.V1Prefix+"/tasks", token, "cle-2", body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, int64(4), taskCount())
+
+	// Les valeurs des paramètres et la query string font partie de l'empreinte
+	task := models.Task{Title: "Cible", Status: models.StatusTodo, UserID: user.ID}
+	if err := a.DB.Create(&task).Error; err != nil {
+		t.Fatal(err)
+	}
+	path := "/tasks/" + strconv.Itoa(int(task.ID))
+	w = idempotentRequest(a.Router, "PUT", path, token, "cle-3", body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = idempotentRequest(a.Router, "PUT", path+"0", token, "cle-3", body)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = idempotentRequest(a.Router, "PUT", path+"?x=1", token, "cle-3", body)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+// TestIdempotencyKeyBodyLimit vérifie que le corps lu par le middleware est borné.
+func TestIdempotencyKeyBodyLimit(t *testing.T) {
+	t.Parallel()
+	a := newTestAppWith(t, app.Options{MaxAttachmentSize: 64})
+	_, token := createTestUserAndToken(t, a.DB)
+
+	req, _ := http.NewRequest("POST", "/tasks", bytes.NewReader(bytes.Repeat([]byte(" "), 6<<20)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Idempotency-Key", "cle-lourde")
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	var n int64
+	if err := a.DB.Model(&models.IdempotencyKey{}).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.Zero(t, n)
+}
+
+// TestIdempotencyKeySecretRoutes vérifie que les réponses contenant un secret ne sont jamais conservées.
+func TestIdempotencyKeySecretRoutes(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	_, token := createTestUserAndToken(t, a.DB)
+
+	body := map[string]interface{}{"name": "ci", "scopes": []string{"tasks:read"}}
+	first := idempotentRequest(a.Router, "POST", "/me/tokens", token, "cle-secret", body)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	second := idempotentRequest(a.Router, "POST", "/me/tokens", token, "cle-secret", body)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Empty(t, second.Header().Get("Idempotent-Replayed"))
+	assert.NotEqual(t, first.Body.String(), second.Body.String())
+
+	w := idempotentRequest(a.Router, "POST", "/me/mfa/totp", token, "cle-totp", nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = idempotentRequest(a.Router, "POST", "/calendar/token", token, "cle-calendrier", nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var n int64
+	if err := a.DB.Model(&models.IdempotencyKey{}).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.Zero(t, n)
+}
+
+// TestIdempotencyKeyConcurrent vérifie que des requêtes simultanées avec la même clé
+// n'exécutent la requête qu'une fois et reçoivent toutes la même réponse.
+func TestIdempotencyKeyConcurrent(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	user, token := createTestUserAndToken(t, a.DB)
+
+	const clients = 8
+	responses := make([]*httptest.ResponseRecorder, clients)
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = idempotentRequest(a.Router, "POST", "/tasks", token, "cle-mobile", map[string]string{"title": "Réessayée"})
+		}(i)
+	}
+	wg.Wait()
+
+	replayed := 0
+	for _, w := range responses {
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, responses[0].Body.String(), w.Body.String())
+		if w.Header().Get("Idempotent-Replayed") == "true" {
+			replayed++
+		}
+	}
+	assert.Equal(t, clients-1, replayed)
+
+	var n int64
+	if err := a.DB.Model(&models.Task{}).Where("user_id = ?", user.ID).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1), n)
+}
@@ -0,0 +1,213 @@
+// tests/mfa_test.go
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/app"
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// enableTOTP active la double authentification et retourne le secret et les codes de récupération
+func enableTOTP(t *testing.T, a *app.App, jwt string) (string, []string) {
+	w := apiTokenRequest(a.Router, "POST", "/me/mfa/totp", jwt, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var enrolled struct {
+		Secret     string `json:"secret"`
+		OTPAuthURI string `json:"otpauth_uri"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &enrolled); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, strings.HasPrefix(enrolled.OTPAuthURI, "otpauth://totp/"), enrolled.OTPAuthURI)
+	assert.Contains(t, enrolled.OTPAuthURI, "secret="+enrolled.Secret)
+
+	w = apiTokenRequest(a.Router, "POST", "/me/mfa/totp/confirm", jwt, gin.H{"code": "000000x"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	code, err := utils.TOTPCode(enrolled.Secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = apiTokenRequest(a.Router, "POST", "/me/mfa/totp/confirm", jwt, gin.H{"code": code})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var confirmed struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &confirmed); err != nil {
+		t.Fatal(err)
+	}
+	return enrolled.Secret, confirmed.RecoveryCodes
+}
+
+// passwordLogin envoie l'étape du mot de passe et retourne la réponse décodée
+func passwordLogin(t *testing.T, a *app.App) (int, map[string]interface{}) {
+	w := apiTokenRequest(a.Router, "POST", "/login", "", gin.H{"email": "testUser@example.com", "password": "password"})
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, resp
+}
+
+// TestMFAEnrollmentAndLogin vérifie l'enrôlement TOTP et la connexion en deux étapes.
+func TestMFAEnrollmentAndLogin(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	_, jwt := createTestUserAndToken(t, a.DB)
+
+	secret, recoveryCodes := enableTOTP(t, a, jwt)
+	assert.Len(t, recoveryCodes, 10)
+
+	// Seuls les hash des codes de récupération sont stockés
+	var stored []models.RecoveryCode
+	if err := a.DB.Find(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, stored, 10)
+	for _, rc := range stored {
+		for _, code := range recoveryCodes {
+			assert.NotContains(t, rc.CodeHash, strings.ReplaceAll(code, "-", ""))
+		}
+	}
+
+	// Un second enrôlement est refusé tant que le TOTP est actif
+	w := apiTokenRequest(a.Router, "POST", "/me/mfa/totp", jwt, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Le mot de passe ne donne plus qu'un mfa_token, qui n'authentifie pas l'API
+	status, resp := passwordLogin(t, a)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, true, resp["mfa_required"])
+	_, hasToken := resp["token"]
+	assert.False(t, hasToken)
+	mfaToken := resp["mfa_token"].(string)
+	w = apiTokenRequest(a.Router, "GET", "/tasks", mfaToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Le code de la confirmation a déjà servi
+	used, err := utils.TOTPCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = apiTokenRequest(a.Router, "POST", "/login/mfa", "", gin.H{"mfa_token": mfaToken, "code": used})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	next, err := utils.TOTPCode(secret, time.Now().Add(30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = apiTokenRequest(a.Router, "POST", "/login/mfa", "", gin.H{"mfa_token": mfaToken, "code": next})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var login map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &login); err != nil {
+		t.Fatal(err)
+	}
+	w = apiTokenRequest(a.Router, "GET", "/tasks", login["token"], nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Le mfa_token n'est utilisable qu'une fois
+	w = apiTokenRequest(a.Router, "POST", "/login/mfa", "", gin.H{"mfa_token": mfaToken, "code": recoveryCodes[0]})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Un code de récupération remplace le code TOTP, une seule fois, quelle que soit la casse
+	_, resp = passwordLogin(t, a)
+	w = apiTokenRequest(a.Router, "POST", "/login/mfa", "", gin.H{"mfa_token": resp["mfa_token"], "code": strings.ToUpper(recoveryCodes[0])})
+	assert.Equal(t, http.StatusOK, w.Code)
+	_, resp = passwordLogin(t, a)
+	w = apiTokenRequest(a.Router, "POST", "/login/mfa", "", gin.H{"mfa_token": resp["mfa_token"], "code": recoveryCodes[0]})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = apiTokenRequest(a.Router, "GET", "/me/mfa", jwt, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"totp_enabled": true, "recovery_codes_remaining": 9}`, w.Body.String())
+
+	// Désactivée, la connexion retourne de nouveau le JWT directement
+	w = apiTokenRequest(a.Router, "POST", "/me/mfa/disable", jwt, gin.H{"code": recoveryCodes[1]})
+	assert.Equal(t, http.StatusOK, w.Code)
+	status, resp = passwordLogin(t, a)
+	assert.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, resp["token"])
+	_, hasMFA := resp["mfa_required"]
+	assert.False(t, hasMFA)
+}
+
+// TestMFAChallengeLimits vérifie l'invalidation du mfa_token après trop d'essais ou à son expiration.
+func TestMFAChallengeLimits(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	_, jwt := createTestUserAndToken(t, a.DB)
+	_, recoveryCodes := enableTOTP(t, a, jwt)
+
+	_, resp := passwordLogin(t, a)
+	for i := 0; i < 5; i++ {
+		w := apiTokenRequest(a.Router, "POST", "/login/mfa", "", gin.H{"mfa_token": resp["mfa_token"], "code": "000000"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+	// Le bon code ne suffit plus, il faut recommencer la connexion
+	w := apiTokenRequest(a.Router, "POST", "/login/mfa", "", gin.H{"mfa_token": resp["mfa_token"], "code": recoveryCodes[0]})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "mfa_token")
+
+	_, resp = passwordLogin(t, a)
+	if err := a.DB.Model(&models.MFAChallenge{}).Where("1 = 1").Update("expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	w = apiTokenRequest(a.Router, "POST", "/login/mfa", "", gin.H{"mfa_token": resp["mfa_token"], "code": recoveryCodes[0]})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Un token d'accès personnel ne gère pas la double authentification
+	w = apiTokenRequest(a.Router, "POST", "/me/tokens", jwt, gin.H{"name": "ci", "scopes": []string{"tasks:read"}})
+	var pat createdAPIToken
+	if err := json.Unmarshal(w.Body.Bytes(), &pat); err != nil {
+		t.Fatal(err)
+	}
+	w = apiTokenRequest(a.Router, "POST", "/me/mfa/disable", pat.Token, gin.H{"code": recoveryCodes[0]})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// TestMFALockout vérifie que les codes invalides sont comptés par utilisateur, tous mfa_token
+// confondus, et que la vérification est bloquée après trop d'échecs.
+func TestMFALockout(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	_, jwt := createTestUserAndToken(t, a.DB)
+	_, recoveryCodes := enableTOTP(t, a, jwt)
+
+	// Chaque connexion par mot de passe ne redonne pas de nouveaux essais
+	for login := 0; login < 2; login++ {
+		_, resp := passwordLogin(t, a)
+		for i := 0; i < 5; i++ {
+			w := apiTokenRequest(a.Router, "POST", "/login/mfa", "", gin.H{"mfa_token": resp["mfa_token"], "code": "000000"})
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		}
+	}
+
+	// Même un code valide est refusé pendant le blocage, sans être consommé
+	_, resp := passwordLogin(t, a)
+	w := apiTokenRequest(a.Router, "POST", "/login/mfa", "", gin.H{"mfa_token": resp["mfa_token"], "code": recoveryCodes[0]})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	w = apiTokenRequest(a.Router, "POST", "/me/mfa/disable", jwt, gin.H{"code": recoveryCodes[0]})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// Le blocage levé, le code est accepté et le compteur remis à zéro
+	if err := a.DB.Model(&models.UserTOTP{}).Where("1 = 1").Update("locked_until", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	w = apiTokenRequest(a.Router, "POST", "/login/mfa", "", gin.H{"mfa_token": resp["mfa_token"], "code": recoveryCodes[0]})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var totp models.UserTOTP
+	if err := a.DB.First(&totp).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, totp.FailedAttempts)
+}
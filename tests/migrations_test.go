@@ -0,0 +1,211 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"YoannLetacq/todo-api.git/config"
+	"YoannLetacq/todo-api.git/internal/migrations"
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// TestMigrationsUpDownStatus vérifie l'application, l'annulation et l'état des migrations.
+func TestMigrationsUpDownStatus(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db, err := config.OpenTestDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// OpenTestDB a déjà tout appliqué
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, statuses)
+	for _, st := range statuses {
+		assert.NotNil(t, st.AppliedAt, "migration %04d_%s en attente", st.Version, st.Name)
+	}
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, applied)
+
+	// Tout annuler supprime les tables
+	reverted, err := migrator.Down(ctx, len(statuses))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, reverted, len(statuses))
+	assert.Equal(t, statuses[len(statuses)-1].Version, reverted[0].Version)
+	assert.False(t, db.Migrator().HasTable(&models.User{}))
+
+	statuses, err = migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range statuses {
+		assert.Nil(t, st.AppliedAt)
+	}
+
+	// Et les réappliquer les recrée
+	applied, err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, applied, len(statuses))
+	assert.True(t, db.Migrator().HasTable(&models.Task{}))
+	if err := db.Create(&models.User{Username: "migre", Email: "migre@example.com", Password: "x"}).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMigrationsConcurrent simule plusieurs instances qui démarrent en même temps sur la même base.
+func TestMigrationsConcurrent(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "concurrent.db")
+
+	const instances = 4
+	results := make([][]migrations.Migration, instances)
+	errs := make([]error, instances)
+
+	var wg sync.WaitGroup
+	for i := 0; i < instances; i++ {
+		db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		migrator, err := migrations.New(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = migrator.Up(context.Background())
+		}(i)
+	}
+	wg.Wait()
+
+	// Une seule instance applique les migrations, les autres trouvent le schéma à jour
+	total := 0
+	for i := 0; i < instances; i++ {
+		assert.NoError(t, errs[i])
+		total += len(results[i])
+	}
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(statuses), total)
+}
+
+// TestMigrationsAdoptAutoMigrate vérifie qu'une base créée par l'ancien AutoMigrate est reprise sans erreur.
+func TestMigrationsAdoptAutoMigrate(t *testing.T) {
+	t.Parallel()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "legacy.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Task{}, &models.CalendarFeed{}, &models.TaskEvent{}, &models.Comment{}, &models.Notification{}, &models.Attachment{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.User{Username: "ancien", Email: "ancien@example.com", Password: "x"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := config.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int64
+	db.Model(&models.User{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+// TestMigrationsStatusReadOnly vérifie que Status ne crée rien et n'attend pas le verrou des migrations.
+func TestMigrationsStatusReadOnly(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "status.db")
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Base vide : tout est en attente et schema_migrations n'est pas créée
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range statuses {
+		assert.Nil(t, st.AppliedAt)
+	}
+	assert.False(t, db.Migrator().HasTable("schema_migrations"))
+
+	// NewSearcher ne crée pas l'index de recherche, c'est le rôle des migrations
+	if _, err := repository.NewSearcher(db); err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, db.Migrator().HasTable("tasks_fts"))
+
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Une autre instance détient le verrou d'écriture : Status répond quand même
+	other, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := other.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(context.Background(), `BEGIN IMMEDIATE`); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.ExecContext(context.Background(), `ROLLBACK`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	statuses, err = migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range statuses {
+		assert.NotNil(t, st.AppliedAt, "migration %04d_%s en attente", st.Version, st.Name)
+	}
+}
@@ -0,0 +1,424 @@
+package tests
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"YoannLetacq/todo-api.git/config"
+	"YoannLetacq/todo-api.git/internal/app"
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+// Client enregistré auprès du fournisseur de test
+const (
+	mockClientID     = "todo-api"
+	mockClientSecret = "secret-du-client"
+	mockRedirectURL  = "http://localhost/auth/mock/callback"
+)
+
+// mockOIDC est un fournisseur OpenID Connect minimal : découverte, JWKS,
+// autorisation avec PKCE S256 et échange du code contre un ID token signé en RS256.
+type mockOIDC struct {
+	*httptest.Server
+	key  *rsa.PrivateKey
+	jwks utils.JWKSet
+
+	mu     sync.Mutex
+	grants map[string]mockGrant
+	// claims sont ajoutés à l'ID token de la prochaine autorisation
+	claims jwt.MapClaims
+}
+
+type mockGrant struct {
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newMockOIDC(t *testing.T) *mockOIDC {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := utils.NewJWTKeys(utils.JWTKey{ID: "mock-key", Algorithm: utils.AlgRS256, PrivateKey: key, PublicKey: &key.PublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &mockOIDC{key: key, jwks: keys.JWKS(), grants: make(map[string]mockGrant)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                m.URL,
+			"authorization_endpoint":                m.URL + "/authorize",
+			"token_endpoint":                        m.URL + "/token",
+			"jwks_uri":                              m.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(m.jwks)
+	})
+	mux.HandleFunc("/authorize", m.authorize)
+	mux.HandleFunc("/token", m.token)
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+// setUser choisit l'utilisateur connecté lors de la prochaine autorisation
+func (m *mockOIDC) setUser(claims jwt.MapClaims) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.claims = claims
+}
+
+func (m *mockOIDC) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != mockClientID || q.Get("redirect_uri") != mockRedirectURL ||
+		q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" ||
+		!strings.Contains(q.Get("scope"), "openid") {
+		http.Error(w, "requête d'autorisation invalide", http.StatusBadRequest)
+		return
+	}
+
+	raw := make([]byte, 16)
+	rand.Read(raw)
+	code := hex.EncodeToString(raw)
+	m.mu.Lock()
+	m.grants[code] = mockGrant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), claims: m.claims}
+	m.mu.Unlock()
+
+	redirect, _ := url.Parse(mockRedirectURL)
+	redirect.RawQuery = url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (m *mockOIDC) token(w http.ResponseWriter, r *http.Request) {
+	tokenError := func(code string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": code})
+	}
+
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID, secret = r.FormValue("client_id"), r.FormValue("client_secret")
+	}
+	if clientID != mockClientID || secret != mockClientSecret {
+		tokenError("invalid_client")
+		return
+	}
+
+	m.mu.Lock()
+	grant, ok := m.grants[r.FormValue("code")]
+	delete(m.grants, r.FormValue("code"))
+	m.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || r.FormValue("grant_type") != "authorization_code" || r.FormValue("redirect_uri") != mockRedirectURL ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		tokenError("invalid_grant")
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   m.URL,
+		"aud":   mockClientID,
+		"exp":   time.Now().Add(5 * time.Minute).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": grant.nonce,
+	}
+	for k, v := range grant.claims {
+		claims[k] = v
+	}
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = "mock-key"
+	signed, err := idToken.SignedString(m.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+// newOIDCTestApp construit une instance de l'API qui délègue la connexion au fournisseur de test
+func newOIDCTestApp(t *testing.T, mock *mockOIDC) *app.App {
+	return newTestAppWith(t, app.Options{OIDCProviders: map[string]*config.OIDCProviderConfig{
+		"mock": {
+			Issuer:       mock.URL,
+			ClientID:     mockClientID,
+			ClientSecret: mockClientSecret,
+			RedirectURL:  mockRedirectURL,
+			Scopes:       "email profile",
+		},
+	}})
+}
+
+// startOIDCLogin appelle /auth/mock/login et retourne l'URL d'autorisation et le cookie de connexion
+func startOIDCLogin(t *testing.T, router *gin.Engine) (*url.URL, *http.Cookie) {
+	req, _ := http.NewRequest("GET", "/auth/mock/login", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("login: statut %d, %s", w.Code, w.Body.String())
+	}
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("login: %d cookies", len(cookies))
+	}
+	return location, cookies[0]
+}
+
+// authorizeOIDC suit la redirection vers le fournisseur et retourne le code et le state du callback
+func authorizeOIDC(t *testing.T, authURL *url.URL) (code, state string) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: statut %d", resp.StatusCode)
+	}
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return callback.Query().Get("code"), callback.Query().Get("state")
+}
+
+// completeOIDCLogin appelle le callback avec le cookie de connexion
+func completeOIDCLogin(router *gin.Engine, code, state string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	query := url.Values{"code": {code}, "state": {state}}
+	req, _ := http.NewRequest("GET", "/auth/mock/callback?"+query.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// oidcLogin effectue une connexion complète et retourne la réponse du callback
+func oidcLogin(t *testing.T, router *gin.Engine) *httptest.ResponseRecorder {
+	authURL, cookie := startOIDCLogin(t, router)
+	code, state := authorizeOIDC(t, authURL)
+	return completeOIDCLogin(router, code, state, cookie)
+}
+
+// oidcUserID retourne l'utilisateur du JWT émis par le callback
+func oidcUserID(t *testing.T, w *httptest.ResponseRecorder) string {
+	var resp map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	_, claims, err := testJWTKeys.Parse(resp["token"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return claims["user_id"]
+}
+
+// TestOIDCLoginCreatesAndLinksUsers vérifie la création d'un compte à la première connexion,
+// sa reconnexion par le même sujet et la liaison à un compte existant par email vérifié.
+func TestOIDCLoginCreatesAndLinksUsers(t *testing.T) {
+	t.Parallel()
+	mock := newMockOIDC(t)
+	a := newOIDCTestApp(t, mock)
+
+	// L'URL d'autorisation porte le challenge PKCE, le cookie est limité au callback
+	authURL, cookie := startOIDCLogin(t, a.Router)
+	assert.Equal(t, mock.URL+"/authorize", authURL.Scheme+"://"+authURL.Host+authURL.Path)
+	assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+	assert.NotEmpty(t, authURL.Query().Get("code_challenge"))
+	assert.NotEmpty(t, authURL.Query().Get("nonce"))
+	assert.Equal(t, "/auth/mock/callback", cookie.Path)
+	assert.True(t, cookie.HttpOnly)
+
+	// Première connexion : un utilisateur est créé
+	mock.setUser(jwt.MapClaims{"sub": "alice-1", "email": "Alice@Example.com", "email_verified": true, "preferred_username": "alice"})
+	w := oidcLogin(t, a.Router)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	created := oidcUserID(t, w)
+
+	var user models.User
+	if err := a.DB.First(&user, created).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "alice", user.Username)
+	assert.Equal(t, "Alice@Example.com", user.Email)
+	assert.Empty(t, user.Password, "Un compte externe n'a pas de mot de passe")
+
+	// Le même sujet retrouve le même utilisateur, même si son email a changé
+	mock.setUser(jwt.MapClaims{"sub": "alice-1", "email": "alice@nouveau.example", "email_verified": true})
+	w = oidcLogin(t, a.Router)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, created, oidcUserID(t, w))
+
+	// Un utilisateur existant est lié par son email vérifié
+	existing, _ := createTestUserAndToken(t, a.DB)
+	mock.setUser(jwt.MapClaims{"sub": "bob-1", "email": existing.Email, "email_verified": "true", "preferred_username": "alice"})
+	w = oidcLogin(t, a.Router)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, strconv.Itoa(int(existing.ID)), oidcUserID(t, w))
+
+	var identities []models.UserIdentity
+	a.DB.Order("id").Find(&identities)
+	if assert.Len(t, identities, 2) {
+		assert.Equal(t, "mock", identities[1].Provider)
+		assert.Equal(t, existing.ID, identities[1].UserID)
+	}
+
+	// Un nom d'utilisateur déjà pris reçoit un suffixe
+	mock.setUser(jwt.MapClaims{"sub": "alice-2", "email": "autre-alice@example.com", "email_verified": true, "preferred_username": "alice"})
+	w = oidcLogin(t, a.Router)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var homonym models.User
+	if err := a.DB.First(&homonym, oidcUserID(t, w)).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "alice2", homonym.Username)
+}
+
+// TestOIDCLoginRejections vérifie les refus : email non vérifié, state ou cookie invalides,
+// code_verifier PKCE falsifié, ID token d'une autre connexion et fournisseur inconnu.
+func TestOIDCLoginRejections(t *testing.T) {
+	t.Parallel()
+	mock := newMockOIDC(t)
+	a := newOIDCTestApp(t, mock)
+
+	// Un email non vérifié ne permet ni de lier ni de créer un compte
+	mock.setUser(jwt.MapClaims{"sub": "eve", "email": "eve@example.com", "email_verified": false})
+	w := oidcLogin(t, a.Router)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var count int64
+	a.DB.Model(&models.User{}).Count(&count)
+	assert.Zero(t, count)
+
+	mock.setUser(jwt.MapClaims{"sub": "carol", "email": "carol@example.com", "email_verified": true})
+
+	// State différent de celui de la connexion en cours
+	authURL, cookie := startOIDCLogin(t, a.Router)
+	code, _ := authorizeOIDC(t, authURL)
+	w = completeOIDCLogin(a.Router, code, "state-forge", cookie)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Callback sans cookie de connexion, le cookie est effacé après usage
+	authURL, cookie = startOIDCLogin(t, a.Router)
+	code, state := authorizeOIDC(t, authURL)
+	w = completeOIDCLogin(a.Router, code, state, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	if cleared := w.Result().Cookies(); assert.Len(t, cleared, 1) {
+		assert.Equal(t, -1, cleared[0].MaxAge)
+	}
+
+	// Code_verifier PKCE falsifié : le fournisseur refuse l'échange du code
+	authURL, cookie = startOIDCLogin(t, a.Router)
+	code, state = authorizeOIDC(t, authURL)
+	w = completeOIDCLogin(a.Router, code, state, tamperOIDCCookie(t, cookie, func(login map[string]string) {
+		login["verifier"] = strings.Repeat("a", 43)
+	}))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// ID token émis pour une autre connexion : le nonce ne correspond pas
+	authURL, cookie = startOIDCLogin(t, a.Router)
+	code, state = authorizeOIDC(t, authURL)
+	w = completeOIDCLogin(a.Router, code, state, tamperOIDCCookie(t, cookie, func(login map[string]string) {
+		login["nonce"] = "autre-nonce"
+	}))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// ID token destiné à un autre client
+	mock.setUser(jwt.MapClaims{"sub": "carol", "email": "carol@example.com", "email_verified": true, "aud": "autre-client"})
+	w = oidcLogin(t, a.Router)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	a.DB.Model(&models.User{}).Count(&count)
+	assert.Zero(t, count)
+
+	req, _ := http.NewRequest("GET", "/auth/inconnu/login", nil)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestOIDCLoginRequiresMFA vérifie qu'une connexion par le fournisseur d'un compte lié
+// à un utilisateur avec la double authentification exige encore le code TOTP.
+func TestOIDCLoginRequiresMFA(t *testing.T) {
+	t.Parallel()
+	mock := newMockOIDC(t)
+	a := newOIDCTestApp(t, mock)
+	user, jwtToken := createTestUserAndToken(t, a.DB)
+	secret, _ := enableTOTP(t, a, jwtToken)
+
+	mock.setUser(jwt.MapClaims{"sub": "sso-1", "email": user.Email, "email_verified": true})
+	w := oidcLogin(t, a.Router)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, true, resp["mfa_required"])
+	_, hasToken := resp["token"]
+	assert.False(t, hasToken, "Aucun JWT ne doit être émis avant le second facteur")
+
+	var sessions int64
+	a.DB.Model(&models.Session{}).Where("user_id = ?", user.ID).Count(&sessions)
+	assert.Zero(t, sessions)
+
+	// Le mfa_token s'échange contre le JWT avec un code TOTP valide
+	code, err := utils.TOTPCode(secret, time.Now().Add(30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = apiTokenRequest(a.Router, "POST", "/login/mfa", "", gin.H{"mfa_token": resp["mfa_token"], "code": code})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, strconv.Itoa(int(user.ID)), oidcUserID(t, w))
+}
+
+// tamperOIDCCookie modifie la connexion en cours conservée dans le cookie
+func tamperOIDCCookie(t *testing.T, cookie *http.Cookie, edit func(login map[string]string)) *http.Cookie {
+	data, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	login := map[string]string{}
+	if err := json.Unmarshal(data, &login); err != nil {
+		t.Fatal(err)
+	}
+	edit(login)
+	data, _ = json.Marshal(login)
+	return &http.Cookie{Name: cookie.Name, Value: base64.RawURLEncoding.EncodeToString(data)}
+}
@@ -0,0 +1,420 @@
+// tests/openapi_test.go
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/routes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// openAPIContract vérifie les réponses réelles du routeur contre la spécification qu'il sert.
+// Le validateur couvre le sous-ensemble de JSON Schema utilisé par openapi.json.
+type openAPIContract struct {
+	t      *testing.T
+	router *gin.Engine
+	doc    map[string]interface{}
+	// base est l'URL du serveur déclarée par la spécification, préfixée aux chemins des requêtes
+	base string
+}
+
+func loadOpenAPIContract(t *testing.T, router *gin.Engine) *openAPIContract {
+	req, _ := http.NewRequest("GET", routes.V1Prefix+"/openapi.json", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json: %d", w.Code)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	servers, _ := doc["servers"].([]interface{})
+	if len(servers) == 0 {
+		t.Fatal("openapi.json: aucun serveur déclaré")
+	}
+	base, _ := servers[0].(map[string]interface{})["url"].(string)
+	return &openAPIContract{t: t, router: router, doc: doc, base: strings.TrimSuffix(base, "/")}
+}
+
+// operation retourne l'opération documentée pour une méthode et un chemin de la spécification
+func (s *openAPIContract) operation(method, template string) (map[string]interface{}, bool) {
+	paths, _ := s.doc["paths"].(map[string]interface{})
+	item, _ := paths[template].(map[string]interface{})
+	op, ok := item[strings.ToLower(method)].(map[string]interface{})
+	return op, ok
+}
+
+// resolve suit les $ref locaux, ex: #/components/schemas/Task
+func (s *openAPIContract) resolve(node interface{}) map[string]interface{} {
+	m, _ := node.(map[string]interface{})
+	for m != nil {
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			return m
+		}
+		var target interface{} = s.doc
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			target = target.(map[string]interface{})[part]
+		}
+		m, _ = target.(map[string]interface{})
+	}
+	return m
+}
+
+// request envoie une requête authentifiée puis vérifie sa réponse contre la spécification.
+// template est le chemin documenté, ex: /tasks/{id}
+func (s *openAPIContract) request(method, template, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	req, _ := http.NewRequest(method, s.base+path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return s.serve(req, template)
+}
+
+// upload envoie un fichier dans le champ file d'un formulaire multipart
+func (s *openAPIContract) upload(template, path, token, filename string, content []byte) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	part, _ := form.CreateFormFile("file", filename)
+	part.Write(content)
+	form.Close()
+	req, _ := http.NewRequest("POST", s.base+path, &buf)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return s.serve(req, template)
+}
+
+func (s *openAPIContract) serve(req *http.Request, template string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	name := fmt.Sprintf("%s %s -> %d", req.Method, template, w.Code)
+	op, ok := s.operation(req.Method, template)
+	if !assert.True(s.t, ok, "%s: opération non documentée", name) {
+		return w
+	}
+	responses, _ := op["responses"].(map[string]interface{})
+	response := s.resolve(responses[strconv.Itoa(w.Code)])
+	if !assert.NotNil(s.t, response, "%s: statut non documenté", name) {
+		return w
+	}
+	content, _ := response["content"].(map[string]interface{})
+	if len(content) == 0 {
+		return w
+	}
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	media, ok := content[mediaType].(map[string]interface{})
+	if !assert.True(s.t, ok, "%s: type %q non documenté", name, mediaType) || mediaType != "application/json" {
+		return w
+	}
+	var value interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &value); err != nil {
+		s.t.Errorf("%s: réponse JSON invalide: %v", name, err)
+		return w
+	}
+	errs := s.validate(media["schema"], value, "$")
+	assert.Empty(s.t, errs, "%s: %s", name, w.Body.String())
+	return w
+}
+
+// validate retourne les écarts entre value et schema, chacun préfixé par son chemin
+func (s *openAPIContract) validate(node interface{}, value interface{}, at string) []string {
+	schema := s.resolve(node)
+	if schema == nil {
+		return nil
+	}
+	var errs []string
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, at+": "+fmt.Sprintf(format, args...))
+	}
+
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			errs = append(errs, s.validate(sub, value, at)...)
+		}
+	}
+	if one, ok := schema["oneOf"].([]interface{}); ok {
+		matched := 0
+		for _, sub := range one {
+			if len(s.validate(sub, value, at)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			fail("%d schémas oneOf correspondent au lieu d'un", matched)
+		}
+	}
+	if c, ok := schema["const"]; ok && c != value {
+		fail("%v attendu, %v reçu", c, value)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || e == value
+		}
+		if !found {
+			fail("%v hors de l'énumération %v", value, enum)
+		}
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		actual := jsonType(value)
+		ok := false
+		for _, typ := range types {
+			ok = ok || typ == actual || (typ == "number" && actual == "integer")
+		}
+		if !ok {
+			fail("type %s attendu, %s reçu", strings.Join(types, "|"), actual)
+			return errs
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+				fail("date-time invalide %q", v)
+			}
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(v) {
+			fail("%q ne respecte pas %s", v, pattern)
+		}
+	case []interface{}:
+		for i, item := range v {
+			errs = append(errs, s.validate(schema["items"], item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := v[name.(string)]; !ok {
+					fail("propriété %q manquante", name)
+				}
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prop, ok := properties[name]; ok {
+				errs = append(errs, s.validate(prop, v[name], at+"."+name)...)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					fail("propriété %q non documentée", name)
+				}
+			case map[string]interface{}:
+				errs = append(errs, s.validate(extra, v[name], at+"."+name)...)
+			}
+		}
+	}
+	return errs
+}
+
+func schemaTypes(t interface{}) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, len(t))
+		for i, typ := range t {
+			types[i] = typ.(string)
+		}
+		return types
+	}
+	return nil
+}
+
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// TestOpenAPIDocumentation vérifie que la spécification et la page de documentation sont servies
+// et que chaque route d'authentification et de tâches de la v1 est documentée.
+func TestOpenAPIDocumentation(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	spec := loadOpenAPIContract(t, a.Router)
+	assert.Equal(t, "3.1.0", spec.doc["openapi"])
+	assert.Equal(t, routes.V1Prefix, spec.base)
+
+	req, _ := http.NewRequest("GET", routes.V1Prefix+"/docs", nil)
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `url: "openapi.json"`)
+
+	param := regexp.MustCompile(`:([a-z_]+)`)
+	for _, route := range a.Router.Routes() {
+		// Les routes sans préfixe sont des alias dépréciés des mêmes handlers
+		path, ok := strings.CutPrefix(route.Path, routes.V1Prefix)
+		if !ok {
+			continue
+		}
+		if path != "/register" && !strings.HasPrefix(path, "/login") && !strings.HasPrefix(path, "/tasks") {
+			continue
+		}
+		template := param.ReplaceAllString(path, "{$1}")
+		_, ok = spec.operation(route.Method, template)
+		assert.True(t, ok, "%s %s non documentée", route.Method, template)
+	}
+}
+
+// TestOpenAPIContract vérifie les réponses réelles des routes documentées contre leur schéma.
+func TestOpenAPIContract(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	spec := loadOpenAPIContract(t, a.Router)
+	user, token := createTestUserAndToken(t, a.DB)
+
+	// Authentification
+	w := spec.request("POST", "/register", "/register", "", map[string]string{"username": "nouveau", "email": "nouveau@example.com", "password": "secret"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = spec.request("POST", "/register", "/register", "", "pas un objet")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = spec.request("POST", "/login", "/login", "", map[string]string{"email": user.Email, "password": "password"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = spec.request("POST", "/login", "/login", "", map[string]string{"email": user.Email, "password": "mauvais"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = spec.request("POST", "/login/mfa", "/login/mfa", "", map[string]string{"mfa_token": "inconnu", "code": "123456"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Tâches
+	due := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+	w = spec.request("POST", "/tasks", "/tasks", token, map[string]interface{}{"title": "Écrire la doc", "description": "OpenAPI", "due_date": due})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Task struct {
+			ID uint `json:"id"`
+		} `json:"task"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	id := strconv.Itoa(int(created.Task.ID))
+
+	w = spec.request("GET", "/tasks", "/tasks", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = spec.request("GET", "/tasks", "/tasks", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = spec.request("GET", "/tasks/{id}", "/tasks/"+id, token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = spec.request("GET", "/tasks/{id}", "/tasks/9999", token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = spec.request("GET", "/tasks/{id}", "/tasks/abc", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = spec.request("PUT", "/tasks/{id}", "/tasks/"+id, token, map[string]string{"title": "Écrire la doc OpenAPI", "status": models.StatusInProgress})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	readOnly, err := testJWTKeys.GenerateWithScopes(strconv.Itoa(int(user.ID)), user.Email, []string{models.ScopeTasksRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = spec.request("POST", "/tasks", "/tasks", readOnly, map[string]string{"title": "Refusée"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = spec.request("POST", "/tasks/bulk", "/tasks/bulk", token, map[string]interface{}{
+		"mode":       "best_effort",
+		"operations": []map[string]interface{}{{"op": "create", "title": "Lot"}, {"op": "update", "id": 9999, "title": "Absente"}},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = spec.request("POST", "/tasks/bulk", "/tasks/bulk", token, map[string]interface{}{
+		"operations": []map[string]interface{}{{"op": "create", "title": "Lot"}, {"op": "delete", "id": 9999}},
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = spec.request("GET", "/tasks/search", "/tasks/search?q=doc", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = spec.request("GET", "/tasks/search", "/tasks/search", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = spec.request("GET", "/tasks/{id}/history", "/tasks/"+id+"/history", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = spec.request("GET", "/tasks/export", "/tasks/export", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = spec.request("GET", "/tasks/export", "/tasks/export?format=csv", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = spec.request("POST", "/tasks/import", "/tasks/import?format=json", token, []map[string]string{{"title": "Importée"}, {"description": "Sans titre"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Commentaires
+	w = spec.request("POST", "/tasks/{id}/comments", "/tasks/"+id+"/comments", token, map[string]string{"body": "Premier jet"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var comment struct {
+		Comment struct {
+			ID uint `json:"id"`
+		} `json:"comment"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &comment); err != nil {
+		t.Fatal(err)
+	}
+	commentPath := "/tasks/" + id + "/comments/" + strconv.Itoa(int(comment.Comment.ID))
+	w = spec.request("GET", "/tasks/{id}/comments", "/tasks/"+id+"/comments", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = spec.request("PUT", "/tasks/{id}/comments/{comment_id}", commentPath, token, map[string]string{"body": "Relu"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = spec.request("DELETE", "/tasks/{id}/comments/{comment_id}", commentPath, token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Pièces jointes
+	w = spec.upload("/tasks/{id}/attachments", "/tasks/"+id+"/attachments", token, "notes.txt", []byte("contenu"))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var attachment struct {
+		Attachment struct {
+			ID uint `json:"id"`
+		} `json:"attachment"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &attachment); err != nil {
+		t.Fatal(err)
+	}
+	w = spec.request("GET", "/tasks/{id}/attachments", "/tasks/"+id+"/attachments", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = spec.request("DELETE", "/tasks/{id}/attachments/{attachment_id}", "/tasks/"+id+"/attachments/"+strconv.Itoa(int(attachment.Attachment.ID)), token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = spec.request("DELETE", "/tasks/{id}", "/tasks/"+id, token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = spec.request("DELETE", "/tasks/{id}", "/tasks/"+id, token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,275 @@
+// tests/ratelimit_test.go
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"YoannLetacq/todo-api.git/config"
+	"YoannLetacq/todo-api.git/internal/app"
+	"YoannLetacq/todo-api.git/internal/models"
+	"YoannLetacq/todo-api.git/internal/ratelimit"
+	"YoannLetacq/todo-api.git/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRateLimitBucket vérifie le remplissage continu du seau et les délais retournés.
+func TestRateLimitBucket(t *testing.T) {
+	t.Parallel()
+	p := ratelimit.Policy{Name: "write", Limit: 2, Window: 10 * time.Second}
+	assert.Equal(t, "2;w=10", p.Header())
+
+	now := time.Now()
+	b := ratelimit.NewBucket(p, now)
+	res := b.Take(p, now)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+	assert.Equal(t, 5*time.Second, res.Reset)
+	res = b.Take(p, now)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	// Seau vide : un jeton revient toutes les 5 secondes
+	res = b.Take(p, now.Add(time.Second))
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 4*time.Second, res.RetryAfter)
+	res = b.Take(p, now.Add(5*time.Second))
+	assert.True(t, res.Allowed)
+	assert.Equal(t, now.Add(15*time.Second), b.FullAt(p))
+
+	// Le seau ne dépasse jamais sa capacité
+	res = b.Take(p, now.Add(time.Hour))
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+}
+
+// TestRateLimitStores vérifie que les stockages mémoire et SQL appliquent le même quota par clé.
+func TestRateLimitStores(t *testing.T) {
+	t.Parallel()
+	db, err := config.OpenTestDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := map[string]ratelimit.Store{"memory": ratelimit.NewMemoryStore(), "sql": ratelimit.NewSQLStore(db)}
+	p := ratelimit.Policy{Name: "read", Limit: 3, Window: time.Minute}
+	now := time.Now()
+
+	for name, store := range stores {
+		for i := 0; i < 3; i++ {
+			res, err := store.Take(context.Background(), "read:user:1", p, now)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			assert.True(t, res.Allowed, name)
+			assert.Equal(t, 2-i, res.Remaining, name)
+		}
+		res, err := store.Take(context.Background(), "read:user:1", p, now)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		assert.False(t, res.Allowed, name)
+		assert.Equal(t, 20*time.Second, res.RetryAfter, name)
+
+		// Les autres clés ont leur propre seau
+		res, err = store.Take(context.Background(), "read:user:2", p, now)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		assert.True(t, res.Allowed, name)
+
+		// Le temps remplit le seau
+		res, err = store.Take(context.Background(), "read:user:1", p, now.Add(20*time.Second))
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		assert.True(t, res.Allowed, name)
+	}
+
+	// Les seaux redevenus pleins sont supprimés de la base
+	if _, err := stores["sql"].Take(context.Background(), "read:user:3", p, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	if err := db.Table("rate_limit_buckets").Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1), count)
+}
+
+// rateLimitedApp construit une instance avec des quotas réduits sur le stockage donné
+func rateLimitedApp(t *testing.T, opts app.Options, store ratelimit.Store) *app.App {
+	opts.RateLimitStore = store
+	opts.RateLimits = ratelimit.Policies{
+		Auth:  ratelimit.Policy{Name: "auth", Limit: 2, Window: time.Minute},
+		Read:  ratelimit.Policy{Name: "read", Limit: 3, Window: time.Minute},
+		Write: ratelimit.Policy{Name: "write", Limit: 1, Window: time.Minute},
+	}
+	return newTestAppWith(t, opts)
+}
+
+// TestRateLimitMiddleware vérifie les en-têtes RateLimit-*, la réponse 429 et les seaux
+// par utilisateur ou par IP.
+func TestRateLimitMiddleware(t *testing.T) {
+	t.Parallel()
+	a := rateLimitedApp(t, app.Options{}, ratelimit.NewMemoryStore())
+	user, token := createTestUserAndToken(t, a.DB)
+
+	// Lecture : 3 requêtes par minute pour l'utilisateur
+	for i := 0; i < 3; i++ {
+		w := apiTokenRequest(a.Router, "GET", "/tasks", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "3", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, strconv.Itoa(2-i), w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "3;w=60", w.Header().Get("RateLimit-Policy"))
+	}
+	w := apiTokenRequest(a.Router, "GET", "/tasks", token, nil)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "20", w.Header().Get("Retry-After"))
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+	assert.Contains(t, w.Body.String(), "Trop de requêtes")
+
+	// L'écriture a son propre quota
+	body := map[string]string{"title": "Tâche"}
+	w = apiTokenRequest(a.Router, "POST", "/tasks", token, body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "1;w=60", w.Header().Get("RateLimit-Policy"))
+	w = apiTokenRequest(a.Router, "POST", "/tasks", token, body)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// Un autre utilisateur n'est pas concerné
+	other := models.User{Username: "autre", Email: "autre@example.com"}
+	if err := a.DB.Create(&other).Error; err != nil {
+		t.Fatal(err)
+	}
+	otherJWT, err := testJWTKeys.Generate(strconv.Itoa(int(other.ID)), other.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = apiTokenRequest(a.Router, "GET", "/tasks", otherJWT, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Connexion : 2 tentatives par minute et par IP, quel que soit le compte
+	login := func(ip, email string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"email": email, "password": "password"})
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = ip + ":41234"
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+		return w
+	}
+	assert.Equal(t, http.StatusOK, login("192.0.2.1", user.Email).Code)
+	assert.Equal(t, http.StatusUnauthorized, login("192.0.2.1", "inconnu@example.com").Code)
+	w = login("192.0.2.1", user.Email)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+	assert.Equal(t, http.StatusOK, login("192.0.2.2", user.Email).Code)
+}
+
+// TestRateLimitAuthByIP vérifie que le quota de connexion reste par IP quand la requête
+// porte un token valide : un token ne donne pas de seau séparé.
+func TestRateLimitAuthByIP(t *testing.T) {
+	t.Parallel()
+	a := rateLimitedApp(t, app.Options{}, ratelimit.NewMemoryStore())
+	user, token := createTestUserAndToken(t, a.DB)
+
+	login := func(token string) int {
+		body, _ := json.Marshal(map[string]string{"email": user.Email, "password": "password"})
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		req.RemoteAddr = "192.0.2.1:41234"
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, login(token))
+	assert.Equal(t, http.StatusOK, login(token))
+	assert.Equal(t, http.StatusTooManyRequests, login(token))
+	assert.Equal(t, http.StatusTooManyRequests, login(""), "Le quota de l'IP doit être épuisé, avec ou sans token")
+}
+
+// TestRateLimitForwardedFor vérifie que X-Forwarded-For n'est cru que d'un proxy de confiance :
+// un client qui change cet en-tête à chaque requête reste dans le seau de son IP.
+func TestRateLimitForwardedFor(t *testing.T) {
+	t.Parallel()
+	login := func(a *app.App, remoteIP, forwardedFor string) int {
+		body, _ := json.Marshal(map[string]string{"email": "inconnu@example.com", "password": "password"})
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.Header.Set("X-Real-IP", forwardedFor)
+		req.RemoteAddr = remoteIP + ":41234"
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Sans proxy de confiance, les en-têtes sont ignorés
+	a := rateLimitedApp(t, app.Options{}, ratelimit.NewMemoryStore())
+	assert.Equal(t, http.StatusUnauthorized, login(a, "192.0.2.1", "198.51.100.1"))
+	assert.Equal(t, http.StatusUnauthorized, login(a, "192.0.2.1", "198.51.100.2"))
+	assert.Equal(t, http.StatusTooManyRequests, login(a, "192.0.2.1", "198.51.100.3"))
+
+	// Derrière un proxy de confiance, chaque client a son seau
+	a = rateLimitedApp(t, app.Options{TrustedProxies: []string{"10.0.0.0/8"}}, ratelimit.NewMemoryStore())
+	assert.Equal(t, http.StatusUnauthorized, login(a, "10.0.0.5", "198.51.100.1"))
+	assert.Equal(t, http.StatusUnauthorized, login(a, "10.0.0.5", "198.51.100.1"))
+	assert.Equal(t, http.StatusTooManyRequests, login(a, "10.0.0.5", "198.51.100.1"))
+	assert.Equal(t, http.StatusUnauthorized, login(a, "10.0.0.5", "198.51.100.2"))
+	// Un client hors du réseau du proxy ne peut pas se faire passer pour un autre
+	assert.Equal(t, http.StatusUnauthorized, login(a, "192.0.2.1", "198.51.100.3"))
+	assert.Equal(t, http.StatusUnauthorized, login(a, "192.0.2.1", "198.51.100.4"))
+	assert.Equal(t, http.StatusTooManyRequests, login(a, "192.0.2.1", "198.51.100.5"))
+}
+
+// TestRateLimitSharedStore vérifie que deux instances sur la même base partagent les quotas.
+func TestRateLimitSharedStore(t *testing.T) {
+	t.Parallel()
+	first := rateLimitedApp(t, app.Options{}, nil)
+	_, token := createTestUserAndToken(t, first.DB)
+
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	instances := make([]*app.App, 2)
+	for i := range instances {
+		instances[i], err = app.New(first.DB, app.Options{
+			Blobs:          blobs,
+			JWTKeys:        testJWTKeys,
+			RateLimitStore: ratelimit.NewSQLStore(first.DB),
+			RateLimits:     ratelimit.Policies{Read: ratelimit.Policy{Name: "read", Limit: 3, Window: time.Minute}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Les requêtes alternent entre les instances, le quota est commun
+	for i := 0; i < 3; i++ {
+		w := apiTokenRequest(instances[i%2].Router, "GET", "/tasks", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, strconv.Itoa(2-i), w.Header().Get("RateLimit-Remaining"))
+	}
+	for _, instance := range instances {
+		w := apiTokenRequest(instance.Router, "GET", "/tasks", token, nil)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	}
+
+	// Sans stockage, aucune limite n'est appliquée
+	for i := 0; i < 5; i++ {
+		w := apiTokenRequest(first.Router, "GET", "/tasks", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	}
+}
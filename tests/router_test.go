@@ -3,53 +3,60 @@ package tests
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"strconv"
 	"testing"
 
 	"YoannLetacq/todo-api.git/config"
-	"YoannLetacq/todo-api.git/internal/handlers"
+	"YoannLetacq/todo-api.git/internal/app"
 	"YoannLetacq/todo-api.git/internal/models"
-	"YoannLetacq/todo-api.git/internal/repository"
-	"YoannLetacq/todo-api.git/internal/services"
+	"YoannLetacq/todo-api.git/internal/storage"
 	"YoannLetacq/todo-api.git/internal/utils"
-	"YoannLetacq/todo-api.git/routes"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
-// setRouterTestDB initialise la base de données pour les tests.
-func setRouterTestDB() {
-	config.InitDB(true)
-	config.DB.Exec("DELETE FROM users")
-	config.DB.Exec("DELETE FROM tasks")
-	config.DB.AutoMigrate(&models.User{}, &models.Task{})
-}
-
-// initRouterTest initialise les services (User et Task), les injecte dans les handlers,
-// et retourne l'instance du router.
-func initRouterTest() *gin.Engine {
-	// Service User
-	userRepo := repository.NewUserRepository()
-	userSvc := services.NewUserService(userRepo)
-	handlers.InitUserHandlers(userSvc)
+// testJWTKeys signe et vérifie les tokens de toutes les instances de test
+var testJWTKeys, _ = utils.NewJWTKeys(utils.JWTKey{ID: "test", Secret: []byte("my_secret_key")})
 
-	// Service Task
-	taskRepo := repository.NewTaskRepository()
-	taskSvc := services.NewTaskService(taskRepo)
-	handlers.InitTaskHandlers(taskSvc)
+// newTestApp construit une instance isolée de l'API, avec sa propre base en mémoire
+// et un stockage de pièces jointes temporaire.
+func newTestApp(t *testing.T) *app.App {
+	return newTestAppWith(t, app.Options{})
+}
 
-	return routes.SetupRouter()
+// newTestAppWith construit une instance isolée de l'API avec des options spécifiques.
+func newTestAppWith(t *testing.T, opts app.Options) *app.App {
+	db, err := config.OpenTestDB()
+	if err != nil {
+		t.Fatal("Erreur lors de l'ouverture de la base de test:", err)
+	}
+	if opts.Blobs == nil {
+		opts.Blobs, err = storage.NewLocalStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if opts.JWTKeys == nil {
+		opts.JWTKeys = testJWTKeys
+	}
+	a, err := app.New(db, opts)
+	if err != nil {
+		t.Fatal("Erreur lors de la création de l'application:", err)
+	}
+	return a
 }
 
 // createTestUserAndToken crée un utilisateur dans la base de test et retourne l'utilisateur ainsi qu'un token JWT valide.
-func createTestUserAndToken(t *testing.T) (models.User, string) {
-	os.Setenv("JWT_SECRET", "my_secret_key")
+func createTestUserAndToken(t *testing.T, db *gorm.DB) (models.User, string) {
 	password := "password"
 	hashedPass, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -61,11 +68,11 @@ func createTestUserAndToken(t *testing.T) (models.User, string) {
 		Email:    "testUser@example.com",
 		Password: string(hashedPass),
 	}
-	if err := config.DB.Create(&user).Error; err != nil {
+	if err := db.Create(&user).Error; err != nil {
 		t.Fatal("Erreur lors de la création de l'utilisateur:", err)
 	}
 
-	token, err := utils.GenerateJWT(strconv.Itoa(int(user.ID)), user.Email)
+	token, err := testJWTKeys.Generate(strconv.Itoa(int(user.ID)), user.Email)
 	if err != nil {
 		t.Fatal("Erreur lors de la génération du token JWT:", err)
 	}
@@ -74,9 +81,9 @@ func createTestUserAndToken(t *testing.T) (models.User, string) {
 
 // TestRouterRegisterAndLogin teste les endpoints d'inscription et de connexion.
 func TestRouterRegisterAndLogin(t *testing.T) {
-	os.Setenv("JWT_SECRET", "my_secret_key")
-	setRouterTestDB()
-	router := initRouterTest()
+	t.Parallel()
+	a := newTestApp(t)
+	router := a.Router
 
 	// --- Inscription ---
 	registerData := map[string]string{
@@ -114,12 +121,12 @@ func TestRouterRegisterAndLogin(t *testing.T) {
 
 // TestRouterTasksEndpoints teste les endpoints liés aux tâches (CRUD).
 func TestRouterTasksEndpoints(t *testing.T) {
-	os.Setenv("JWT_SECRET", "my_secret_key")
-	setRouterTestDB()
-	router := initRouterTest()
+	t.Parallel()
+	a := newTestApp(t)
+	router := a.Router
 
 	// Création d'un utilisateur et génération de token.
-	_, token := createTestUserAndToken(t)
+	_, token := createTestUserAndToken(t, a.DB)
 
 	// --- Création de tâche ---
 	taskData := map[string]string{
@@ -156,7 +163,7 @@ func TestRouterTasksEndpoints(t *testing.T) {
 
 	// --- Récupération d'une tâche par son ID ---
 	createdTask := createResp["task"].(map[string]interface{})
-	taskID := strconv.Itoa(int(createdTask["ID"].(float64)))
+	taskID := strconv.Itoa(int(createdTask["id"].(float64)))
 	req, _ = http.NewRequest("GET", "/tasks/"+taskID, nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	w = httptest.NewRecorder()
@@ -203,3 +210,65 @@ func TestRouterTasksEndpoints(t *testing.T) {
 	}
 	assert.Equal(t, "Task supprimée.", deleteResp["message"])
 }
+
+// TestRequestLogRedactsTokens vérifie que les tokens passés dans l'URL n'apparaissent pas dans les logs.
+// Ce test n'est pas parallèle car il remplace la sortie globale des logs gin.
+func TestRequestLogRedactsTokens(t *testing.T) {
+	var logs bytes.Buffer
+	previous := gin.DefaultWriter
+	gin.DefaultWriter = &logs
+	defer func() { gin.DefaultWriter = previous }()
+
+	a := newTestApp(t)
+	_, token := createTestUserAndToken(t, a.DB)
+
+	for _, path := range []string{"/ws?token=" + token + "&room=list", "/calendar/feed/jeton-calendrier-secret"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		a.Router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Contains(t, logs.String(), "/ws?room=list&token=%2A%2A%2A")
+	assert.Contains(t, logs.String(), "/calendar/feed/***")
+	assert.NotContains(t, logs.String(), token)
+	assert.NotContains(t, logs.String(), "jeton-calendrier-secret")
+}
+
+// TestJWKSEndpoint vérifie la publication des clés publiques et l'authentification par un token EdDSA.
+func TestJWKSEndpoint(t *testing.T) {
+	t.Parallel()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := utils.NewJWTKeys(utils.JWTKey{ID: "ed-1", Algorithm: utils.AlgEdDSA, PrivateKey: priv, PublicKey: pub},
+		utils.JWTKey{ID: "hs-1", Secret: []byte("ancien-secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newTestAppWith(t, app.Options{JWTKeys: keys})
+
+	req, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "public, max-age=300", w.Header().Get("Cache-Control"))
+
+	var set utils.JWKSet
+	if err := json.Unmarshal(w.Body.Bytes(), &set); err != nil {
+		t.Fatal(err)
+	}
+	// Le secret HS256 n'est pas publié
+	assert.Equal(t, []utils.JWK{{Kty: "OKP", Kid: "ed-1", Use: "sig", Alg: "EdDSA", Crv: "Ed25519",
+		X: base64.RawURLEncoding.EncodeToString(pub)}}, set.Keys)
+
+	// Un token signé par la clé active est accepté par l'API
+	token, err := keys.Generate("1", "test@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ = http.NewRequest("GET", "/tasks", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
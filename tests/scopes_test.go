@@ -0,0 +1,111 @@
+// tests/scopes_test.go
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"YoannLetacq/todo-api.git/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestJWTScopeClaim vérifie que le claim scope est signé dans le token et restitué par Parse.
+func TestJWTScopeClaim(t *testing.T) {
+	t.Parallel()
+	token, err := testJWTKeys.GenerateWithScopes("1", "a@example.com", []string{models.ScopeTasksRead, models.ScopeAdmin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, claims, err := testJWTKeys.Parse(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "tasks:read admin", claims["scope"])
+
+	token, err = testJWTKeys.Generate("1", "a@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, claims, err = testJWTKeys.Parse(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, present := claims["scope"]
+	assert.False(t, present)
+
+	assert.True(t, models.HasScope([]string{models.ScopeAdmin}, models.ScopeTasksDelete))
+	assert.False(t, models.HasScope([]string{models.ScopeTasksWrite}, models.ScopeTasksDelete))
+}
+
+// TestScopedAuthorization vérifie les scopes exigés par route pour les JWT et les tokens d'accès.
+func TestScopedAuthorization(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	user, jwt := createTestUserAndToken(t, a.DB)
+	uid := strconv.Itoa(int(user.ID))
+
+	w := apiTokenRequest(a.Router, "POST", "/tasks", jwt, gin.H{"title": "Tâche"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Task struct {
+			ID uint `json:"id"`
+		} `json:"task"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	taskPath := "/tasks/" + strconv.Itoa(int(created.Task.ID))
+
+	// Un JWT en lecture seule
+	readOnly, err := testJWTKeys.GenerateWithScopes(uid, user.Email, []string{models.ScopeTasksRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = apiTokenRequest(a.Router, "GET", taskPath, readOnly, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = apiTokenRequest(a.Router, "PUT", taskPath, readOnly, gin.H{"title": "Modifiée"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var denied map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &denied); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "insufficient_scope", denied["error"])
+	assert.Equal(t, "tasks:write", denied["required_scope"])
+
+	// Un token d'accès ne peut pas dépasser les scopes de la connexion qui le crée
+	w = apiTokenRequest(a.Router, "POST", "/me/tokens", readOnly, gin.H{"name": "trop", "scopes": []string{"tasks:write"}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = apiTokenRequest(a.Router, "POST", "/me/tokens", jwt, gin.H{"name": "admin", "scopes": []string{"admin"}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// La suppression exige tasks:delete, y compris dans un lot
+	w = apiTokenRequest(a.Router, "POST", "/me/tokens", jwt, gin.H{"name": "ci", "scopes": []string{"tasks:read", "tasks:write"}})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var pat createdAPIToken
+	if err := json.Unmarshal(w.Body.Bytes(), &pat); err != nil {
+		t.Fatal(err)
+	}
+	w = apiTokenRequest(a.Router, "PUT", taskPath, pat.Token, gin.H{"title": "Modifiée"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = apiTokenRequest(a.Router, "DELETE", taskPath, pat.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "tasks:delete")
+	w = apiTokenRequest(a.Router, "POST", "/tasks/bulk", pat.Token, gin.H{"operations": []gin.H{{"op": "delete", "id": created.Task.ID}}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// admin accorde tous les scopes
+	admin, err := testJWTKeys.GenerateWithScopes(uid, user.Email, []string{models.ScopeAdmin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = apiTokenRequest(a.Router, "DELETE", taskPath, admin, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Sans token, la route répond toujours 401 et non 403
+	w = apiTokenRequest(a.Router, "GET", "/tasks", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,62 @@
+// tests/search_test.go
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"YoannLetacq/todo-api.git/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// searchTasks appelle GET /tasks/search et retourne les résultats.
+func searchTasks(t *testing.T, router http.Handler, token, query string) []interface{} {
+	req, _ := http.NewRequest("GET", "/tasks/search?q="+url.QueryEscape(query), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal("Erreur de parsing:", err)
+	}
+	return resp["results"].([]interface{})
+}
+
+// TestSearchTasks vérifie la recherche par préfixe, le classement et le surlignage.
+func TestSearchTasks(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+
+	user, token := createTestUserAndToken(t, a.DB)
+	a.DB.Create(&models.Task{Title: "Appeler le <plombier>", Description: "fuite cuisine", UserID: user.ID})
+	a.DB.Create(&models.Task{Title: "Courses", Description: "penser à appeler maman", UserID: user.ID})
+	a.DB.Create(&models.Task{Title: "Rien à voir", UserID: user.ID})
+	a.DB.Create(&models.Task{Title: "Appeler", UserID: user.ID + 1})
+
+	results := searchTasks(t, a.Router, token, "appel")
+	assert.Len(t, results, 2)
+	first := results[0].(map[string]interface{})
+	assert.Equal(t, "Appeler le <plombier>", first["task"].(map[string]interface{})["title"])
+	assert.Contains(t, first["snippet"], "<mark>Appeler</mark>")
+	assert.Contains(t, first["snippet"], "&lt;plombier&gt;")
+
+	// Plusieurs termes : tous doivent être présents
+	results = searchTasks(t, a.Router, token, "appel plomb")
+	assert.Len(t, results, 1)
+
+	// L'index suit les modifications
+	var task models.Task
+	a.DB.Where("title = ?", "Rien à voir").First(&task)
+	task.Title = "Appeler la banque"
+	a.DB.Save(&task)
+	assert.Len(t, searchTasks(t, a.Router, token, "banque"), 1)
+
+	a.DB.Delete(&task)
+	assert.Len(t, searchTasks(t, a.Router, token, "banque"), 0)
+}
@@ -0,0 +1,194 @@
+// tests/sessions_test.go
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/app"
+	"YoannLetacq/todo-api.git/internal/models"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// loginFrom se connecte depuis un appareil identifié par son user agent et retourne le JWT
+func loginFrom(t *testing.T, a *app.App, userAgent string) string {
+	body, _ := json.Marshal(map[string]string{"email": "testUser@example.com", "password": "password"})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.RemoteAddr = "192.0.2.1:41234"
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp["token"]
+}
+
+type sessionList struct {
+	Sessions []struct {
+		ID         uint      `json:"id"`
+		UserAgent  string    `json:"user_agent"`
+		IP         string    `json:"ip"`
+		CreatedAt  time.Time `json:"created_at"`
+		LastSeenAt time.Time `json:"last_seen_at"`
+		Current    bool      `json:"current"`
+	} `json:"sessions"`
+}
+
+func listSessions(t *testing.T, a *app.App, token string) sessionList {
+	w := apiTokenRequest(a.Router, "GET", "/me/sessions", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list sessionList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+// TestSessions vérifie la liste des appareils connectés et la révocation des sessions.
+func TestSessions(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	createTestUserAndToken(t, a.DB)
+
+	laptop := loginFrom(t, a, "Firefox/128.0")
+	phone := loginFrom(t, a, "TodoApp iOS/2.1")
+
+	// Le JWT est lié à sa session par le claim sid
+	_, claims, err := testJWTKeys.Parse(laptop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, claims["sid"])
+
+	list := listSessions(t, a, laptop)
+	if !assert.Len(t, list.Sessions, 2) {
+		return
+	}
+	byAgent := map[string]uint{}
+	for _, s := range list.Sessions {
+		byAgent[s.UserAgent] = s.ID
+		assert.Equal(t, s.UserAgent == "Firefox/128.0", s.Current)
+		assert.Equal(t, "192.0.2.1", s.IP)
+		assert.WithinDuration(t, time.Now(), s.CreatedAt, time.Minute)
+	}
+	assert.Equal(t, claims["sid"], strconv.Itoa(int(byAgent["Firefox/128.0"])))
+
+	// L'activité d'une session met à jour last_seen_at, au plus une fois par minute
+	old := time.Now().Add(-time.Hour)
+	if err := a.DB.Model(&models.Session{}).Where("id = ?", byAgent["TodoApp iOS/2.1"]).Update("last_seen_at", old).Error; err != nil {
+		t.Fatal(err)
+	}
+	w := apiTokenRequest(a.Router, "GET", "/tasks", phone, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var phoneSession models.Session
+	if err := a.DB.First(&phoneSession, byAgent["TodoApp iOS/2.1"]).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.WithinDuration(t, time.Now(), phoneSession.LastSeenAt, time.Minute)
+
+	// Un autre utilisateur ne peut pas révoquer la session
+	other := models.User{Username: "autre", Email: "autre@example.com"}
+	if err := a.DB.Create(&other).Error; err != nil {
+		t.Fatal(err)
+	}
+	otherJWT, err := testJWTKeys.Generate(strconv.Itoa(int(other.ID)), other.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = apiTokenRequest(a.Router, "DELETE", "/me/sessions/"+strconv.Itoa(int(byAgent["TodoApp iOS/2.1"])), otherJWT, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Révoquer un appareil refuse aussitôt son token, sans toucher aux autres
+	w = apiTokenRequest(a.Router, "DELETE", "/me/sessions/"+strconv.Itoa(int(byAgent["TodoApp iOS/2.1"])), laptop, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = apiTokenRequest(a.Router, "GET", "/tasks", phone, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = apiTokenRequest(a.Router, "GET", "/tasks", laptop, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Révoquer les autres sessions garde la session courante
+	tablet := loginFrom(t, a, "Safari/17.0")
+	desktop := loginFrom(t, a, "Chrome/126.0")
+	w = apiTokenRequest(a.Router, "DELETE", "/me/sessions", laptop, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"revoked":2`)
+	for _, token := range []string{tablet, desktop} {
+		w = apiTokenRequest(a.Router, "GET", "/tasks", token, nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+	list = listSessions(t, a, laptop)
+	if assert.Len(t, list.Sessions, 1) {
+		assert.True(t, list.Sessions[0].Current)
+	}
+
+	// Révoquer sa propre session équivaut à une déconnexion
+	w = apiTokenRequest(a.Router, "DELETE", "/me/sessions/"+strconv.Itoa(int(byAgent["Firefox/128.0"])), laptop, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = apiTokenRequest(a.Router, "GET", "/me/sessions", laptop, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// TestSessionRevocationClosesWebSockets vérifie que révoquer une session ferme ses websockets ouvertes.
+func TestSessionRevocationClosesWebSockets(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	createTestUserAndToken(t, a.DB)
+
+	server := httptest.NewServer(a.Router)
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?token="
+
+	dial := func(token string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL+token, nil)
+		if err != nil {
+			t.Fatal("Erreur de connexion websocket:", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+	// expectClosed attend la fermeture de la websocket pour session révoquée
+	expectClosed := func(conn *websocket.Conn) {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, _, err := conn.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), "fermeture attendue, reçu: %v", err)
+	}
+	// expectOpen vérifie que la websocket répond encore
+	expectOpen := func(conn *websocket.Conn) {
+		conn.WriteJSON(map[string]string{"action": "inconnue"})
+		assert.Equal(t, "error", readEvent(t, conn).Type)
+	}
+
+	laptop := loginFrom(t, a, "Firefox/128.0")
+	phone := loginFrom(t, a, "TodoApp iOS/2.1")
+	_, claims, err := testJWTKeys.Parse(phone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	laptopWS := dial(laptop)
+	phoneWS := dial(phone)
+	w := apiTokenRequest(a.Router, "DELETE", "/me/sessions/"+claims["sid"], laptop, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	expectClosed(phoneWS)
+	expectOpen(laptopWS)
+
+	// Révoquer les autres sessions ferme leurs websockets, pas celles de la session courante
+	tablet := loginFrom(t, a, "Safari/17.0")
+	tabletWS := dial(tablet)
+	w = apiTokenRequest(a.Router, "DELETE", "/me/sessions", laptop, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	expectClosed(tabletWS)
+	expectOpen(laptopWS)
+}
@@ -0,0 +1,55 @@
+// tests/shutdown_test.go
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAppShutdown vérifie que l'arrêt ferme les websockets ouvertes puis la base.
+func TestAppShutdown(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+
+	_, token := createTestUserAndToken(t, a.DB)
+
+	server := httptest.NewServer(a.Router)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?token=" + token
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatal("Erreur de connexion websocket:", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := a.Shutdown(ctx); err != nil {
+		t.Fatal("Erreur lors de l'arrêt:", err)
+	}
+
+	// Le client reçoit une fermeture "going away"
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "fermeture inattendue: %v", err)
+
+	// Les nouvelles websockets sont refusées
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	// Le pool de connexions est fermé
+	sqlDB, err := a.DB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, sqlDB.Ping())
+}
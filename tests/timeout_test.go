@@ -0,0 +1,45 @@
+// tests/timeout_test.go
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/app"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRequestDeadline vérifie qu'une requête qui dépasse son délai répond 504 et non 500.
+func TestRequestDeadline(t *testing.T) {
+	t.Parallel()
+	a := newTestAppWith(t, app.Options{RequestTimeout: time.Nanosecond})
+
+	_, token := createTestUserAndToken(t, a.DB)
+
+	req, _ := http.NewRequest("GET", "/tasks", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+}
+
+// TestRequestCanceled vérifie qu'une requête abandonnée par le client n'est pas traitée comme une erreur serveur.
+func TestRequestCanceled(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+
+	_, token := createTestUserAndToken(t, a.DB)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/tasks", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	assert.Equal(t, 499, w.Code)
+	assert.Empty(t, w.Body.String())
+}
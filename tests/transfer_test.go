@@ -0,0 +1,127 @@
+// tests/transfer_test.go
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"YoannLetacq/todo-api.git/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExportImportTasks vérifie l'export puis le ré-import des tâches dans chaque format.
+func TestExportImportTasks(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	router := a.Router
+
+	user, token := createTestUserAndToken(t, a.DB)
+	a.DB.Create(&models.Task{Title: "Export, CSV", Description: "Ligne 1\nLigne 2", Status: "done", UserID: user.ID})
+	a.DB.Create(&models.Task{Title: "Export 2", Status: "in progress", UserID: user.ID})
+
+	for _, format := range []string{"csv", "json", "ics"} {
+		req, _ := http.NewRequest("GET", "/tasks/export?format="+format, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, format)
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "tasks."+format)
+		exported := w.Body.Bytes()
+
+		req, _ = http.NewRequest("POST", "/tasks/import?dry_run=true&format="+format, bytes.NewReader(exported))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, format)
+
+		var resp map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal("Erreur de parsing:", err)
+		}
+		assert.Equal(t, float64(2), resp["valid"], format)
+		assert.Equal(t, float64(0), resp["imported"], format)
+	}
+
+	var count int64
+	a.DB.Model(&models.Task{}).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(t, int64(2), count, "Le dry-run ne doit rien écrire")
+}
+
+// TestImportTasksCSVMapping vérifie le mapping de colonnes et les erreurs par ligne.
+func TestImportTasksCSVMapping(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	router := a.Router
+
+	user, token := createTestUserAndToken(t, a.DB)
+
+	csvData := strings.Join([]string{
+		"Nom,Notes,Etat",
+		"Acheter du pain,boulangerie,todo",
+		",sans titre,todo",
+		"Payer le loyer,,archived",
+		"Réviser,,done",
+	}, "\n")
+
+	req, _ := http.NewRequest("POST", "/tasks/import?format=csv&mapping=title=Nom,description=Notes,status=Etat", strings.NewReader(csvData))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal("Erreur de parsing:", err)
+	}
+	assert.Equal(t, float64(4), resp["total"])
+	assert.Equal(t, float64(2), resp["imported"])
+
+	rowErrors := resp["errors"].([]interface{})
+	assert.Len(t, rowErrors, 2)
+	assert.Equal(t, float64(3), rowErrors[0].(map[string]interface{})["row"])
+	assert.Equal(t, float64(4), rowErrors[1].(map[string]interface{})["row"])
+
+	var tasks []models.Task
+	a.DB.Where("user_id = ?", user.ID).Order("id").Find(&tasks)
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, "Acheter du pain", tasks[0].Title)
+	assert.Equal(t, "boulangerie", tasks[0].Description)
+}
+
+// TestImportTooLarge vérifie qu'un fichier d'import trop volumineux est refusé en 413, envoyé brut ou en multipart.
+func TestImportTooLarge(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	_, token := createTestUserAndToken(t, a.DB)
+
+	data := "title\n" + strings.Repeat("x", 6<<20)
+
+	req, _ := http.NewRequest("POST", "/tasks/import?format=csv", strings.NewReader(data))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", "taches.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(data))
+	mw.Close()
+
+	req, _ = http.NewRequest("POST", "/tasks/import", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "Fichier trop volumineux")
+}
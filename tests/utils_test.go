@@ -2,26 +2,32 @@ package tests
 
 import (
 	"YoannLetacq/todo-api.git/internal/utils"
-	"log"
-	"os"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGenerateJWT(t *testing.T) {
-	os.Setenv("JWT_SECRET", "my_secret_key")
-
 	userID := "1" // user_id est stocké en string
 	email := "test@example.com"
 
-	token, err := utils.GenerateJWT(userID, email)
+	token, err := testJWTKeys.Generate(userID, email)
 	assert.NoError(t, err, "Erreur lors de la génération du token")
 	assert.NotEmpty(t, token, "Le token généré est vide")
 
-	parsedToken, claims, err := utils.ParseToken(token)
+	parsedToken, claims, err := testJWTKeys.Parse(token)
 	assert.NoError(t, err, "Erreur lors du parsing du token")
 	assert.True(t, parsedToken.Valid, "Le token généré n'est pas valide")
+	assert.Equal(t, "test", parsedToken.Header["kid"], "Le kid de la clé active est absent")
 
 	// Vérifier les claims
 	assert.Equal(t, userID, claims["user_id"], "L'ID utilisateur du token est invalide")
@@ -29,16 +35,13 @@ func TestGenerateJWT(t *testing.T) {
 }
 
 func TestParseToken(t *testing.T) {
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	os.Setenv("JWT_SECRET", "my_secret_key")
-
 	userID := "1"
 	email := "test@example.com"
 
-	tokenString, err := utils.GenerateJWT(userID, email)
+	tokenString, err := testJWTKeys.Generate(userID, email)
 	assert.NoError(t, err, "Erreur lors de la génération du token.")
 
-	parsedToken, claims, err := utils.ParseToken(tokenString)
+	parsedToken, claims, err := testJWTKeys.Parse(tokenString)
 	assert.NoError(t, err, "Erreur lors du parsing du token JWT")
 	assert.True(t, parsedToken.Valid, "Le token JWT n'est pas valide")
 
@@ -48,11 +51,197 @@ func TestParseToken(t *testing.T) {
 
 	// Test avec un token invalide
 	invalidToken := "invalid.token.string"
-	_, _, err = utils.ParseToken(invalidToken)
+	_, _, err = testJWTKeys.Parse(invalidToken)
 	assert.Error(t, err, "Le parsing d'un token invalide aurait dû échouer")
 
-	// Test avec un token signé avec une autre clé secrète
-	os.Setenv("JWT_SECRET", "wrong_secret_key")
-	_, _, err = utils.ParseToken(tokenString)
+	// Test avec un token signé avec une autre clé secrète sous le même kid
+	other, _ := utils.NewJWTKeys(utils.JWTKey{ID: "test", Secret: []byte("wrong_secret_key")})
+	_, _, err = other.Parse(tokenString)
 	assert.Error(t, err, "Le parsing aurait dû échouer avec une clé invalide")
+
+	// Un token non signé (alg none) est refusé
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"user_id": userID, "email": email})
+	noneToken, _ := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	_, _, err = testJWTKeys.Parse(noneToken)
+	assert.Error(t, err, "Un token non signé aurait dû être refusé")
+}
+
+// TestJWTKeyRotation vérifie qu'une rotation de clé ne déconnecte pas les utilisateurs.
+func TestJWTKeyRotation(t *testing.T) {
+	oldKey := utils.JWTKey{ID: "2025-01", Secret: []byte("ancienne-cle-de-signature-32-octets!")}
+	newKey := utils.JWTKey{ID: "2025-06", Secret: []byte("nouvelle-cle-de-signature-32-octets!")}
+
+	before, err := utils.NewJWTKeys(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, _ := before.Generate("1", "test@example.com")
+
+	// La nouvelle clé signe, l'ancienne reste acceptée en vérification
+	after, err := utils.NewJWTKeys(newKey, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, claims, err := after.Parse(oldToken)
+	assert.NoError(t, err, "Un token signé par l'ancienne clé doit rester valide")
+	assert.Equal(t, "1", claims["user_id"])
+
+	newToken, _ := after.Generate("1", "test@example.com")
+	parsed, _, err := after.Parse(newToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-06", parsed.Header["kid"])
+
+	// Une fois l'ancienne clé retirée, ses tokens sont refusés
+	retired, _ := utils.NewJWTKeys(newKey)
+	_, _, err = retired.Parse(oldToken)
+	assert.Error(t, err, "Un token signé par une clé retirée aurait dû être refusé")
+
+	// Un token sans kid, émis avant la rotation, est vérifié avec la clé active
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": "1", "email": "test@example.com"})
+	legacyToken, _ := legacy.SignedString(newKey.Secret)
+	_, _, err = after.Parse(legacyToken)
+	assert.NoError(t, err)
+
+	// Deux clés avec le même kid sont refusées
+	_, err = utils.NewJWTKeys(newKey, utils.JWTKey{ID: "2025-06", Secret: []byte("autre")})
+	assert.Error(t, err)
+}
+
+// pemKey encode une clé privée en PKCS#8 et sa clé publique en PKIX
+func pemKey(t *testing.T, priv interface{}, pub interface{}) (privPEM, pubPEM []byte) {
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+}
+
+// TestAsymmetricJWTKeys vérifie la signature RS256 et EdDSA avec des clés PEM et leur publication JWKS.
+func TestAsymmetricJWTKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPriv, rsaPub := pemKey(t, rsaKey, &rsaKey.PublicKey)
+	edPrivPEM, edPubPEM := pemKey(t, edPriv, edPub)
+
+	rsaActive, err := utils.ParseJWTKeyPEM("rsa-1", rsaPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, utils.AlgRS256, rsaActive.Algorithm)
+	edActive, err := utils.ParseJWTKeyPEM("ed-1", edPrivPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, utils.AlgEdDSA, edActive.Algorithm)
+	edVerify, err := utils.ParseJWTKeyPEM("ed-1", edPubPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, utils.AlgEdDSA, edVerify.Algorithm)
+	assert.Nil(t, edVerify.PrivateKey, "Une clé publique ne doit pas pouvoir signer")
+
+	for _, active := range []utils.JWTKey{rsaActive, edActive} {
+		keys, err := utils.NewJWTKeys(active)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := keys.Generate("1", "test@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, claims, err := keys.Parse(token)
+		assert.NoError(t, err)
+		assert.Equal(t, active.Algorithm, parsed.Method.Alg())
+		assert.Equal(t, active.ID, parsed.Header["kid"])
+		assert.Equal(t, "1", claims["user_id"])
+	}
+
+	// Passage de RS256 à EdDSA : l'ancienne clé publique RSA suffit pour vérifier ses tokens
+	oldKeys, _ := utils.NewJWTKeys(rsaActive)
+	oldToken, _ := oldKeys.Generate("1", "test@example.com")
+	rsaVerify, err := utils.ParseJWTKeyPEM("rsa-1", rsaPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := utils.NewJWTKeys(edActive, rsaVerify, utils.JWTKey{ID: "hs-1", Secret: []byte("ancien-secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = keys.Parse(oldToken)
+	assert.NoError(t, err, "Un token signé par l'ancienne clé RSA doit rester valide")
+
+	// Une clé publique seule ne peut pas être la clé active
+	_, err = utils.NewJWTKeys(rsaVerify)
+	assert.Error(t, err)
+
+	// Confusion d'algorithme : un token HS256 signé avec la clé publique PEM sous le kid RSA est refusé
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": "1", "email": "test@example.com"})
+	forged.Header["kid"] = "rsa-1"
+	forgedToken, _ := forged.SignedString(rsaPub)
+	_, _, err = keys.Parse(forgedToken)
+	assert.Error(t, err, "Un token HS256 présenté sous un kid RSA aurait dû être refusé")
+
+	// Le JWKS publie les clés publiques dans l'ordre, jamais les secrets HS256
+	set := keys.JWKS()
+	if assert.Len(t, set.Keys, 2) {
+		assert.Equal(t, utils.JWK{Kty: "OKP", Kid: "ed-1", Use: "sig", Alg: "EdDSA", Crv: "Ed25519",
+			X: base64.RawURLEncoding.EncodeToString(edPub)}, set.Keys[0])
+		assert.Equal(t, "RSA", set.Keys[1].Kty)
+		assert.Equal(t, "rsa-1", set.Keys[1].Kid)
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()), set.Keys[1].N)
+		assert.Equal(t, "AQAB", set.Keys[1].E)
+	}
+
+	// Une clé RSA trop courte est refusée
+	small, _ := rsa.GenerateKey(rand.Reader, 1024)
+	smallPEM, _ := pemKey(t, small, &small.PublicKey)
+	_, err = utils.ParseJWTKeyPEM("small", smallPEM)
+	assert.Error(t, err)
+
+	_, err = utils.ParseJWTKeyPEM("bad", []byte("pas une clé"))
+	assert.Error(t, err)
+}
+
+// TestTOTP vérifie les codes TOTP avec les vecteurs de la RFC 6238 (SHA1, 6 derniers chiffres).
+func TestTOTP(t *testing.T) {
+	// Base32 du secret ASCII "12345678901234567890"
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	for unix, code := range map[int64]string{59: "287082", 1111111109: "081804", 1234567890: "005924", 2000000000: "279037"} {
+		got, err := utils.TOTPCode(secret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, code, got, unix)
+	}
+
+	// Une période de décalage est tolérée, pas deux
+	now := time.Unix(1111111109, 0)
+	step, ok := utils.ValidateTOTP(secret, "081804", now.Add(30*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, int64(1111111109/30), step)
+	_, ok = utils.ValidateTOTP(secret, "081804", now.Add(90*time.Second))
+	assert.False(t, ok)
+	_, ok = utils.ValidateTOTP(secret, "81804", now)
+	assert.False(t, ok)
+
+	generated, err := utils.NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, generated, 32)
+	uri := utils.TOTPURI("Todo API", "a@example.com", generated)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Todo%20API:a@example.com?"), uri)
+	assert.Contains(t, uri, "secret="+generated)
+	assert.Contains(t, uri, "issuer=Todo+API")
 }
@@ -0,0 +1,84 @@
+// tests/versioning_test.go
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/app"
+	"YoannLetacq/todo-api.git/routes"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAPIVersioning vérifie que l'API est servie sous /api/v1 et que les anciennes routes
+// sans préfixe restent des alias annonçant leur dépréciation.
+func TestAPIVersioning(t *testing.T) {
+	t.Parallel()
+	sunset := time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)
+	a := newTestAppWith(t, app.Options{LegacySunset: sunset})
+	_, token := createTestUserAndToken(t, a.DB)
+
+	// Les routes versionnées ne sont pas dépréciées
+	w := apiTokenRequest(a.Router, "POST", routes.V1Prefix+"/tasks", token, map[string]string{"title": "Versionnée"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = apiTokenRequest(a.Router, "GET", routes.V1Prefix+"/tasks", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Deprecation"))
+	assert.Empty(t, w.Header().Get("Sunset"))
+
+	// Les anciennes routes servent les mêmes données avec les en-têtes de dépréciation
+	legacy := apiTokenRequest(a.Router, "GET", "/tasks", token, nil)
+	assert.Equal(t, http.StatusOK, legacy.Code)
+	assert.JSONEq(t, w.Body.String(), legacy.Body.String())
+	assert.Regexp(t, `^@\d+$`, legacy.Header().Get("Deprecation"))
+	assert.Equal(t, "Thu, 01 Apr 2027 00:00:00 GMT", legacy.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v1/tasks>; rel="successor-version"`, legacy.Header().Get("Link"))
+
+	// Les en-têtes sont aussi envoyés sur les réponses d'erreur
+	w = apiTokenRequest(a.Router, "GET", "/tasks/999", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Deprecation"))
+
+	// Les clés publiques restent à leur emplacement standard, sans version
+	req, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Deprecation"))
+
+	// Le lien du flux iCalendar suit la version de la route qui l'a créé
+	var resp map[string]string
+	w = apiTokenRequest(a.Router, "POST", routes.V1Prefix+"/calendar/token", token, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, resp["url"], routes.V1Prefix+"/calendar/feed/")
+	w = apiTokenRequest(a.Router, "POST", "/calendar/token", token, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, resp["url"], routes.V1Prefix)
+}
+
+// TestAPIVersioningWithoutLegacyRoutes vérifie que les anciennes routes peuvent être retirées.
+func TestAPIVersioningWithoutLegacyRoutes(t *testing.T) {
+	t.Parallel()
+	a := newTestAppWith(t, app.Options{DisableLegacyRoutes: true})
+	_, token := createTestUserAndToken(t, a.DB)
+
+	w := apiTokenRequest(a.Router, "GET", "/tasks", token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = apiTokenRequest(a.Router, "GET", routes.V1Prefix+"/tasks", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,83 @@
+// tests/websocket_test.go
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"YoannLetacq/todo-api.git/internal/realtime"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// readEvent lit le prochain évènement de la websocket avec un délai maximum.
+func readEvent(t *testing.T, conn *websocket.Conn) realtime.Event {
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var ev realtime.Event
+	if err := conn.ReadJSON(&ev); err != nil {
+		t.Fatal("Erreur de lecture websocket:", err)
+	}
+	return ev
+}
+
+// TestWebSocketTaskBroadcast vérifie qu'une mutation de tâche est diffusée dans la room de la liste.
+func TestWebSocketTaskBroadcast(t *testing.T) {
+	t.Parallel()
+	a := newTestApp(t)
+	router := a.Router
+
+	user, token := createTestUserAndToken(t, a.DB)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// Connexion sans token refusée
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?token="+token, nil)
+	if err != nil {
+		t.Fatal("Erreur de connexion websocket:", err)
+	}
+	defer conn.Close()
+
+	// Abonnement à la liste d'un autre utilisateur refusé
+	conn.WriteJSON(map[string]string{"action": "subscribe", "room": realtime.ListRoom(user.ID + 1)})
+	ev := readEvent(t, conn)
+	assert.Equal(t, "error", ev.Type)
+
+	room := realtime.ListRoom(user.ID)
+	conn.WriteJSON(map[string]string{"action": "subscribe", "room": room})
+	ev = readEvent(t, conn)
+	assert.Equal(t, realtime.EventPresenceJoin, ev.Type)
+	ev = readEvent(t, conn)
+	assert.Equal(t, "subscribed", ev.Type)
+	assert.Equal(t, []interface{}{float64(user.ID)}, ev.Data)
+
+	// Création d'une tâche via l'API REST
+	jsonData, _ := json.Marshal(map[string]string{"title": "Live Task"})
+	req, _ := http.NewRequest("POST", server.URL+"/tasks", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	httpResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("Erreur lors de la création de la tâche:", err)
+	}
+	httpResp.Body.Close()
+	assert.Equal(t, http.StatusCreated, httpResp.StatusCode)
+
+	ev = readEvent(t, conn)
+	assert.Equal(t, realtime.EventTaskCreated, ev.Type)
+	assert.Equal(t, room, ev.Room)
+	data, ok := ev.Data.(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, "Live Task", data["title"])
+}